@@ -7,67 +7,73 @@ package main
 
 import (
 	"flag"
-	"image/color"
 	"log"
 	"time"
 
-	"github.com/disintegration/imaging"
-	"github.com/fogleman/gg"
 	"github.com/toothrot/gink/devices/epd7in5bhd"
-	"golang.org/x/image/font"
-	"golang.org/x/image/font/gofont/gomonobold"
-	"golang.org/x/image/font/opentype"
+	"github.com/toothrot/gink/render"
 )
 
 var (
-	text   = flag.String("text", "Hello, world!", "Text to display.")
-	rotate = flag.Float64("rotate", 0.0, "Image rotation in degrees.")
-	red    = flag.Bool("red", false, "Render in red instead of black.")
+	text      = flag.String("text", "Hello, world!", "Text to display.")
+	rotate    = flag.Float64("rotate", 0.0, "Text rotation in degrees, counter-clockwise.")
+	colorName = flag.String("color", "black", "Text color: black, white, or highlight.")
+	red       = flag.Bool("red", false, "Shorthand for -color=highlight.")
+	highlight = flag.String("highlight", "red", "Color of the panel's highlight plane: red, yellow, or blue.")
+	fontPath  = flag.String("font", "", "Path to a TrueType or OpenType font. Defaults to the embedded Go Mono Bold.")
+	noClear   = flag.Bool("noclear", false, "Skip clearing the panel before drawing. Faster, but may leave ghosting from the previous image.")
+	resample  = flag.String("resample", "lanczos", "Filter used to scale images to the display: lanczos, linear, or nearest.")
 )
 
 func main() {
 	flag.Parse()
+	if *red {
+		*colorName = "highlight"
+	}
+	hc, err := epd7in5bhd.ParseHighlight(*highlight)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fg, err := epd7in5bhd.ParsePanelColor(*colorName, hc)
+	if err != nil {
+		log.Fatal(err)
+	}
+	rs, err := epd7in5bhd.ParseResample(*resample)
+	if err != nil {
+		log.Fatal(err)
+	}
+	ff, err := render.FaceOrDefault(*fontPath, 92)
+	if ff == nil {
+		log.Fatal(err)
+	}
+	if err != nil {
+		log.Printf("Using the embedded font, as -font could not be loaded: %v", err)
+	}
+
 	d, err := epd7in5bhd.New(epd7in5bhd.DefaultPins)
 	if err != nil {
 		log.Fatal(err)
 	}
+	d.HighlightColor = hc
 
 	log.Println("Initializing")
-	d.Init()
+	if err := d.Init(); err != nil {
+		log.Fatal(err)
+	}
 	defer d.Sleep()
-	log.Println("Clearing")
-	d.Clear()
-	log.Printf("Waiting %vs", epd7in5bhd.DefaultWait.Seconds())
-	time.Sleep(epd7in5bhd.DefaultWait)
-
-	img := imaging.New(epd7in5bhd.DisplayWidth, epd7in5bhd.DisplayHeight, color.White)
-	ctx := gg.NewContextForImage(img)
-
-	ctx.SetFontFace(fontFace())
-	ctx.SetRGB(0, 0, 0)
-	if *red {
-		ctx.SetRGB(255, 0, 0)
+	if !*noClear {
+		log.Println("Clearing")
+		d.Clear()
+		log.Printf("Waiting %vs", epd7in5bhd.DefaultWait.Seconds())
+		time.Sleep(epd7in5bhd.DefaultWait)
 	}
-	ctx.DrawStringWrapped(*text, epd7in5bhd.DisplayWidth/2, epd7in5bhd.DisplayHeight/2, 0.5, 0.5, epd7in5bhd.DisplayWidth-80, 1.0, gg.AlignCenter)
-	rot := imaging.Rotate(ctx.Image(), *rotate, color.White)
-	fit := imaging.Fit(rot, epd7in5bhd.DisplayWidth, epd7in5bhd.DisplayHeight, imaging.Lanczos)
-	final := imaging.PasteCenter(imaging.New(epd7in5bhd.DisplayWidth, epd7in5bhd.DisplayHeight, color.White), fit)
-	d.DrawAndRefresh(final)
-	time.Sleep(epd7in5bhd.DefaultWait)
-}
 
-func fontFace() font.Face {
-	f, err := opentype.Parse(gomonobold.TTF)
+	// The text is drawn rotated, rather than rotating and rescaling the finished image.
+	img, err := render.Text(d.Size(), *text, render.TextOptions{Face: ff, Color: fg, Margin: 40, Rotate: *rotate})
 	if err != nil {
 		log.Fatal(err)
 	}
-	ff, err := opentype.NewFace(f, &opentype.FaceOptions{
-		Size:    92,
-		DPI:     72,
-		Hinting: font.HintingNone,
-	})
-	if err != nil {
-		log.Fatal(err)
-	}
-	return ff
+	final := d.FitCentered(img, epd7in5bhd.WithResample(rs))
+	d.DrawAndRefresh(final)
+	time.Sleep(epd7in5bhd.DefaultWait)
 }
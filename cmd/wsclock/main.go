@@ -7,88 +7,227 @@ package main
 
 import (
 	"flag"
+	"fmt"
+	"image"
 	"image/color"
 	"log"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
+	"unicode/utf8"
 
 	"github.com/disintegration/imaging"
-	"github.com/fogleman/gg"
 	"github.com/toothrot/gink/devices/epd7in5bhd"
-	"golang.org/x/image/font"
-	"golang.org/x/image/font/gofont/gomonobold"
-	"golang.org/x/image/font/opentype"
+	"github.com/toothrot/gink/render"
 )
 
 var (
-	format = flag.String("format", time.RFC822, "time.Time format.")
-	rotate = flag.Float64("rotate", 0.0, "Image rotation in degrees.")
-	red    = flag.Bool("red", false, "Render in red instead of black.")
+	format    = flag.String("format", time.RFC822, "time.Time format.")
+	rotate    = flag.Float64("rotate", 0.0, "Text rotation in degrees, counter-clockwise.")
+	colorName = flag.String("color", "black", "Text color: black, white, or highlight.")
+	red       = flag.Bool("red", false, "Shorthand for -color=highlight.")
+	highlight = flag.String("highlight", "red", "Color of the panel's highlight plane: red, yellow, or blue.")
+	fontPath  = flag.String("font", "", "Path to a TrueType or OpenType font. Defaults to the embedded Go Mono Bold.")
+	reconnect = flag.Int("reconnect", 5, "Reconnect attempts after a bus error, 0 to disable.")
+	watchdog  = flag.Duration("watchdog", time.Minute, "Reinitialize the panel if a refresh takes longer than this, 0 to disable.")
+	grid      = flag.Bool("grid", false, "Draw each character of the time in a fixed cell, and upload only the cells that changed. Not supported with -rotate.")
+	idle      = flag.Duration("idle", 10*time.Minute, "Put the panel to sleep once the time has not changed for this long, such as with a -format without minutes.")
+	noClear   = flag.Bool("noclear", false, "Skip clearing the panel before the first refresh. Faster, but may leave ghosting from the previous image.")
+	resample  = flag.String("resample", "lanczos", "Filter used to scale images to the display: lanczos, linear, or nearest.")
 )
 
 func main() {
 	flag.Parse()
-	d, err := epd7in5bhd.New(epd7in5bhd.DefaultPins)
+	if *grid && *rotate != 0 {
+		log.Fatal("-grid does not support -rotate")
+	}
+	if *red {
+		*colorName = "highlight"
+	}
+	hc, err := epd7in5bhd.ParseHighlight(*highlight)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fg, err := epd7in5bhd.ParsePanelColor(*colorName, hc)
+	if err != nil {
+		log.Fatal(err)
+	}
+	rs, err := epd7in5bhd.ParseResample(*resample)
 	if err != nil {
 		log.Fatal(err)
 	}
+	// The font is loaded at several sizes, so a -font that can't be loaded is reported once here,
+	// and the embedded font used from then on.
+	if _, err := render.FaceOrDefault(*fontPath, 128); err != nil && *fontPath != "" {
+		log.Printf("Using the embedded font, as -font could not be loaded: %v", err)
+		*fontPath = ""
+	}
 
-	log.Println("Initializing")
-	d.Init()
-	defer d.Sleep()
-	log.Println("Clearing")
-	d.Clear()
+	d, err := epd7in5bhd.New(epd7in5bhd.DefaultPins, epd7in5bhd.WithAutoReconnect(*reconnect, time.Second), epd7in5bhd.WithWatchdog(*watchdog))
+	if err != nil {
+		log.Fatal(err)
+	}
+	d.HighlightColor = hc
 
-	c := make(chan os.Signal)
+	// Signals are only handled between refreshes, so one sent while the panel is being
+	// initialized or drawn waits for that to finish, rather than leaving it half-drawn.
+	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 
+	log.Println("Initializing")
+	if err := d.Init(); err != nil {
+		log.Fatal(err)
+	}
+	defer d.Sleep()
+	if !*noClear {
+		log.Println("Clearing")
+		d.Clear()
+	}
+
+	// Only the time changes between ticks, so the layout and font are prepared once.
+	cl := &clock{d: d, fg: fg, fit: epd7in5bhd.WithResample(rs)}
+	if *grid {
+		cl.cols = widestFormat(*format)
+		cl.grid, err = newGrid(d.Size(), cl.cols, fg)
+	} else {
+		cl.tmpl, err = newTemplate(d.Size(), fg)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	cl.tick(time.Now())
 	ticker := time.NewTicker(time.Minute)
 	defer ticker.Stop()
 	for {
 		select {
 		case s := <-c:
-			log.Printf("Got signal %q, quitting", s.String())
-			d.Clear()
-			time.Sleep(epd7in5bhd.DefaultWait)
+			log.Printf("Got signal %q, clearing and quitting", s.String())
+			if err := cl.wake(); err != nil {
+				log.Printf("Waking the panel: %v", err)
+			}
+			if err := d.Clear(); err != nil {
+				log.Printf("Clear() = %v", err)
+			}
 			return
 		case t := <-ticker.C:
-			update(d, t.Format(*format))
+			cl.tick(t)
 		}
 	}
 }
 
-func update(d *epd7in5bhd.Display, text string) {
-	d.Reset()
-	img := imaging.New(epd7in5bhd.DisplayWidth, epd7in5bhd.DisplayHeight, color.White)
-	ctx := gg.NewContextForImage(img)
-	ctx.SetFontFace(fontFace())
-	ctx.SetRGB(0, 0, 0)
-	if *red {
-		ctx.SetRGB(255, 0, 0)
+// newTemplate returns a Template that wraps the time, drawn in fg, to fit the display.
+func newTemplate(size image.Point, fg color.Color) (*render.Template, error) {
+	ff, err := render.FaceOrDefault(*fontPath, 128)
+	if err != nil {
+		return nil, err
 	}
+	opts := render.TextOptions{Face: ff, Margin: 40, Color: fg, Rotate: *rotate}
+	return render.NewTemplate(imaging.New(size.X, size.Y, color.White), image.Rectangle{Max: size}, opts)
+}
 
-	ctx.DrawStringWrapped(text, epd7in5bhd.DisplayWidth/2, epd7in5bhd.DisplayHeight/2, 0.5, 0.5, epd7in5bhd.DisplayWidth-80, 1.0, gg.AlignCenter)
-	rot := imaging.Rotate(ctx.Image(), *rotate, color.White)
-	fit := imaging.Fit(rot, epd7in5bhd.DisplayWidth, epd7in5bhd.DisplayHeight, imaging.Lanczos)
-	final := imaging.PasteCenter(imaging.New(epd7in5bhd.DisplayWidth, epd7in5bhd.DisplayHeight, color.White), fit)
-	d.DrawAndRefresh(final)
-	d.Sleep()
+// newGrid returns a Grid of cols cells on a single line, drawn in fg, in the largest font size
+// up to 128 points that fits the display.
+func newGrid(size image.Point, cols int, fg color.Color) (*render.Grid, error) {
+	for pt := 128.0; pt >= 8; pt *= 0.9 {
+		ff, err := render.FaceOrDefault(*fontPath, pt)
+		if err != nil {
+			return nil, err
+		}
+		g, err := render.NewGrid(size, cols, render.TextOptions{Face: ff, Margin: 40, Color: fg})
+		if err == nil {
+			return g, nil
+		}
+	}
+	return nil, fmt.Errorf("-format %q is too long to fit the display on one line", *format)
 }
 
-func fontFace() font.Face {
-	f, err := opentype.Parse(gomonobold.TTF)
-	if err != nil {
-		log.Fatal(err)
+// widestFormat returns the most characters layout formats any time to, such as "Wednesday"
+// for "Monday", found by formatting every hour of a leap year in the local time zone.
+func widestFormat(layout string) int {
+	n := 0
+	t := time.Date(2000, time.January, 1, 0, 59, 59, 999999999, time.Local)
+	for end := t.AddDate(1, 0, 0); t.Before(end); t = t.Add(time.Hour) {
+		if w := utf8.RuneCountInString(t.Format(layout)); w > n {
+			n = w
+		}
 	}
-	ff, err := opentype.NewFace(f, &opentype.FaceOptions{
-		Size:    128,
-		DPI:     72,
-		Hinting: font.HintingNone,
-	})
-	if err != nil {
-		log.Fatal(err)
+	return n
+}
+
+// clock keeps the panel initialized between updates, and only puts it to sleep once the
+// displayed time has not changed for -idle. With a grid, only the cells that changed since the
+// last refresh are uploaded.
+type clock struct {
+	d           *epd7in5bhd.Display
+	fg          color.Color
+	fit         epd7in5bhd.FitOption
+	tmpl        *render.Template
+	grid        *render.Grid
+	cols        int
+	last        string
+	lastRefresh time.Time
+	asleep      bool
+	// full is set when the panel's RAM may not hold the last frame, so the next refresh must
+	// upload all of it.
+	full bool
+}
+
+// tick shows t, if it differs from what is shown.
+func (c *clock) tick(t time.Time) {
+	text := t.Format(*format)
+	if text == c.last {
+		if !c.asleep && *idle > 0 && time.Since(c.lastRefresh) >= *idle {
+			log.Println("Sleeping until the time changes")
+			c.d.Sleep()
+			c.asleep = true
+		}
+		return
+	}
+	if err := c.wake(); err != nil {
+		log.Printf("Waking the panel: %v", err)
+		return
+	}
+	if err := c.refresh(text); err != nil {
+		log.Printf("Refreshing %q: %v", text, err)
+		c.full = true
+		return
+	}
+	c.last = text
+	c.lastRefresh = time.Now()
+	c.full = false
+}
+
+// refresh shows text on the display.
+func (c *clock) refresh(text string) error {
+	if c.grid == nil {
+		return c.d.DrawAndRefresh(c.d.FitCentered(c.tmpl.Render(text), c.fit))
+	}
+	if n := utf8.RuneCountInString(text); n > c.cols {
+		// widestFormat missed a longer form, so widen the grid rather than cut off the text.
+		g, err := newGrid(c.d.Size(), n, c.fg)
+		if err != nil {
+			return err
+		}
+		c.grid, c.cols, c.full = g, n, true
+	}
+	c.d.Draw(c.grid.Render(text))
+	if c.full || c.last == "" {
+		return c.d.Refresh()
+	}
+	return c.d.RefreshRegion(c.grid.Changed(c.last, text))
+}
+
+// wake reinitializes the panel if it is asleep. If that fails, the panel is still considered
+// asleep, so the next call tries again.
+func (c *clock) wake() error {
+	if !c.asleep {
+		return nil
+	}
+	if err := c.d.Init(); err != nil {
+		return err
 	}
-	return ff
+	c.asleep = false
+	c.full = true
+	return nil
 }
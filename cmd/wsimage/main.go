@@ -3,13 +3,24 @@
 // license that can be found in the LICENSE file.
 
 // Binary wsimage displays an image on a waveshare display.
+//
+// With no arguments, it shows a series of demo images. With the argument -, it shows a single
+// PNG, JPEG, GIF, or WebP image read from standard input instead, such as:
+//
+//	curl -s https://example.com/photo.jpg | wsimage -
 package main
 
 import (
+	"bytes"
+	"errors"
 	"flag"
+	"fmt"
 	"image"
 	"image/color"
+	_ "image/gif"
+	_ "image/jpeg"
 	_ "image/png"
+	"io/ioutil"
 	"log"
 	"os"
 	"runtime/pprof"
@@ -19,15 +30,38 @@ import (
 	"github.com/makeworld-the-better-one/dither"
 	"github.com/toothrot/gink/devices/epd7in5bhd"
 	"github.com/toothrot/gink/static"
+	_ "golang.org/x/image/webp"
 )
 
 var (
 	rotate     = flag.Float64("rotate", 0.0, "Image rotation in degrees.")
 	cpuprofile = flag.String("cpuprofile", "", "write cpu profile to file")
+	measure    = flag.Bool("measure", false, "Clear the display, print how long the refresh took, and exit.")
+	auto       = flag.Bool("auto", false, "Adjust contrast until black coverage is in a target range, instead of a fixed adjustment.")
+	preview    = flag.Int("preview", 0, "Print each frame to the terminal, scaled down by this factor. 0 disables.")
+	resample   = flag.String("resample", "lanczos", "Filter used to scale images to the display: lanczos, linear, or nearest.")
+	footer     = flag.String("footer", "", "Caption drawn along the bottom of each image.")
 )
 
 func main() {
 	flag.Parse()
+	if flag.NArg() > 1 || (flag.NArg() == 1 && flag.Arg(0) != "-") {
+		log.Fatalf("usage: wsimage [flags] [-]")
+	}
+	rs, err := epd7in5bhd.ParseResample(*resample)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fit := epd7in5bhd.WithResample(rs)
+	// The image is read before the display is touched, so that bad input leaves it as it is.
+	var stdinImage image.Image
+	if flag.Arg(0) == "-" {
+		img, err := readStdin()
+		if err != nil {
+			log.Fatal(err)
+		}
+		stdinImage = img
+	}
 	if *cpuprofile != "" {
 		f, err := os.Create(*cpuprofile)
 		if err != nil {
@@ -40,43 +74,70 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
+	d.Footer = *footer
 
 	log.Println("Initializing")
-	d.Init()
+	if err := d.Init(); err != nil {
+		log.Fatal(err)
+	}
+	if *measure {
+		log.Println("Clearing")
+		d.Clear()
+		dur, err := d.MeasureRefresh()
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("Refresh took %v\n", dur)
+		d.Sleep()
+		return
+	}
+	if stdinImage != nil {
+		log.Println("Displaying image from standard input")
+		if err := d.DrawAndRefresh(fitImage(d, stdinImage, fit)); err != nil {
+			log.Print(err)
+		}
+		printPreview(d)
+		log.Println("Powering off")
+		d.Sleep()
+		return
+	}
 	log.Println("Clearing")
 	d.Clear()
 	log.Printf("Waiting %vs", epd7in5bhd.DefaultWait.Seconds())
 	time.Sleep(epd7in5bhd.DefaultWait)
 
-	bimg, err := staticImage("images/7in5B_HD_b.png")
+	bimg, err := staticImage(d, "images/7in5B_HD_b.png", fit)
 	if err != nil {
 		log.Fatal(err)
 	}
-	rimg, err := staticImage("images/7in5B_HD_r.png")
+	rimg, err := staticImage(d, "images/7in5B_HD_r.png", fit)
 	if err != nil {
 		log.Fatal(err)
 	}
-	comb, err := staticImage("images/7in5B_HD.png")
+	comb, err := staticImage(d, "images/7in5B_HD.png", fit)
 	if err != nil {
 		log.Fatal(err)
 	}
-	cimg, err := staticImage("images/cardinal.png")
+	cimg, err := staticImage(d, "images/cardinal.png", fit)
 	if err != nil {
 		log.Fatal(err)
 	}
 
 	log.Println("Displaying image")
 	d.DrawAndRefreshImages(bimg, rimg)
+	printPreview(d)
 	log.Printf("Waiting %vs", epd7in5bhd.DefaultWait.Seconds())
 	time.Sleep(epd7in5bhd.DefaultWait)
 
 	log.Println("Displaying image")
 	d.DrawAndRefresh(comb)
+	printPreview(d)
 	log.Printf("Waiting %vs", epd7in5bhd.DefaultWait.Seconds())
 	time.Sleep(epd7in5bhd.DefaultWait)
 
 	log.Println("Displaying image")
-	d.DrawAndRefresh(imaging.Fill(cimg, epd7in5bhd.DisplayWidth, epd7in5bhd.DisplayHeight, imaging.Center, imaging.Lanczos))
+	d.DrawAndRefresh(d.Fill(cimg, fit))
+	printPreview(d)
 	log.Printf("Waiting %vs", epd7in5bhd.DefaultWait.Seconds())
 	time.Sleep(epd7in5bhd.DefaultWait)
 
@@ -86,6 +147,7 @@ func main() {
 	dith.Matrix = dither.FloydSteinberg
 	dith.Serpentine = true
 	d.DrawAndRefresh(dith.DitherPaletted(cimg))
+	printPreview(d)
 	log.Printf("Waiting %vs", epd7in5bhd.DefaultWait.Seconds())
 	time.Sleep(epd7in5bhd.DefaultWait)
 
@@ -94,7 +156,12 @@ func main() {
 	dith = dither.NewDitherer(colors)
 	dith.Matrix = dither.FloydSteinberg
 	dith.Serpentine = true
-	d.DrawAndRefresh(dith.DitherPaletted(imaging.AdjustBrightness(imaging.AdjustContrast(cimg, 25), 25)))
+	var adjusted image.Image = imaging.AdjustBrightness(imaging.AdjustContrast(cimg, 25), 25)
+	if *auto {
+		adjusted = autoContrast(cimg)
+	}
+	d.DrawAndRefresh(dith.DitherPaletted(adjusted))
+	printPreview(d)
 	log.Printf("Waiting %vs", epd7in5bhd.DefaultWait.Seconds())
 	time.Sleep(epd7in5bhd.DefaultWait)
 
@@ -102,7 +169,50 @@ func main() {
 	d.Sleep()
 }
 
-func staticImage(path string) (image.Image, error) {
+// printPreview prints the display buffer to stdout if -preview is set.
+func printPreview(d *epd7in5bhd.Display) {
+	if *preview <= 0 {
+		return
+	}
+	if err := d.PrintTerminal(os.Stdout, *preview); err != nil {
+		log.Printf("PrintTerminal() = %v", err)
+	}
+}
+
+// Black coverage targets for -auto, as a fraction of the image.
+const (
+	minBlack = 0.15
+	maxBlack = 0.35
+)
+
+// autoContrast adjusts the contrast of img in steps until the fraction of pixels nearest to
+// black is between minBlack and maxBlack, or gives up after a few steps.
+func autoContrast(img image.Image) image.Image {
+	out := img
+	contrast := 0.0
+	for i := 0; i < 10; i++ {
+		h := epd7in5bhd.Histogram(out)
+		total := h[0] + h[1] + h[2]
+		if total == 0 {
+			return out
+		}
+		black := float64(h[1]) / float64(total)
+		switch {
+		case black < minBlack:
+			contrast += 10
+		case black > maxBlack:
+			contrast -= 10
+		default:
+			log.Printf("Adjusted contrast by %v for %.0f%% black", contrast, black*100)
+			return out
+		}
+		out = imaging.AdjustContrast(img, contrast)
+	}
+	log.Printf("Black coverage still out of range after adjusting contrast by %v", contrast)
+	return out
+}
+
+func staticImage(d *epd7in5bhd.Display, path string, fit epd7in5bhd.FitOption) (image.Image, error) {
 	imgf, err := static.Images.Open(path)
 	if err != nil {
 	}
@@ -110,7 +220,28 @@ func staticImage(path string) (image.Image, error) {
 	if err != nil {
 		return nil, err
 	}
+	return fitImage(d, img, fit), err
+}
+
+// readStdin decodes a single image from standard input.
+func readStdin() (image.Image, error) {
+	b, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		return nil, fmt.Errorf("reading standard input: %w", err)
+	}
+	if len(b) == 0 {
+		return nil, errors.New("no image on standard input")
+	}
+	img, format, err := image.Decode(bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("decoding %d bytes from standard input: %w", len(b), err)
+	}
+	log.Printf("Read a %s image of %v from standard input", format, img.Bounds().Size())
+	return img, nil
+}
+
+// fitImage rotates img by -rotate, and fits it to d with fit.
+func fitImage(d *epd7in5bhd.Display, img image.Image, fit epd7in5bhd.FitOption) image.Image {
 	rot := imaging.Rotate(img, *rotate, color.White)
-	fit := imaging.Fit(rot, epd7in5bhd.DisplayWidth, epd7in5bhd.DisplayHeight, imaging.Lanczos)
-	return imaging.PasteCenter(imaging.New(epd7in5bhd.DisplayWidth, epd7in5bhd.DisplayHeight, color.White), fit), err
+	return d.FitCentered(rot, fit)
 }
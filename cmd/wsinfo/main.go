@@ -0,0 +1,77 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Binary wsinfo lists the GPIO pins and SPI buses available on this host.
+//
+// Use it to find valid epd7in5bhd.Pins values when New fails with an invalid pin error. With
+// -verify, it also opens the display on the default pins and checks that it is an epd7in5bhd.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/toothrot/gink/devices/epd7in5bhd"
+	"periph.io/x/periph/conn/gpio/gpioreg"
+	"periph.io/x/periph/conn/spi/spireg"
+	"periph.io/x/periph/host"
+)
+
+var verify = flag.Bool("verify", false, "Open the display on the default pins and check that it is an epd7in5bhd. Overwrites part of the panel's memory, but not what is shown.")
+
+func main() {
+	flag.Parse()
+	if _, err := host.Init(); err != nil {
+		log.Fatalf("host.Init() = _, %v", err)
+	}
+
+	fmt.Println("GPIO pins:")
+	for _, p := range gpioreg.All() {
+		fmt.Printf("  %-12s %s\n", p.Name(), p.Function())
+	}
+	for _, p := range gpioreg.Aliases() {
+		fmt.Printf("  %-12s alias\n", p.Name())
+	}
+
+	fmt.Println("SPI buses:")
+	buses := spireg.All()
+	for _, r := range buses {
+		fmt.Printf("  %-12s %s\n", r.Name, strings.Join(r.Aliases, ", "))
+	}
+	// spireg.Open("") opens the first bus, which is what the display packages use.
+	if len(buses) == 0 {
+		fmt.Println("Default SPI bus: missing. Is SPI enabled, for example with dtparam=spi=on?")
+	} else {
+		fmt.Printf("Default SPI bus: %s\n", buses[0].Name)
+	}
+
+	fmt.Println("Default pins:")
+	p := epd7in5bhd.DefaultPins
+	for _, pin := range []struct{ use, name string }{{"Busy", p.Busy}, {"CS", p.CS}, {"DC", p.DC}, {"RST", p.RST}} {
+		status := "ok"
+		if gpioreg.ByName(pin.name) == nil {
+			status = "missing"
+		}
+		fmt.Printf("  %-4s %-8s %s\n", pin.use, pin.name, status)
+	}
+
+	if !*verify {
+		return
+	}
+	d, err := epd7in5bhd.New(epd7in5bhd.DefaultPins)
+	if err != nil {
+		log.Fatal(err)
+	}
+	switch err := d.Verify(); {
+	case errors.Is(err, epd7in5bhd.ErrNoReads):
+		fmt.Printf("Panel: can't verify, %v. Is the panel's data line wired for reads?\n", err)
+	case err != nil:
+		fmt.Printf("Panel: %v\n", err)
+	default:
+		fmt.Println("Panel: ok")
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Binary wsprogress displays a progress bar on a waveshare display.
+//
+// Percentages from 0 to 100 are read from standard input, one per line, and other lines are
+// skipped. Refreshes are slow, so the display is refreshed at most once per -interval with the
+// latest percentage read.
+//
+//	for i in $(seq 0 10 100); do echo $i; sleep 60; done | wsprogress
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/toothrot/gink/devices/epd7in5bhd"
+	"github.com/toothrot/gink/render"
+)
+
+var (
+	interval  = flag.Duration("interval", time.Minute, "Minimum time between refreshes.")
+	colorName = flag.String("color", "black", "Bar color: black, white, or highlight.")
+	red       = flag.Bool("red", false, "Shorthand for -color=highlight.")
+	highlight = flag.String("highlight", "red", "Color of the panel's highlight plane: red, yellow, or blue.")
+)
+
+func main() {
+	flag.Parse()
+	if *red {
+		*colorName = "highlight"
+	}
+	hc, err := epd7in5bhd.ParseHighlight(*highlight)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fg, err := epd7in5bhd.ParsePanelColor(*colorName, hc)
+	if err != nil {
+		log.Fatal(err)
+	}
+	d, err := epd7in5bhd.New(epd7in5bhd.DefaultPins)
+	if err != nil {
+		log.Fatal(err)
+	}
+	d.HighlightColor = hc
+
+	log.Println("Initializing")
+	if err := d.Init(); err != nil {
+		log.Fatal(err)
+	}
+	defer d.Sleep()
+	log.Println("Clearing")
+	d.Clear()
+
+	updates := make(chan float64)
+	go readPercentages(updates)
+
+	var last time.Time
+	pct, ok := <-updates
+	for ok {
+		// Wait out the rest of the interval, keeping only the most recent percentage.
+		timer := time.NewTimer(*interval - time.Since(last))
+	wait:
+		for {
+			select {
+			case p, more := <-updates:
+				if !more {
+					timer.Stop()
+					break wait
+				}
+				pct = p
+			case <-timer.C:
+				break wait
+			}
+		}
+		log.Printf("Displaying %.0f%%", pct*100)
+		last = time.Now()
+		if err := drawProgress(d, pct, fg); err != nil {
+			log.Fatal(err)
+		}
+		if err := d.Refresh(); err != nil {
+			log.Printf("Refresh() = %v", err)
+		}
+		pct, ok = <-updates
+	}
+}
+
+// readPercentages sends each percentage read from stdin as a fraction, and closes updates at EOF.
+func readPercentages(updates chan<- float64) {
+	defer close(updates)
+	s := bufio.NewScanner(os.Stdin)
+	for s.Scan() {
+		line := strings.TrimSuffix(strings.TrimSpace(s.Text()), "%")
+		if line == "" {
+			continue
+		}
+		p, err := strconv.ParseFloat(line, 64)
+		if err != nil {
+			log.Printf("Skipping %q: %v", s.Text(), err)
+			continue
+		}
+		if !(p >= 0 && p <= 100) {
+			log.Printf("Skipping %q: not between 0 and 100", s.Text())
+			continue
+		}
+		updates <- p / 100
+	}
+	if err := s.Err(); err != nil {
+		log.Printf("Reading stdin: %v", err)
+	}
+}
+
+// drawProgress draws the percentage as text above a progress bar in c to the display buffer.
+// The bar is drawn on its own, so that its two colors take the display's paletted fast path.
+func drawProgress(d *epd7in5bhd.Display, pct float64, c color.Color) error {
+	size := d.Size()
+	text, err := render.Text(size, fmt.Sprintf("%.0f%%", pct*100), render.TextOptions{Size: 96})
+	if err != nil {
+		return err
+	}
+	// Drawing an empty image fills the display with its background.
+	d.Draw(image.NewRGBA(image.Rectangle{}))
+	// Move the text up from the center to make room for the bar.
+	d.DrawAt(text, image.Pt(0, -size.Y/6))
+
+	bar := render.ProgressBar(pct, size.X*3/4, size.Y/8, c)
+	d.DrawAt(bar, image.Pt((size.X-bar.Bounds().Dx())/2, size.Y*5/8))
+	return nil
+}
@@ -0,0 +1,321 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Binary wsscheduler shows different content on a waveshare display at different times of day,
+// as listed in a playlist file.
+//
+// Each line of the playlist is a time window, a kind of content, and its argument:
+//
+//	# Blank lines and lines starting with # are ignored.
+//	07:00-22:00  clock  Mon Jan 2 15:04
+//	22:00-07:00  image  /srv/art/night.png
+//	*            text   Hello, world!
+//
+// Windows are in local time, and include their start but not their end. A window that ends
+// before it starts wraps past midnight, and * matches all day. The first matching line is
+// shown; if none match, the panel is left as it is.
+//
+// The kinds are text, which wraps its argument to fit the display; clock, which shows the
+// current time in the time.Time format given as its argument (15:04 by default); and image,
+// which fits and dithers the image file at its argument. Dashboards are not supported as a
+// kind; render one to an image file with another program, and show it with image, which is
+// redrawn whenever the file changes. The panel is only refreshed when what it would show
+// changes, and never more often than -mininterval.
+//
+// Sending SIGHUP reloads the playlist. If the new playlist can't be read, the old one is kept.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+	"unicode"
+
+	"github.com/makeworld-the-better-one/dither"
+	"github.com/toothrot/gink/devices/epd7in5bhd"
+	"github.com/toothrot/gink/render"
+)
+
+var (
+	playlistPath = flag.String("playlist", "", "Path to the playlist file.")
+	check        = flag.Duration("check", time.Minute, "How often to check whether the content should change.")
+	minInterval  = flag.Duration("mininterval", 3*time.Minute, "Minimum time between the start of one refresh and the next.")
+	size         = flag.Float64("size", 64, "Font size in points of text and clock content.")
+	margin       = flag.Int("margin", 40, "Margin around text and clock content in pixels.")
+	resample     = flag.String("resample", "lanczos", "Filter used to scale images to the display: lanczos, linear, or nearest.")
+	highlight    = flag.String("highlight", "red", "Color of the panel's highlight plane: red, yellow, or blue.")
+)
+
+func main() {
+	flag.Parse()
+	if *playlistPath == "" {
+		log.Fatal("-playlist is required")
+	}
+	pl, err := loadPlaylist(*playlistPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	hc, err := epd7in5bhd.ParseHighlight(*highlight)
+	if err != nil {
+		log.Fatal(err)
+	}
+	rs, err := epd7in5bhd.ParseResample(*resample)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fit := epd7in5bhd.WithResample(rs)
+	d, err := epd7in5bhd.New(epd7in5bhd.DefaultPins)
+	if err != nil {
+		log.Fatal(err)
+	}
+	d.MinRefreshInterval = *minInterval
+	d.HighlightColor = hc
+
+	// Caught before Init, so that signals are only handled between refreshes.
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	log.Println("Initializing")
+	if err := d.Init(); err != nil {
+		log.Fatal(err)
+	}
+	defer d.Sleep()
+	log.Println("Clearing")
+	d.Clear()
+
+	var shown string
+	ticker := time.NewTicker(*check)
+	defer ticker.Stop()
+	for {
+		shown = show(d, pl, time.Now(), shown, fit)
+		select {
+		case s := <-c:
+			log.Printf("Got signal %q, clearing and quitting", s.String())
+			d.Clear()
+			return
+		case <-hup:
+			next, err := loadPlaylist(*playlistPath)
+			if err != nil {
+				log.Printf("Keeping the current playlist: %v", err)
+				continue
+			}
+			log.Printf("Reloaded %q with %d entries", *playlistPath, len(next))
+			pl = next
+		case <-ticker.C:
+		}
+	}
+}
+
+// show refreshes the display with the playlist entry for t, unless it would show the same
+// thing as shown, the key of what is already displayed. Images are fitted with fit. It returns
+// the key of what is displayed afterwards.
+func show(d *epd7in5bhd.Display, pl playlist, t time.Time, shown string, fit epd7in5bhd.FitOption) string {
+	e, ok := pl.active(t)
+	if !ok {
+		return shown
+	}
+	key, err := e.key(t)
+	if err != nil {
+		log.Printf("Line %d: %v", e.line, err)
+		return shown
+	}
+	if key == shown {
+		return shown
+	}
+	log.Printf("Displaying line %d: %s %s", e.line, e.kind, e.arg)
+	if err := e.draw(d, t, fit); err != nil {
+		log.Printf("Line %d: %v", e.line, err)
+		return shown
+	}
+	if err := d.Refresh(); err != nil {
+		log.Printf("Refresh() = %v", err)
+		return ""
+	}
+	return key
+}
+
+// playlist is the entries of a playlist file, in order.
+type playlist []entry
+
+// active returns the first entry whose window includes t.
+func (pl playlist) active(t time.Time) (entry, bool) {
+	m := t.Hour()*60 + t.Minute()
+	for _, e := range pl {
+		if e.includes(m) {
+			return e, true
+		}
+	}
+	return entry{}, false
+}
+
+// entry is a line of a playlist.
+type entry struct {
+	// line is the line number in the playlist file, for logging.
+	line int
+	// start and end are the window in minutes past midnight. They are equal for all day.
+	start, end int
+	kind       string
+	arg        string
+}
+
+// includes reports whether the window includes m minutes past midnight.
+func (e entry) includes(m int) bool {
+	switch {
+	case e.start == e.end:
+		return true
+	case e.start < e.end:
+		return e.start <= m && m < e.end
+	default:
+		return e.start <= m || m < e.end
+	}
+}
+
+// key identifies what e shows at t, so that the display is only refreshed when it changes.
+func (e entry) key(t time.Time) (string, error) {
+	switch e.kind {
+	case "clock":
+		return "clock " + t.Format(e.arg), nil
+	case "image":
+		fi, err := os.Stat(e.arg)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("image %s %v", e.arg, fi.ModTime()), nil
+	}
+	return e.kind + " " + e.arg, nil
+}
+
+// draw draws what e shows at t to the display buffer, fitting images with fit.
+func (e entry) draw(d *epd7in5bhd.Display, t time.Time, fit epd7in5bhd.FitOption) error {
+	opts := render.TextOptions{Size: *size, Margin: *margin}
+	switch e.kind {
+	case "clock":
+		return d.DrawString(t.Format(e.arg), opts)
+	case "image":
+		img, err := loadImage(d, e.arg, fit)
+		if err != nil {
+			return err
+		}
+		d.Draw(img)
+		return nil
+	}
+	return d.DrawString(e.arg, opts)
+}
+
+// loadPlaylist reads the playlist file at path.
+func loadPlaylist(path string) (playlist, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	pl, err := parsePlaylist(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", path, err)
+	}
+	return pl, nil
+}
+
+// parsePlaylist parses the lines of a playlist. Errors are prefixed with their line number.
+func parsePlaylist(r io.Reader) (playlist, error) {
+	var pl playlist
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		window, rest := field(line)
+		kind, arg := field(rest)
+		e := entry{line: n, kind: kind, arg: arg}
+		var err error
+		if e.start, e.end, err = parseWindow(window); err != nil {
+			return nil, fmt.Errorf("%d: %w", n, err)
+		}
+		switch kind {
+		case "clock":
+			if e.arg == "" {
+				e.arg = "15:04"
+			}
+		case "text", "image":
+			if e.arg == "" {
+				return nil, fmt.Errorf("%d: %s needs an argument", n, kind)
+			}
+		case "":
+			return nil, fmt.Errorf("%d: missing the kind of content", n)
+		default:
+			return nil, fmt.Errorf("%d: unknown kind %q, want text, clock, or image", n, kind)
+		}
+		pl = append(pl, e)
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return pl, nil
+}
+
+// parseWindow parses a window such as 07:00-22:00, or * for all day, into minutes past
+// midnight.
+func parseWindow(s string) (start, end int, err error) {
+	if s == "*" {
+		return 0, 0, nil
+	}
+	parts := strings.Split(s, "-")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("window %q is not of the form 07:00-22:00 or *", s)
+	}
+	var mins [2]int
+	for i, p := range parts {
+		t, err := time.Parse("15:04", p)
+		if err != nil {
+			return 0, 0, fmt.Errorf("window %q: %w", s, err)
+		}
+		mins[i] = t.Hour()*60 + t.Minute()
+	}
+	if mins[0] == mins[1] {
+		return 0, 0, fmt.Errorf("window %q is empty, use * for all day", s)
+	}
+	return mins[0], mins[1], nil
+}
+
+// field splits s at its first run of whitespace.
+func field(s string) (first, rest string) {
+	i := strings.IndexFunc(s, unicode.IsSpace)
+	if i < 0 {
+		return s, ""
+	}
+	return s[:i], strings.TrimSpace(s[i:])
+}
+
+// loadImage decodes the image at path, then fits it to d with fit and dithers it to the panel's
+// colors.
+func loadImage(d *epd7in5bhd.Display, path string, fit epd7in5bhd.FitOption) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("image.Decode() = %w", err)
+	}
+	dith := dither.NewDitherer([]color.Color{color.White, d.HighlightColor, color.Black})
+	dith.Matrix = dither.FloydSteinberg
+	dith.Serpentine = true
+	return dith.DitherPaletted(d.FitCentered(img, fit)), nil
+}
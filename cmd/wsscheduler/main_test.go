@@ -0,0 +1,104 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParsePlaylist(t *testing.T) {
+	pl, err := parsePlaylist(strings.NewReader(`# A comment, then a blank line.
+
+07:00-22:00  clock
+22:00-07:00  image  /srv/art/night.png
+*            text   Hello, world!
+`))
+	if err != nil {
+		t.Fatalf("parsePlaylist() = _, %v, wanted no error", err)
+	}
+	want := playlist{
+		{line: 3, start: 7 * 60, end: 22 * 60, kind: "clock", arg: "15:04"},
+		{line: 4, start: 22 * 60, end: 7 * 60, kind: "image", arg: "/srv/art/night.png"},
+		{line: 5, kind: "text", arg: "Hello, world!"},
+	}
+	if len(pl) != len(want) {
+		t.Fatalf("parsePlaylist() = %v, wanted %v", pl, want)
+	}
+	for i := range want {
+		if pl[i] != want[i] {
+			t.Errorf("parsePlaylist()[%d] = %+v, wanted %+v", i, pl[i], want[i])
+		}
+	}
+}
+
+func TestParsePlaylistErrors(t *testing.T) {
+	cases := []struct {
+		in   string
+		want string
+	}{
+		{in: "* text", want: "1: text needs an argument"},
+		{in: "\n*", want: "2: missing the kind of content"},
+		{in: "* dashboard weather", want: `1: unknown kind "dashboard"`},
+		{in: "7-22 clock", want: `1: window "7-22"`},
+	}
+	for _, c := range cases {
+		if _, err := parsePlaylist(strings.NewReader(c.in)); err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("parsePlaylist(%q) = _, %v, wanted error containing %q", c.in, err, c.want)
+		}
+	}
+}
+
+func TestParseWindow(t *testing.T) {
+	cases := []struct {
+		in         string
+		start, end int
+		wantErr    string
+	}{
+		{in: "*"},
+		{in: "07:00-22:00", start: 7 * 60, end: 22 * 60},
+		{in: "22:30-06:15", start: 22*60 + 30, end: 6*60 + 15},
+		{in: "07:00", wantErr: "is not of the form"},
+		{in: "07:00-22:00-23:00", wantErr: "is not of the form"},
+		{in: "07:00-25:00", wantErr: "out of range"},
+		{in: "07:00-07:00", wantErr: "is empty"},
+	}
+	for _, c := range cases {
+		start, end, err := parseWindow(c.in)
+		if c.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), c.wantErr) {
+				t.Errorf("parseWindow(%q) = _, _, %v, wanted error containing %q", c.in, err, c.wantErr)
+			}
+			continue
+		}
+		if err != nil || start != c.start || end != c.end {
+			t.Errorf("parseWindow(%q) = %d, %d, %v, wanted %d, %d, nil", c.in, start, end, err, c.start, c.end)
+		}
+	}
+}
+
+func TestEntryIncludes(t *testing.T) {
+	day := entry{start: 7 * 60, end: 22 * 60}
+	night := entry{start: 22 * 60, end: 7 * 60}
+	all := entry{}
+	cases := []struct {
+		e    entry
+		m    int
+		want bool
+	}{
+		{e: day, m: 7 * 60, want: true},
+		{e: day, m: 22*60 - 1, want: true},
+		{e: day, m: 22 * 60, want: false},
+		{e: day, m: 0, want: false},
+		{e: night, m: 22 * 60, want: true},
+		{e: night, m: 0, want: true},
+		{e: night, m: 7*60 - 1, want: true},
+		{e: night, m: 7 * 60, want: false},
+		{e: night, m: 12 * 60, want: false},
+		{e: all, m: 0, want: true},
+		{e: all, m: 24*60 - 1, want: true},
+	}
+	for _, c := range cases {
+		if got := c.e.includes(c.m); got != c.want {
+			t.Errorf("entry{start: %d, end: %d}.includes(%d) = %t, wanted %t", c.e.start, c.e.end, c.m, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Binary wsslideshow cycles through the images in a directory on a waveshare display.
+//
+// Each image is fit to the display and dithered to the display's three colors. Files that
+// cannot be decoded as images are skipped. Refreshes are slow, so -interval is the minimum
+// time each image is shown, measured from the start of its refresh.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/disintegration/imaging"
+	"github.com/makeworld-the-better-one/dither"
+	"github.com/toothrot/gink/devices/epd7in5bhd"
+)
+
+var (
+	dir      = flag.String("dir", ".", "Directory of images to display.")
+	interval = flag.Duration("interval", 5*time.Minute, "Minimum time to show each image.")
+	shuffle  = flag.Bool("shuffle", false, "Shuffle the images on each pass through the directory.")
+	rotate   = flag.Float64("rotate", 0.0, "Image rotation in degrees.")
+	cacheMB  = flag.Int("cache", 16, "Megabytes of converted images to keep, so they are not converted again on the next pass.")
+	resample = flag.String("resample", "lanczos", "Filter used to scale images to the display: lanczos, linear, or nearest.")
+	footer   = flag.String("footer", "", "Caption drawn along the bottom of each image. {file} is replaced by the image's file name, and {time} by the time it is shown.")
+)
+
+func main() {
+	flag.Parse()
+	rand.Seed(time.Now().UnixNano())
+	rs, err := epd7in5bhd.ParseResample(*resample)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fit := epd7in5bhd.WithResample(rs)
+	d, err := epd7in5bhd.New(epd7in5bhd.DefaultPins)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Caught before Init, so that a signal during startup waits for it rather than killing
+	// the process partway through.
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+
+	log.Println("Initializing")
+	if err := d.Init(); err != nil {
+		log.Fatal(err)
+	}
+	defer d.Sleep()
+	log.Println("Clearing")
+	d.Clear()
+
+	cache := d.NewFrameCache(*cacheMB << 20)
+
+	for {
+		// The directory is re-read on every pass, so images can be added and removed while running.
+		paths, err := imagePaths(*dir)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if *shuffle {
+			rand.Shuffle(len(paths), func(i, j int) { paths[i], paths[j] = paths[j], paths[i] })
+		}
+		var shown int
+		for _, p := range paths {
+			img, err := cachedImage(d, cache, p, fit)
+			if err != nil {
+				log.Printf("Skipping %q: %v", p, err)
+				continue
+			}
+			shown++
+			d.Footer = strings.NewReplacer("{file}", filepath.Base(p), "{time}", time.Now().Format("2006-01-02 15:04")).Replace(*footer)
+			log.Printf("Displaying %q", p)
+			start := time.Now()
+			d.DrawAndRefresh(img)
+			select {
+			case s := <-c:
+				log.Printf("Got signal %q, quitting", s.String())
+				d.Clear()
+				return
+			case <-time.After(*interval - time.Since(start)):
+			}
+		}
+		if shown == 0 {
+			log.Fatalf("No images found in %q", *dir)
+		}
+	}
+}
+
+// imagePaths returns the paths of the regular files in dir, sorted by name.
+func imagePaths(dir string) ([]string, error) {
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, fi := range fis {
+		if fi.Mode().IsRegular() {
+			paths = append(paths, filepath.Join(dir, fi.Name()))
+		}
+	}
+	return paths, nil
+}
+
+// cacheKey identifies a version of an image file.
+type cacheKey struct {
+	path    string
+	modTime time.Time
+}
+
+// cachedImage returns the image at path from cache, loading it with loadImage if it is not
+// cached or the file has changed.
+func cachedImage(d *epd7in5bhd.Display, cache *epd7in5bhd.FrameCache, path string, fit epd7in5bhd.FitOption) (image.Image, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	img, err := cache.Convert(cacheKey{path: path, modTime: fi.ModTime()}, func() (image.Image, error) {
+		return loadImage(d, path, fit)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return img, nil
+}
+
+// loadImage decodes the image at path, then fits it to d with fit and dithers it.
+func loadImage(d *epd7in5bhd.Display, path string, fit epd7in5bhd.FitOption) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("image.Decode() = %w", err)
+	}
+	rot := imaging.Rotate(img, *rotate, color.White)
+	final := d.FitCentered(rot, fit)
+
+	dith := dither.NewDitherer([]color.Color{color.White, color.RGBA{255, 0, 0, 255}, color.Black})
+	dith.Matrix = dither.FloydSteinberg
+	dith.Serpentine = true
+	return dith.DitherPaletted(final), nil
+}
@@ -0,0 +1,75 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Binary wsticker displays a long message on a waveshare display, one page at a time.
+//
+// E-paper cannot scroll smoothly, so text that does not fit on one screen is word-wrapped
+// into pages, and the display advances to the next page every interval, looping forever.
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/toothrot/gink/devices/epd7in5bhd"
+	"github.com/toothrot/gink/render"
+)
+
+var (
+	text     = flag.String("text", "Hello, world!", "Text to display.")
+	interval = flag.Duration("interval", 3*time.Minute, "Time to show each page.")
+	size     = flag.Float64("size", 64, "Font size in points.")
+	margin   = flag.Int("margin", 40, "Margin around the text in pixels.")
+)
+
+func main() {
+	flag.Parse()
+	d, err := epd7in5bhd.New(epd7in5bhd.DefaultPins)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	opts := render.TextOptions{Size: *size, Margin: *margin}
+	pages, err := render.Pages(d.Size(), *text, opts)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(pages) == 0 {
+		log.Fatal("-text is empty")
+	}
+	log.Printf("Paginated text into %d pages", len(pages))
+
+	// Caught from the start, so that a signal never interrupts a refresh.
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+
+	log.Println("Initializing")
+	if err := d.Init(); err != nil {
+		log.Fatal(err)
+	}
+	defer d.Sleep()
+	log.Println("Clearing")
+	d.Clear()
+
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	for i := 0; ; i = (i + 1) % len(pages) {
+		log.Printf("Displaying page %d of %d", i+1, len(pages))
+		if err := d.DrawString(pages[i], opts); err != nil {
+			log.Fatal(err)
+		}
+		d.Refresh()
+		select {
+		case s := <-c:
+			log.Printf("Got signal %q, quitting", s.String())
+			d.Clear()
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package epd7in5bc
+
+type command byte
+
+const (
+	panelSetting               command = 0x00
+	powerSetting               command = 0x01
+	powerOff                   command = 0x02
+	powerOn                    command = 0x04
+	boosterSoftStart           command = 0x06
+	deepSleep                  command = 0x07
+	dataStartTransmission1     command = 0x10
+	displayRefresh             command = 0x12
+	pllControl                 command = 0x30
+	vcomAndDataIntervalSetting command = 0x50
+	tconSetting                command = 0x60
+	tconResolution             command = 0x61
+	flashControl               command = 0x65
+	vcmDCSetting               command = 0x82
+	flashMode                  command = 0xE5
+)
@@ -0,0 +1,241 @@
+// Package epd7in5bc drives the original 640x384 Waveshare 7.5 inch b/c e-Paper display.
+//
+// Unlike the HD panel, this panel's controller takes a single frame with 4 bits per pixel,
+// two pixels to a byte, rather than separate black and highlight planes. Images are still
+// drawn into an epd7in5bhd.Image, which is packed when the display is refreshed.
+package epd7in5bc
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/toothrot/gink/devices/epd7in5bhd"
+	"github.com/toothrot/gink/devices/internal/driver"
+	"golang.org/x/image/draw"
+	"periph.io/x/periph/conn/gpio"
+)
+
+const (
+	// Device width in pixels.
+	DisplayWidth = 640
+	// Device height in pixels.
+	DisplayHeight = 384
+	// Size of a frame on the wire in bytes, at 2 pixels per byte.
+	BufSize = DisplayWidth * DisplayHeight / 2
+)
+
+var (
+	DisplayBounds = image.Rect(0, 0, DisplayWidth, DisplayHeight)
+)
+
+// Pixel values on the wire.
+const (
+	wireBlack     = 0x0
+	wireWhite     = 0x3
+	wireHighlight = 0x4
+)
+
+// Pins are the names of the pins used by the display. The HAT is wired the same way as the
+// HD panel's.
+type Pins = epd7in5bhd.Pins
+
+// DefaultPins are the standard pin locations of the HAT.
+var DefaultPins = epd7in5bhd.DefaultPins
+
+// Display is a client for the e-Paper display. Its methods are safe for concurrent use.
+type Display struct {
+	// mu guards the buffer and serializes the command sequences sent to the panel.
+	mu     sync.Mutex
+	hw     *driver.Hardware
+	buffer *epd7in5bhd.Image
+}
+
+// New creates a Display configured for use.
+func New(p Pins) (*Display, error) {
+	return NewContext(context.Background(), p)
+}
+
+// NewContext is like New, but gives up acquiring the pins and SPI bus once ctx is done.
+func NewContext(ctx context.Context, p Pins) (*Display, error) {
+	hw, err := driver.Open(ctx, driver.Pins(p), "", 0)
+	if err != nil {
+		return nil, err
+	}
+	return &Display{
+		hw:     hw,
+		buffer: epd7in5bhd.NewImage(DisplayBounds),
+	}, nil
+}
+
+// Size returns the width and height of the display in pixels.
+func (d *Display) Size() image.Point {
+	return d.buffer.Bounds().Size()
+}
+
+// Bounds returns the bounds of the display buffer.
+func (d *Display) Bounds() image.Rectangle {
+	return d.buffer.Bounds()
+}
+
+func (d *Display) sendCommand(cmd command, data ...byte) error {
+	n, err := d.hw.CommandWriter().Write(append([]byte{byte(cmd)}, data...))
+	if err != nil {
+		return fmt.Errorf("sendCommand(%#02x) Write() = %d, %w", byte(cmd), n, err)
+	}
+	return nil
+}
+
+// waitUntilIdle waits for the busy pin to be high. The panel holds it low while busy.
+func (d *Display) waitUntilIdle() {
+	for d.hw.Busy().Read() == gpio.Low {
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// initStep is a command sent to the panel during initialization.
+type initStep struct {
+	cmd  command
+	data []byte
+}
+
+// initSteps configure the panel after a reset.
+var initSteps = []initStep{
+	{cmd: powerSetting, data: []byte{0x37, 0x00}},
+	{cmd: panelSetting, data: []byte{0xCF, 0x08}},
+	// PLL: 0-15C: 0x3C, 15C+: 0x3A
+	{cmd: pllControl, data: []byte{0x3A}},
+	// All temperature ranges.
+	{cmd: vcmDCSetting, data: []byte{0x28}},
+	{cmd: boosterSoftStart, data: []byte{0xC7, 0xCC, 0x15}},
+	{cmd: vcomAndDataIntervalSetting, data: []byte{0x77}},
+	{cmd: tconSetting, data: []byte{0x22}},
+	{cmd: flashControl, data: []byte{0x00}},
+	{cmd: tconResolution, data: []byte{DisplayWidth >> 8, DisplayWidth & 0xFF, DisplayHeight >> 8, DisplayHeight & 0xFF}},
+	{cmd: flashMode, data: []byte{0x03}},
+}
+
+// Reset resets the panel. It can also be used to awaken the device after a call to Sleep.
+func (d *Display) Reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.reset()
+}
+
+func (d *Display) reset() {
+	d.hw.RST().Out(gpio.High)
+	time.Sleep(200 * time.Millisecond)
+	d.hw.RST().Out(gpio.Low)
+	time.Sleep(2 * time.Millisecond)
+	d.hw.RST().Out(gpio.High)
+	time.Sleep(200 * time.Millisecond)
+}
+
+// Init resets and initializes the display. It should be used if the device is asleep and
+// needs reinitialization.
+func (d *Display) Init() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.reset()
+	for _, s := range initSteps {
+		if err := d.sendCommand(s.cmd, s.data...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Clear clears the screen.
+func (d *Display) Clear() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.buffer.Reset()
+	return d.refresh()
+}
+
+// Draw draws an image to the display buffer in 3 colors (black, white and red/yellow). Colors
+// are matched as they are by epd7in5bhd.Model.
+func (d *Display) Draw(img image.Image) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	draw.Draw(d.buffer, d.buffer.Bounds(), img, image.Point{}, draw.Src)
+}
+
+// Refresh uploads the buffer to the display and waits for it to be shown.
+func (d *Display) Refresh() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.refresh()
+}
+
+func (d *Display) refresh() error {
+	if err := d.sendCommand(dataStartTransmission1, pack(d.buffer)...); err != nil {
+		return err
+	}
+	if err := d.sendCommand(powerOn); err != nil {
+		return err
+	}
+	d.waitUntilIdle()
+	if err := d.sendCommand(displayRefresh); err != nil {
+		return err
+	}
+	time.Sleep(100 * time.Millisecond)
+	d.waitUntilIdle()
+	return nil
+}
+
+// DrawAndRefresh is a convenience method for Draw and Refresh.
+func (d *Display) DrawAndRefresh(img image.Image) error {
+	d.Draw(img)
+	return d.Refresh()
+}
+
+// Sleep powers off the panel and puts the controller into deep sleep.
+//
+// The display can be reawakened with Reset, and re-initialized with Init.
+func (d *Display) Sleep() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.sendCommand(powerOff); err != nil {
+		log.Print(err)
+		return
+	}
+	d.waitUntilIdle()
+	if err := d.sendCommand(deepSleep, 0xA5); err != nil {
+		log.Print(err)
+	}
+}
+
+// pack converts img to the panel's wire format: 4 bits per pixel, with the leftmost pixel of
+// each pair in the high bits.
+func pack(img *epd7in5bhd.Image) []byte {
+	r := img.Bounds()
+	buf := make([]byte, 0, (r.Dx()*r.Dy()+1)/2)
+	var b byte
+	n := 0
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			var v byte
+			switch img.ColorIndexAt(x, y) {
+			case 0:
+				v = wireWhite
+			case 1:
+				v = wireBlack
+			case 2:
+				v = wireHighlight
+			}
+			b = b<<4 | v
+			if n++; n%2 == 0 {
+				buf = append(buf, b)
+				b = 0
+			}
+		}
+	}
+	if n%2 != 0 {
+		buf = append(buf, b<<4|wireWhite)
+	}
+	return buf
+}
@@ -0,0 +1,59 @@
+package epd7in5bc
+
+import (
+	"bytes"
+	"image"
+	"testing"
+
+	"github.com/toothrot/gink/devices/epd7in5bhd"
+	"github.com/toothrot/gink/devices/internal/driver/drivertest"
+)
+
+func TestPack(t *testing.T) {
+	img := epd7in5bhd.NewImage(image.Rect(0, 0, 6, 1))
+	img.Set(1, 0, epd7in5bhd.Black)
+	img.Set(2, 0, epd7in5bhd.Highlight)
+	img.Set(5, 0, epd7in5bhd.Black)
+
+	want := []byte{0x30, 0x43, 0x30}
+	if got := pack(img); !bytes.Equal(got, want) {
+		t.Errorf("pack() = % x, wanted % x", got, want)
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	hw, bus := drivertest.NewHardware()
+	d := &Display{hw: hw, buffer: epd7in5bhd.NewImage(DisplayBounds)}
+	if err := d.Refresh(); err != nil {
+		t.Fatalf("Refresh() = %v, wanted no error", err)
+	}
+	got := bus.Commands()
+	want := []command{dataStartTransmission1, powerOn, displayRefresh}
+	if len(got) != len(want) {
+		t.Fatalf("Refresh() sent %d commands, wanted %d", len(got), len(want))
+	}
+	for i, c := range want {
+		if command(got[i].Cmd) != c {
+			t.Errorf("command %d = %#02x, wanted %#02x", i, got[i].Cmd, byte(c))
+		}
+	}
+	if data := got[0].Data; len(data) != BufSize || data[0] != wireWhite<<4|wireWhite {
+		t.Errorf("Refresh() sent %d bytes of frame data, wanted %d white pixels", len(data), BufSize)
+	}
+}
+
+func TestInit(t *testing.T) {
+	hw, bus := drivertest.NewHardware()
+	d := &Display{hw: hw, buffer: epd7in5bhd.NewImage(DisplayBounds)}
+	if err := d.Init(); err != nil {
+		t.Fatalf("Init() = %v, wanted no error", err)
+	}
+	got := bus.Commands()
+	if len(got) != len(initSteps) {
+		t.Fatalf("Init() sent %d commands, wanted %d", len(got), len(initSteps))
+	}
+	res := got[len(got)-2]
+	if command(res.Cmd) != tconResolution || !bytes.Equal(res.Data, []byte{0x02, 0x80, 0x01, 0x80}) {
+		t.Errorf("Init() sent resolution %#02x % X, wanted %#02x 02 80 01 80", res.Cmd, res.Data, byte(tconResolution))
+	}
+}
@@ -0,0 +1,47 @@
+package epd7in5bhd
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestDrawText(t *testing.T) {
+	cases := []struct {
+		desc          string
+		c             color.Color
+		wantBlack     []byte
+		wantHighlight []byte
+	}{
+		{
+			desc: "black",
+			c:    color.Black,
+			// Rows 0 and 1 of "AB", then the empty row 7.
+			wantBlack:     []byte{0x8C, 0x3F, 0x75, 0xDF, 0xFF, 0xFF},
+			wantHighlight: []byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+		},
+		{
+			desc:          "highlight",
+			c:             Highlight,
+			wantBlack:     []byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF},
+			wantHighlight: []byte{0x73, 0xC0, 0x8A, 0x20, 0x00, 0x00},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			img := NewImage(image.Rect(0, 0, 16, GlyphHeight))
+			img.DrawText(0, 0, "AB", c.c)
+			for j, row := range []int{0, 1, 7} {
+				for k := 0; k < 2; k++ {
+					idx := row*2 + k
+					if got, want := img.Black[idx], c.wantBlack[j*2+k]; got != want {
+						t.Errorf("img.Black[%d] = %08b, wanted %08b", idx, got, want)
+					}
+					if got, want := img.Highlight[idx], c.wantHighlight[j*2+k]; got != want {
+						t.Errorf("img.Highlight[%d] = %08b, wanted %08b", idx, got, want)
+					}
+				}
+			}
+		})
+	}
+}
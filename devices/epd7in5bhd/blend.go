@@ -0,0 +1,60 @@
+package epd7in5bhd
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/makeworld-the-better-one/dither"
+)
+
+// BlendFrames mixes a and b at blend factor t, from 0 for all of a to 1 for all of b, and
+// dithers the mix to the display's colors. Showing one or two blends between two frames
+// approximates a crossfade, such as in a slideshow.
+//
+// The result has the bounds of a. b is aligned by its top left corner, and is white wherever
+// it does not cover a. t is clamped to between 0 and 1.
+//
+// Each blend is drawn with a refresh of its own, which costs as much as showing b, so a
+// transition of n blends takes n+1 full refreshes.
+func BlendFrames(a, b image.Image, t float64) image.Image {
+	if t < 0 {
+		t = 0
+	}
+	if t > 1 {
+		t = 1
+	}
+	r := a.Bounds()
+	off := b.Bounds().Min.Sub(r.Min)
+	mix := image.NewNRGBA(r)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			var bc color.Color = color.White
+			if p := image.Pt(x, y).Add(off); p.In(b.Bounds()) {
+				bc = b.At(p.X, p.Y)
+			}
+			mix.SetNRGBA(x, y, blend(a.At(x, y), bc, t))
+		}
+	}
+	d := dither.NewDitherer([]color.Color(defaultPalette))
+	d.Matrix = dither.FloydSteinberg
+	d.Serpentine = true
+	return d.DitherPaletted(mix)
+}
+
+// blend returns the opaque color (1-t)*a + t*b, with any transparency in a or b composited over
+// white.
+func blend(a, b color.Color, t float64) color.NRGBA {
+	ar, ag, ab := overWhite(a)
+	br, bg, bb := overWhite(b)
+	mix := func(x, y float64) uint8 {
+		return uint8(((1-t)*x + t*y) / 0x101)
+	}
+	return color.NRGBA{mix(ar, br), mix(ag, bg), mix(ab, bb), 0xff}
+}
+
+// overWhite returns the 16-bit channels of c composited over white.
+func overWhite(c color.Color) (r, g, b float64) {
+	cr, cg, cb, ca := c.RGBA()
+	bg := float64(0xffff - ca)
+	return float64(cr) + bg, float64(cg) + bg, float64(cb) + bg
+}
@@ -0,0 +1,54 @@
+package epd7in5bhd
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/disintegration/imaging"
+)
+
+func TestBlendFrames(t *testing.T) {
+	black := imaging.New(100, 100, color.Black)
+	white := imaging.New(100, 100, color.White)
+	// The blend is dithered to match its brightness rather than its sRGB value, so black
+	// pixels fall with t, but not linearly.
+	last := 10001
+	for _, f := range []float64{-1, 0, 0.25, 0.5, 0.75, 1, 2} {
+		got := BlendFrames(black, white, f)
+		if got.Bounds() != black.Bounds() {
+			t.Errorf("BlendFrames(%v).Bounds() = %v, wanted %v", f, got.Bounds(), black.Bounds())
+		}
+		h := Histogram(got)
+		if h[2] != 0 {
+			t.Errorf("BlendFrames(%v) has %d highlight pixels, wanted none", f, h[2])
+		}
+		switch {
+		case f <= 0 && h[1] != 10000:
+			t.Errorf("BlendFrames(%v) has %d black pixels, wanted all 10000", f, h[1])
+		case f >= 1 && h[1] != 0:
+			t.Errorf("BlendFrames(%v) has %d black pixels, wanted none", f, h[1])
+		case f > 0 && f < 1 && (h[1] == 0 || h[1] == 10000 || h[1] >= last):
+			t.Errorf("BlendFrames(%v) has %d black pixels, wanted a mix with fewer than %d", f, h[1], last)
+		}
+		if f > 0 {
+			last = h[1]
+		}
+	}
+}
+
+func TestBlendFramesBounds(t *testing.T) {
+	a := imaging.New(20, 10, color.Black)
+	// b is smaller, and offset, so it is aligned to a's corner and the rest is white.
+	b := image.NewRGBA(image.Rect(5, 5, 15, 15))
+	for i := range b.Pix {
+		b.Pix[i] = 0xff
+	}
+	got := BlendFrames(a, b, 1)
+	if h := Histogram(got); h[0] != 200 {
+		t.Errorf("BlendFrames(a, b, 1) has %v white, black, and highlight pixels, wanted all 200 white", h)
+	}
+	if _, ok := got.(*image.Paletted); !ok {
+		t.Errorf("BlendFrames() = %T, wanted *image.Paletted so that Draw can use its fast paths", got)
+	}
+}
@@ -0,0 +1,116 @@
+package epd7in5bhd
+
+import (
+	"container/list"
+	"image"
+	"sync"
+)
+
+// FrameCache holds converted frames, so that a frame that is shown again, such as in a
+// slideshow or a dashboard that cycles through a fixed set of screens, is not scaled, dithered,
+// and converted each time. The least recently used frames are evicted once the cache holds more
+// than its limit.
+//
+// A FrameCache is safe for concurrent use.
+type FrameCache struct {
+	mu       sync.Mutex
+	maxBytes int
+	size     int
+	// order holds *frameEntry values, most recently used first.
+	order   *list.List
+	entries map[interface{}]*list.Element
+	// draw converts a built frame into dst, which has the frame's bounds.
+	draw func(dst *Image, src image.Image)
+}
+
+type frameEntry struct {
+	key interface{}
+	img *Image
+}
+
+// NewFrameCache returns a FrameCache that holds up to maxBytes of frames. Each full-screen
+// frame is BufSize*2 bytes, about 116KB. A frame larger than maxBytes is returned, but not kept.
+func NewFrameCache(maxBytes int) *FrameCache {
+	return &FrameCache{
+		maxBytes: maxBytes,
+		order:    list.New(),
+		entries:  make(map[interface{}]*list.Element),
+		draw:     func(dst *Image, src image.Image) { drawImage(dst, src) },
+	}
+}
+
+// NewFrameCache is like the package's NewFrameCache, but frames are converted as d draws
+// them, with colors matched as set by HighlightColor, HighlightMargin, and HighlightMatches,
+// and transparent pixels composited over Background. Frames are not flipped; Draw flips them
+// by Mirror and FlipVertical as it copies them.
+func (d *Display) NewFrameCache(maxBytes int) *FrameCache {
+	c := NewFrameCache(maxBytes)
+	c.draw = func(dst *Image, src image.Image) {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		bg, err := d.background()
+		if err != nil {
+			d.logf("FrameCache: %v, using white", err)
+		}
+		d.drawOver(dst, src, bg)
+	}
+	return c
+}
+
+// Convert returns the frame cached for key. If there is none, it calls build, converts the
+// result as Convert does, or as the Display does for a cache from Display.NewFrameCache, and
+// caches it.
+//
+// key must be comparable, and should identify everything that build's result depends on, such
+// as a file path and modification time, or a source image pointer along with the bounds it is
+// fit to. The returned Image is shared with the cache, so it must not be modified. Drawing it
+// with Draw copies it without converting colors again.
+func (c *FrameCache) Convert(key interface{}, build func() (image.Image, error)) (*Image, error) {
+	c.mu.Lock()
+	if e, ok := c.entries[key]; ok {
+		c.order.MoveToFront(e)
+		c.mu.Unlock()
+		return e.Value.(*frameEntry).img, nil
+	}
+	c.mu.Unlock()
+
+	// Building is slow, so it is done without the lock. Concurrent misses for the same key may
+	// both build it.
+	src, err := build()
+	if err != nil {
+		return nil, err
+	}
+	img := NewImage(src.Bounds())
+	c.draw(img, src)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[key]; ok {
+		c.order.MoveToFront(e)
+		return e.Value.(*frameEntry).img, nil
+	}
+	c.entries[key] = c.order.PushFront(&frameEntry{key: key, img: img})
+	c.size += frameBytes(img)
+	for c.size > c.maxBytes && c.order.Len() > 0 {
+		c.remove(c.order.Back())
+	}
+	return img, nil
+}
+
+// Len returns the number of frames in the cache.
+func (c *FrameCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+func (c *FrameCache) remove(e *list.Element) {
+	fe := c.order.Remove(e).(*frameEntry)
+	delete(c.entries, fe.key)
+	c.size -= frameBytes(fe.img)
+}
+
+// frameBytes returns the size of img's planes.
+func frameBytes(img *Image) int {
+	return len(img.Black) + len(img.Highlight)
+}
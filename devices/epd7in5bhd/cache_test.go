@@ -0,0 +1,94 @@
+package epd7in5bhd
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestFrameCache(t *testing.T) {
+	r := image.Rect(0, 0, 16, 2)
+	frame := NewImage(r)
+	// Room for 2 frames of 16x2, which are 8 bytes each.
+	c := NewFrameCache(2 * 8)
+	var builds int
+	build := func() (image.Image, error) {
+		builds++
+		src := image.NewRGBA(r)
+		src.Set(0, 0, color.Black)
+		return src, nil
+	}
+
+	first, err := c.Convert("a", build)
+	if err != nil {
+		t.Fatalf("Convert(%q) = _, %v, wanted no error", "a", err)
+	}
+	if got := first.At(0, 0); got != Black {
+		t.Errorf("Convert(%q).At(0, 0) = %v, wanted %v", "a", got, Black)
+	}
+	again, _ := c.Convert("a", build)
+	if again != first || builds != 1 {
+		t.Errorf("Convert(%q) again built %d times, wanted 1 and the cached frame", "a", builds)
+	}
+
+	c.Convert("b", build)
+	// "a" was used more recently than "b", so "b" is evicted.
+	c.Convert("a", build)
+	c.Convert("c", build)
+	if c.Len() != 2 {
+		t.Errorf("Len() = %d, wanted 2", c.Len())
+	}
+	builds = 0
+	c.Convert("a", build)
+	if builds != 0 {
+		t.Errorf("Convert(%q) was rebuilt, wanted it kept as the most recently used", "a")
+	}
+	c.Convert("b", build)
+	if builds != 1 {
+		t.Errorf("Convert(%q) built %d times, wanted 1 after it was evicted", "b", builds)
+	}
+
+	wantErr := errors.New("no image")
+	if _, err := c.Convert("d", func() (image.Image, error) { return nil, wantErr }); err != wantErr {
+		t.Errorf("Convert(%q) = _, %v, wanted %v", "d", err, wantErr)
+	}
+
+	// A cached frame is drawn by copying its planes.
+	d := &Display{buffer: frame}
+	d.Draw(first)
+	if !d.buffer.Equal(first) {
+		t.Errorf("Draw() of a cached frame = %x, wanted %x", d.buffer.Black, first.Black)
+	}
+}
+
+func TestFrameCacheTooLarge(t *testing.T) {
+	c := NewFrameCache(1)
+	img, err := c.Convert("a", func() (image.Image, error) { return image.NewRGBA(image.Rect(0, 0, 8, 1)), nil })
+	if err != nil || img == nil {
+		t.Fatalf("Convert() = %v, %v, wanted a frame", img, err)
+	}
+	if c.Len() != 0 {
+		t.Errorf("Len() = %d after a frame larger than the cache, wanted 0", c.Len())
+	}
+}
+
+func TestDisplayFrameCache(t *testing.T) {
+	yellow := HighlightColors["yellow"]
+	d := &Display{buffer: NewImage(DisplayBounds), HighlightColor: yellow}
+	c := d.NewFrameCache(1 << 20)
+	img, err := c.Convert("yellow", func() (image.Image, error) {
+		src := image.NewRGBA(image.Rect(0, 0, 8, 1))
+		src.Set(0, 0, yellow)
+		src.Set(1, 0, color.Black)
+		return src, nil
+	})
+	if err != nil {
+		t.Fatalf("Convert() = _, %v, wanted no error", err)
+	}
+	for x, want := range []Color{Highlight, Black, White} {
+		if got := img.At(x, 0); got != want {
+			t.Errorf("Convert().At(%d, 0) = %v, wanted %v", x, got, want)
+		}
+	}
+}
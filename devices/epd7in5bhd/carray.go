@@ -0,0 +1,67 @@
+package epd7in5bhd
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"io"
+)
+
+// carrayBytesPerLine is how many bytes EncodeCArray writes per line, as the vendor examples do.
+const carrayBytesPerLine = 16
+
+// EncodeCArray writes img to w as C source, for comparing against the vendor's C examples or
+// embedding a frame in firmware. The planes are written as name_black and name_red, in the
+// format of the vendor's images: in both planes a 0 bit is a black or red pixel. This is
+// Encode's format with InvertHighlight, as the vendor's display inverts the red image as it
+// sends it, like UploadRaw. name must be a valid C identifier.
+func EncodeCArray(w io.Writer, name string, img image.Image) error {
+	if !isCIdentifier(name) {
+		return fmt.Errorf("invalid C identifier %q", name)
+	}
+	black, red := Convert(img)
+	invert(black, red, []EncodeOption{InvertHighlight()})
+	r := img.Bounds()
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "// %dx%d, one bit per pixel, rows padded to a whole byte.\n", r.Dx(), r.Dy())
+	writeCArray(bw, name+"_black", black)
+	bw.WriteString("\n")
+	writeCArray(bw, name+"_red", red)
+	return bw.Flush()
+}
+
+// writeCArray writes b to w as a C array declaration.
+func writeCArray(w *bufio.Writer, name string, b []byte) {
+	fmt.Fprintf(w, "const unsigned char %s[%d] = {\n", name, len(b))
+	for i := 0; i < len(b); i += carrayBytesPerLine {
+		w.WriteString("\t")
+		end := i + carrayBytesPerLine
+		if end > len(b) {
+			end = len(b)
+		}
+		for j := i; j < end; j++ {
+			fmt.Fprintf(w, "0x%02x,", b[j])
+			if j < end-1 {
+				w.WriteString(" ")
+			}
+		}
+		w.WriteString("\n")
+	}
+	w.WriteString("};\n")
+}
+
+// isCIdentifier reports whether s is a valid C identifier.
+func isCIdentifier(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, c := range s {
+		switch {
+		case c == '_', 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z':
+		case '0' <= c && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,57 @@
+package epd7in5bhd
+
+import (
+	"bytes"
+	"image"
+	"strings"
+	"testing"
+)
+
+func TestEncodeCArray(t *testing.T) {
+	img := NewImage(image.Rect(0, 0, 9, 2))
+	img.Reset()
+	img.SetColorIndex(0, 0, 1)
+	img.SetColorIndex(8, 1, 2)
+
+	var buf bytes.Buffer
+	if err := EncodeCArray(&buf, "frame", img); err != nil {
+		t.Fatalf("EncodeCArray() = %v, wanted no error", err)
+	}
+	want := strings.Join([]string{
+		"// 9x2, one bit per pixel, rows padded to a whole byte.",
+		"const unsigned char frame_black[4] = {",
+		"\t0x7f, 0xff, 0xff, 0xff,",
+		"};",
+		"",
+		"const unsigned char frame_red[4] = {",
+		"\t0xff, 0xff, 0xff, 0x7f,",
+		"};",
+		"",
+	}, "\n")
+	if got := buf.String(); got != want {
+		t.Errorf("EncodeCArray() = %q, wanted %q", got, want)
+	}
+
+	for _, name := range []string{"", "1frame", "my-frame", "frame[0]"} {
+		if err := EncodeCArray(&buf, name, img); err == nil {
+			t.Errorf("EncodeCArray(_, %q, _) = nil, wanted error", name)
+		}
+	}
+}
+
+func TestEncodeCArrayLineLength(t *testing.T) {
+	img := NewImage(image.Rect(0, 0, 8*carrayBytesPerLine+8, 1))
+	img.Reset()
+	var buf bytes.Buffer
+	if err := EncodeCArray(&buf, "frame", img); err != nil {
+		t.Fatalf("EncodeCArray() = %v, wanted no error", err)
+	}
+	lines := strings.Split(buf.String(), "\n")
+	// The black plane's 17 bytes fill one line and start another.
+	if got := strings.Count(lines[2], "0x"); got != carrayBytesPerLine {
+		t.Errorf("EncodeCArray() line 3 has %d bytes, wanted %d: %q", got, carrayBytesPerLine, lines[2])
+	}
+	if lines[3] != "\t0xff," {
+		t.Errorf("EncodeCArray() line 4 = %q, wanted %q", lines[3], "\t0xff,")
+	}
+}
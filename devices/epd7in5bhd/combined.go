@@ -0,0 +1,113 @@
+package epd7in5bhd
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+)
+
+// combinedMagic starts each frame of the combined format written by EncodeCombined.
+const combinedMagic = "EPDC"
+
+// combinedHeaderSize is the size of a combined frame's header: the magic, then the width and
+// height in pixels as big-endian uint16s.
+const combinedHeaderSize = len(combinedMagic) + 4
+
+// combinedFrameSize is the size of a combined frame the size of the display.
+const combinedFrameSize = combinedHeaderSize + 2*BufSize
+
+// EncodeCombined writes img to w as a single frame of the combined format, which Display.Writer
+// accepts: a header of the four bytes "EPDC" and img's width and height in pixels as big-endian
+// uint16s, followed by the black plane and the highlight plane as Encode writes them. Colors
+// are matched as Convert matches them, against a red highlight plane.
+//
+// Frames can be concatenated, such as to stream them through a pipe. Display.Writer only
+// accepts frames the size of the display, such as of an image from FitCentered.
+func EncodeCombined(w io.Writer, img image.Image) error {
+	r := img.Bounds()
+	if r.Dx() > 0xffff || r.Dy() > 0xffff {
+		return fmt.Errorf("image of %dx%d pixels is too large to encode", r.Dx(), r.Dy())
+	}
+	header := make([]byte, combinedHeaderSize)
+	copy(header, combinedMagic)
+	binary.BigEndian.PutUint16(header[len(combinedMagic):], uint16(r.Dx()))
+	binary.BigEndian.PutUint16(header[len(combinedMagic)+2:], uint16(r.Dy()))
+	black, red := Convert(img)
+	for _, b := range [][]byte{header, black, red} {
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkCombinedHeader returns an error if h is not the header of a combined frame the size of
+// the display.
+func checkCombinedHeader(h []byte) error {
+	if string(h[:len(combinedMagic)]) != combinedMagic {
+		return errors.New("frame does not start with the combined format's header")
+	}
+	w := binary.BigEndian.Uint16(h[len(combinedMagic):])
+	ht := binary.BigEndian.Uint16(h[len(combinedMagic)+2:])
+	if int(w) != DisplayWidth || int(ht) != DisplayHeight {
+		return fmt.Errorf("frame is %dx%d pixels, wanted the display's %dx%d", w, ht, DisplayWidth, DisplayHeight)
+	}
+	return nil
+}
+
+// Writer returns a Writer that accepts frames in the combined format written by
+// EncodeCombined, and shows each one with Upload as soon as it is complete. This lets another
+// process stream frames to the display, such as with io.Copy from a pipe or socket.
+//
+// Frames may be split across writes, or several may arrive in one. The header of each frame
+// is checked as soon as it arrives. After a bad header or a failed Upload, the Writer returns
+// that error from every write. Each Writer buffers a frame, about 116KB, and is not safe for
+// concurrent use.
+func (d *Display) Writer() io.Writer {
+	return &frameWriter{d: d}
+}
+
+// frameWriter is returned by Display.Writer.
+type frameWriter struct {
+	d *Display
+	// frame holds the part of the current frame written so far.
+	frame []byte
+	err   error
+}
+
+func (w *frameWriter) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	if w.frame == nil {
+		w.frame = make([]byte, 0, combinedFrameSize)
+	}
+	n := 0
+	for len(p) > 0 {
+		want := combinedFrameSize
+		if len(w.frame) < combinedHeaderSize {
+			want = combinedHeaderSize
+		}
+		k := want - len(w.frame)
+		if k > len(p) {
+			k = len(p)
+		}
+		w.frame = append(w.frame, p[:k]...)
+		p = p[k:]
+		n += k
+		switch len(w.frame) {
+		case combinedHeaderSize:
+			w.err = checkCombinedHeader(w.frame)
+		case combinedFrameSize:
+			planes := w.frame[combinedHeaderSize:]
+			w.err = w.d.Upload(planes[:BufSize], planes[BufSize:])
+			w.frame = w.frame[:0]
+		}
+		if w.err != nil {
+			return n, w.err
+		}
+	}
+	return n, nil
+}
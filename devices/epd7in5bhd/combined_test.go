@@ -0,0 +1,86 @@
+package epd7in5bhd
+
+import (
+	"bytes"
+	"image"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestWriter(t *testing.T) {
+	first := image.NewPaletted(DisplayBounds, defaultPalette)
+	first.SetColorIndex(0, 0, 1)
+	second := image.NewPaletted(DisplayBounds, defaultPalette)
+	second.SetColorIndex(9, 0, 2)
+	var stream bytes.Buffer
+	for _, img := range []image.Image{first, second} {
+		if err := EncodeCombined(&stream, img); err != nil {
+			t.Fatalf("EncodeCombined() = %v", err)
+		}
+	}
+	if stream.Len() != 2*combinedFrameSize {
+		t.Fatalf("EncodeCombined() wrote %d bytes, wanted %d", stream.Len(), 2*combinedFrameSize)
+	}
+
+	hw, bus := newFakeHardware()
+	d := &Display{hw: hw, buffer: NewImage(DisplayBounds), SettleDelay: -1}
+	// Split the frames across writes of a byte at a time.
+	if _, err := io.Copy(d.Writer(), iotest.OneByteReader(&stream)); err != nil {
+		t.Fatalf("io.Copy() = %v", err)
+	}
+	var wantBlack, wantRed [][]byte
+	for _, img := range []image.Image{first, second} {
+		black, red := Convert(img)
+		wantBlack, wantRed = append(wantBlack, black), append(wantRed, red)
+	}
+	var gotBlack, gotRed [][]byte
+	for _, c := range bus.commands() {
+		switch c.cmd {
+		case writeRAMBW:
+			gotBlack = append(gotBlack, c.data)
+		case writeRAMRed:
+			gotRed = append(gotRed, c.data)
+		}
+	}
+	if len(gotBlack) != 2 || len(gotRed) != 2 {
+		t.Fatalf("sent %d black and %d red planes, wanted 2 of each", len(gotBlack), len(gotRed))
+	}
+	for i := range wantBlack {
+		if !bytes.Equal(gotBlack[i], wantBlack[i]) || !bytes.Equal(gotRed[i], wantRed[i]) {
+			t.Errorf("frame %d was not uploaded as encoded", i)
+		}
+	}
+}
+
+func TestWriterBadHeader(t *testing.T) {
+	small := image.NewPaletted(image.Rect(0, 0, 8, 8), defaultPalette)
+	var wrongSize bytes.Buffer
+	if err := EncodeCombined(&wrongSize, small); err != nil {
+		t.Fatalf("EncodeCombined() = %v", err)
+	}
+	cases := []struct {
+		desc    string
+		data    []byte
+		wantErr string
+	}{
+		{desc: "not combined", data: bytes.Repeat([]byte{0xFF}, BufSize), wantErr: "header"},
+		{desc: "wrong size", data: wrongSize.Bytes(), wantErr: "8x8"},
+	}
+	for _, c := range cases {
+		hw, bus := newFakeHardware()
+		d := &Display{hw: hw, buffer: NewImage(DisplayBounds)}
+		w := d.Writer()
+		n, err := w.Write(c.data)
+		if err == nil || !strings.Contains(err.Error(), c.wantErr) || n != combinedHeaderSize {
+			t.Errorf("%s: Write() = %d, %v, wanted %d and an error containing %q", c.desc, n, err, combinedHeaderSize, c.wantErr)
+		}
+		if _, err2 := w.Write([]byte{0}); err2 != err {
+			t.Errorf("%s: Write() after an error = _, %v, wanted %v", c.desc, err2, err)
+		}
+		if cmds := bus.commands(); len(cmds) != 0 {
+			t.Errorf("%s: sent %d commands, wanted none", c.desc, len(cmds))
+		}
+	}
+}
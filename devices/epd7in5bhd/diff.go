@@ -0,0 +1,35 @@
+package epd7in5bhd
+
+import (
+	"bytes"
+)
+
+// Equal reports whether i and other have the same bounds and pixels.
+func (i *Image) Equal(other *Image) bool {
+	return i.Rect == other.Rect && bytes.Equal(i.Black, other.Black) && bytes.Equal(i.Highlight, other.Highlight)
+}
+
+// DiffImage compares a and b pixel by pixel over the union of their bounds, and returns an
+// image of the differences along with the number of pixels that differ.
+//
+// In the returned image, pixels that differ are Highlight. Pixels that match are drawn as they
+// appear in a, except that highlight pixels are drawn Black so that only differences stand out.
+// Pixels outside of one image's bounds are compared as White.
+func DiffImage(a, b *Image) (*Image, int) {
+	r := a.Rect.Union(b.Rect)
+	diff := NewImage(r)
+	var n int
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			ai, bi := a.ColorIndexAt(x, y), b.ColorIndexAt(x, y)
+			switch {
+			case ai != bi:
+				diff.SetColorIndex(x, y, 2)
+				n++
+			case ai != 0:
+				diff.SetColorIndex(x, y, 1)
+			}
+		}
+	}
+	return diff, n
+}
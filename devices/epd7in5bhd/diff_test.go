@@ -0,0 +1,46 @@
+package epd7in5bhd
+
+import (
+	"image"
+	"testing"
+)
+
+func TestDiffImage(t *testing.T) {
+	r := image.Rect(0, 0, 16, 4)
+	a, b := NewImage(r), NewImage(r)
+	if !a.Equal(b) {
+		t.Errorf("a.Equal(b) = false for two new images, wanted true")
+	}
+	if _, n := DiffImage(a, b); n != 0 {
+		t.Errorf("DiffImage(a, b) = _, %d, wanted 0", n)
+	}
+
+	a.SetColorIndex(1, 1, 1)
+	b.SetColorIndex(1, 1, 1)
+	a.SetColorIndex(3, 2, 2)
+	b.SetColorIndex(9, 3, 1)
+	if a.Equal(b) {
+		t.Errorf("a.Equal(b) = true, wanted false")
+	}
+	diff, n := DiffImage(a, b)
+	if n != 2 {
+		t.Errorf("DiffImage(a, b) = _, %d, wanted 2", n)
+	}
+	for _, c := range []struct {
+		pt   image.Point
+		want uint8
+	}{
+		{image.Pt(1, 1), 1},
+		{image.Pt(3, 2), 2},
+		{image.Pt(9, 3), 2},
+		{image.Pt(0, 0), 0},
+	} {
+		if got := diff.ColorIndexAt(c.pt.X, c.pt.Y); got != c.want {
+			t.Errorf("diff.ColorIndexAt(%d, %d) = %d, wanted %d", c.pt.X, c.pt.Y, got, c.want)
+		}
+	}
+
+	if a.Equal(NewImage(image.Rect(0, 0, 8, 4))) {
+		t.Errorf("a.Equal() = true for different bounds, wanted false")
+	}
+}
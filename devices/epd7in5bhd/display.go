@@ -7,13 +7,21 @@ package epd7in5bhd
 
 import (
 	"bytes"
+	"context"
+	"errors"
+	"fmt"
 	"image"
 	"image/color"
-	"log"
+	"io"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/toothrot/gink/devices/internal/driver"
+	"github.com/toothrot/gink/render"
 	"golang.org/x/image/draw"
 	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/physic"
 )
 
 const (
@@ -40,9 +48,132 @@ var (
 //  DC   - Data/Cmd  - Pin 22 (GPIO 25)
 //  DIN  - SPI0 MOSI - Pin 19 (GPIO 10)
 //  RST  - Reset     - Pin 11 (GPIO 17)
+//
+// Mirror and FlipVertical are applied while pixels are packed into the buffer,
+// so they affect every subsequent Draw. Rotation by SetScanDirection happens as
+// the buffer is shown, so the effective order is flip, then rotate.
+//
+// Display methods are safe for concurrent use. Set its fields before sharing it.
 type Display struct {
-	hw *hardware
-	buffer *Image
+	// metrics is first so that its 64-bit counters are aligned for atomic access on 32-bit
+	// platforms, such as older Raspberry Pis.
+	metrics metrics
+
+	// Mirror flips drawn images horizontally, for panels viewed through a mirror.
+	Mirror bool
+	// FlipVertical flips drawn images vertically.
+	FlipVertical bool
+	// MinRefreshInterval is the minimum time between the start of one refresh and the next.
+	// A refresh that would start sooner waits until the interval has passed. Zero means no
+	// minimum.
+	MinRefreshInterval time.Duration
+	// SettleDelay is how long the panel is given to settle on each side of a reset pulse. Zero
+	// means DefaultSettleDelay, and a negative value means no delay.
+	//
+	// The default is conservative. Shorter delays make Init and Reset faster on panels and wiring
+	// that tolerate them, but a delay that is too short causes intermittent failures, such as a
+	// panel that ignores its init sequence. The pause after the busy pin reports idle is the
+	// lesser of 10ms and SettleDelay.
+	SettleDelay time.Duration
+	// Background is the color that Clear fills the display with, and that fills any part of
+	// the display not covered by a drawn image. It must be White, Black, Highlight, or a color
+	// equal to one of them as the panel shows it, such as color.Black or HighlightColor. Nil
+	// means White.
+	Background color.Color
+	// HighlightColor is the color that the panel's highlight plane shows, such as one of
+	// HighlightColors. Drawn colors are matched against it, along with white and black, so it
+	// should be set to match the panel before drawing. Nil means red.
+	HighlightColor color.Color
+	// HighlightMatches, if set, changes how colors are matched by Draw, DrawAt, and
+	// DrawAndRefresh: any color within HighlightThreshold of one of them is drawn as
+	// Highlight, and every other color as Black or White as set by LuminanceThreshold. This
+	// keeps gradients and several shades of red, such as in a logo, in the highlight plane.
+	// *Image sources are drawn as they are.
+	HighlightMatches []color.Color
+	// HighlightThreshold is the largest euclidean distance between the 8-bit RGB values of a
+	// color and one of HighlightMatches for the color to be drawn as Highlight. Zero means
+	// DefaultHighlightThreshold.
+	HighlightThreshold int
+	// HighlightMargin, if set, changes how Draw matches colors in images that it matches a
+	// pixel at a time, such as photos: a pixel is drawn as Highlight if the channels that are
+	// high in HighlightColor, such as red, or red and green for yellow, all exceed the others
+	// by at least HighlightMargin in 8-bit steps, and as Black or White by LuminanceThreshold
+	// otherwise. 128, the middle of the range, draws saturated reds on a red panel as
+	// Highlight, while dark reds, pinks, and browns are drawn by their luminance. Zero means
+	// colors are matched to the nearest of white, black, and HighlightColor.
+	HighlightMargin int
+	// LuminanceThreshold is the luma, from 1 to 255, below which a pixel that is not drawn as
+	// Highlight is drawn as Black rather than White, when HighlightMatches or HighlightMargin is
+	// set. Zero means DefaultLuminanceThreshold.
+	LuminanceThreshold int
+	// InvertPlanes swaps the two planes as they are written to the panel, sending the black
+	// plane to the highlight RAM and the highlight plane to the black RAM, in Upload and
+	// everything that uploads a full frame, such as Refresh and Clear. It is meant for
+	// diagnosing panels that show a negative or swapped colors because of wiring or
+	// configuration, without converting images differently.
+	InvertPlanes bool
+	// Footer is a caption, such as a timestamp, drawn with the bitmap font in a strip along the
+	// bottom of every image drawn by Draw and DrawAndRefresh, covering that part of the image.
+	// Empty means no footer.
+	Footer string
+	// FooterScale is the size of each pixel of the footer's font. Zero means DefaultFooterScale.
+	FooterScale int
+	// FooterAlign is the horizontal alignment of the footer.
+	FooterAlign render.Align
+
+	// mu guards the buffer and the state below, and serializes the command sequences sent to
+	// the panel. Exported methods hold it; unexported methods expect it to be held.
+	mu sync.Mutex
+	// hw is also guarded by hwMu, so that Busy can read it without waiting for mu. Both are
+	// held to replace it.
+	hw          *driver.Hardware
+	hwMu        sync.Mutex
+	buffer      *Image
+	stats       RefreshStats
+	lastRefresh time.Time
+	q           queue
+	voltages    *drivingVoltages
+	vcom        *byte
+	// scratch is reused by conversions that need an intermediate Image.
+	scratch *Image
+	scan    ScanDirection
+	// refreshMode selects the waveform used by turnOnDisplay.
+	refreshMode RefreshMode
+	// masks are the regions that are always uploaded as white.
+	masks []image.Rectangle
+	// speed is the SPI clock requested from the driver. Zero means driver.DefaultSpeed.
+	speed physic.Frequency
+	// reopen acquires new hardware for Reconnect.
+	reopen func(context.Context) (*driver.Hardware, error)
+	// hwClosed is set when a reconnect closed hw but could not replace it.
+	hwClosed  bool
+	reconnect reconnectPolicy
+	logger    Logger
+	// watchdog is the longest wait for the panel to be idle. Zero means no limit.
+	watchdog time.Duration
+	// simulated is set when New fell back to a simulator, as allowed by WithSimulator.
+	simulated bool
+
+	// history holds the most recently uploaded frames, up to its capacity. historyNext is the
+	// index of the oldest frame once history is full.
+	history     []*Image
+	historyNext int
+}
+
+// RefreshStats records how long each phase of the most recent refresh took.
+//
+// The upload durations are bound by the SPI clock, while Wait is the time the panel itself
+// took to redraw and cannot be tuned.
+type RefreshStats struct {
+	// BlackUpload is the time spent sending the black/white plane.
+	BlackUpload time.Duration
+	// HighlightUpload is the time spent sending the red/yellow plane.
+	HighlightUpload time.Duration
+	// Wait is the time spent waiting for the panel to finish refreshing.
+	Wait time.Duration
+	// Recovered reports whether the watchdog set by WithWatchdog reinitialized the panel to
+	// complete the refresh. Wait is the time taken by the retry.
+	Recovered bool
 }
 
 type Pins struct {
@@ -70,177 +201,958 @@ var DefaultWait = 25 * time.Second
 //
 // dcPin, csPin, rstPin, and busyPin all expect valid gpioreg.ByName() values, such as P1_22.
 //
+// On a host without display hardware, New returns an error wrapping ErrNoHardware, unless
+// WithSimulator is given.
+//
 //  d, err := epd7in5bhd.New("P1_22", "P1_24", "P1_11", "P1_18")
 //  if err != nil {
 //    // Handle error.
 //  }
-func New(p Pins) (*Display, error) {
-	hw, err := newHardware(p)
+func New(p Pins, opts ...Option) (*Display, error) {
+	return NewContext(context.Background(), p, opts...)
+}
+
+// NewContext is like New, but gives up acquiring the pins and SPI bus once ctx is done. This
+// bounds startup when another process still holds the bus, such as a previous instance that
+// is shutting down.
+//
+// If ctx is done first, the returned error wraps ctx.Err(), and the pins and port are
+// released if they are acquired later.
+func NewContext(ctx context.Context, p Pins, opts ...Option) (*Display, error) {
+	o := options{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	d := &Display{
+		buffer:    NewImage(DisplayBounds),
+		reconnect: o.reconnect,
+		logger:    o.logger,
+		watchdog:  o.watchdog,
+	}
+	speed, txLimit := o.speed, o.txLimit
+	if speed == 0 {
+		speed = physic.Frequency(d.envInt(EnvSPIHz)) * physic.Hertz
+	}
+	if txLimit == 0 {
+		txLimit = int(d.envInt(EnvTxLimit))
+	}
+	hw, err := driver.Open(ctx, driver.Pins(p), o.port, speed)
+	if errors.Is(err, ErrNoHardware) && o.simulator {
+		d.logf("Using a simulated display: %v", err)
+		hw, err = driver.NewSimulator(), nil
+		d.simulated = true
+	}
 	if err != nil {
 		return nil, err
 	}
-	return &Display{
-		hw: hw,
-		buffer: NewImage(DisplayBounds),
-	}, nil
+	if txLimit != 0 {
+		if err := hw.SetTxLimit(txLimit); err != nil {
+			hw.Close()
+			return nil, err
+		}
+	}
+	hw.SetHoldCS(o.holdCS)
+	if o.tap != nil {
+		hw.SetTap(protocolTap(o.tap))
+	}
+	d.hw = hw
+	d.speed = speed
+	d.reopen = func(ctx context.Context) (*driver.Hardware, error) {
+		if d.simulated {
+			return driver.NewSimulator(), nil
+		}
+		return driver.Open(ctx, driver.Pins(p), o.port, speed)
+	}
+	if o.history > 0 {
+		d.history = make([]*Image, 0, o.history)
+	}
+	return d, nil
+}
+
+// Option configures optional behavior of a Display created by New.
+type Option func(*options)
+
+type options struct {
+	history   int
+	holdCS    bool
+	reconnect reconnectPolicy
+	logger    Logger
+	watchdog  time.Duration
+	speed     physic.Frequency
+	txLimit   int
+	port      string
+	simulator bool
+	tap       io.Writer
+}
+
+// WithHistory keeps the last n uploaded frames for debugging, available from History.
+//
+// Each frame holds a copy of both color planes, which is BufSize*2 (about 116KB) for this
+// display, so n frames cost about n*116KB for the life of the Display. History is off by
+// default.
+func WithHistory(n int) Option {
+	return func(o *options) {
+		o.history = n
+	}
+}
+
+// WithChipSelectHeld keeps chip select asserted from each command through the end of its
+// data, instead of releasing it between the two.
+//
+// Some SSD16xx controllers expect a command and its data in a single chip select frame. Try
+// this option if refreshes are intermittently corrupted, especially with longer cables.
+func WithChipSelectHeld() Option {
+	return func(o *options) {
+		o.holdCS = true
+	}
+}
+
+// History returns the most recently uploaded frames, oldest first. It returns nil unless the
+// Display was created with WithHistory.
+//
+// Frames are image.Images, so they can be written out with png.Encode to inspect a glitch.
+func (d *Display) History() []*Image {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.history) == 0 {
+		return nil
+	}
+	frames := make([]*Image, 0, len(d.history))
+	frames = append(frames, d.history[d.historyNext:]...)
+	return append(frames, d.history[:d.historyNext]...)
+}
+
+// record adds a frame to the history, replacing the oldest frame if it is full.
+func (d *Display) record(black, highlight []byte) {
+	if cap(d.history) == 0 {
+		return
+	}
+	frame := NewImage(DisplayBounds)
+	frame.Black, frame.Highlight = black, highlight
+	if len(d.history) < cap(d.history) {
+		d.history = append(d.history, frame)
+		return
+	}
+	d.history[d.historyNext] = frame
+	d.historyNext = (d.historyNext + 1) % len(d.history)
+}
+
+// Size returns the width and height of the display in pixels.
+//
+// Prefer Size and Bounds to the DisplayWidth and DisplayHeight constants when fitting
+// images, so code keeps working with other orientations and models.
+func (d *Display) Size() image.Point {
+	return d.buffer.Bounds().Size()
+}
+
+// Bounds returns the bounds of the display buffer.
+func (d *Display) Bounds() image.Rectangle {
+	return d.buffer.Bounds()
 }
 
 // Reset clears all variables set on the Display.
 //
 // Reset can be also used to awaken the device after a call to Sleep.
 func (d *Display) Reset() {
-	d.hw.rst.Out(gpio.High)
-	time.Sleep(200 * time.Millisecond)
-	d.hw.rst.Out(gpio.Low)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.reset()
+}
+
+func (d *Display) reset() {
+	d.hw.RST().Out(gpio.High)
+	time.Sleep(d.settleDelay())
+	d.hw.RST().Out(gpio.Low)
 	time.Sleep(2 * time.Millisecond)
-	d.hw.rst.Out(gpio.High)
-	time.Sleep(200 * time.Millisecond)
+	d.hw.RST().Out(gpio.High)
+	time.Sleep(d.settleDelay())
+}
+
+// DefaultSettleDelay is the default for Display.SettleDelay.
+const DefaultSettleDelay = 200 * time.Millisecond
+
+// settleDelay returns the effective SettleDelay.
+func (d *Display) settleDelay() time.Duration {
+	switch {
+	case d.SettleDelay == 0:
+		return DefaultSettleDelay
+	case d.SettleDelay < 0:
+		return 0
+	}
+	return d.SettleDelay
+}
+
+// SetTxLimit sets the maximum number of bytes sent to the display in a single SPI transfer.
+// The default is 2048.
+//
+// Larger transfers are more efficient on some kernels, but the Linux spidev driver rejects any
+// transfer larger than its bufsiz module parameter, which is 4096 bytes unless configured
+// otherwise (see /sys/module/spidev/parameters/bufsiz). To use a larger limit, raise bufsiz as
+// well, for example with spidev.bufsiz=65536 on the kernel command line.
+func (d *Display) SetTxLimit(n int) error {
+	return d.hw.SetTxLimit(n)
 }
 
-func (d *Display) sendCommand(cmd command, data ...byte) {
-	n, err := d.hw.CommandWriter().Write(append([]byte{byte(cmd)}, data...))
+// sendCommand sends cmd followed by data. Errors are logged as well as returned, once any
+// reconnects allowed by WithAutoReconnect have failed.
+func (d *Display) sendCommand(cmd command, data ...byte) error {
+	p := append([]byte{byte(cmd)}, data...)
+	n, err := d.hw.CommandWriter().Write(p)
+	for attempt := 0; err != nil && attempt < d.reconnect.attempts; attempt++ {
+		d.logf("sendCommand Write() = %d, %v; reconnecting", n, err)
+		time.Sleep(d.reconnect.backoff << attempt)
+		if rerr := d.reopenHardware(); rerr != nil {
+			d.logf("reconnect attempt %d: %v", attempt+1, rerr)
+			continue
+		}
+		n, err = d.hw.CommandWriter().Write(p)
+	}
 	if err != nil {
-		log.Printf("sendCommand Write() = %d, %v", n, err)
+		atomic.AddUint64(&d.metrics.failedWrites, 1)
+		d.logf("sendCommand Write() = %d, %v", n, err)
+		return fmt.Errorf("sendCommand(%s) Write() = %d, %w", cmd, n, err)
 	}
+	return nil
 }
 
 // waitUntilIdle waits for the busy pin to be low voltage. It's required after some commands, and should not be
 // called unless necessary.
-func (d *Display)  waitUntilIdle() {
-	for d.hw.busy.Read() == gpio.Low {
-		time.Sleep(10 * time.Millisecond)
+//
+// If a watchdog is set, waitUntilIdle gives up once it has waited that long, and returns an
+// error wrapping ErrRefreshTimeout.
+//
+// The busy pin is set up to detect its rising edge, but the level is read before each wait for
+// an edge, so that a panel that was already idle, or became idle just before the wait, is not
+// waited on. Each wait for an edge times out as the old polling loop did, in case the edge was
+// missed or the pin can't detect edges.
+func (d *Display) waitUntilIdle() error {
+	start := time.Now()
+	for d.hw.Busy().Read() == gpio.Low {
+		if d.watchdog > 0 && time.Since(start) > d.watchdog {
+			atomic.AddUint64(&d.metrics.watchdogTimeouts, 1)
+			return fmt.Errorf("busy pin %v still low after %v: %w", d.hw.Busy(), d.watchdog, ErrRefreshTimeout)
+		}
+		d.hw.Busy().WaitForEdge(10 * time.Millisecond)
+	}
+	settle := 10 * time.Millisecond
+	if s := d.settleDelay(); s < settle {
+		settle = s
+	}
+	time.Sleep(settle)
+	return nil
+}
+
+// Busy reports whether the panel is busy, such as in the middle of a refresh. It does not wait
+// for other Display methods, so it can be polled while a refresh is in progress, for example to
+// show a refreshing indicator.
+//
+// The panel holds its busy pin low while it is busy.
+func (d *Display) Busy() bool {
+	d.hwMu.Lock()
+	hw := d.hw
+	d.hwMu.Unlock()
+	return hw.Busy().Read() == gpio.Low
+}
+
+// pace waits until MinRefreshInterval has passed since the previous refresh started, and then
+// records the start of a new one.
+func (d *Display) pace() {
+	if wait := d.MinRefreshInterval - time.Since(d.lastRefresh); !d.lastRefresh.IsZero() && wait > 0 {
+		time.Sleep(wait)
 	}
-	time.Sleep(10 * time.Millisecond)
+	d.lastRefresh = time.Now()
 }
 
 // As far as I can tell this actually triggers a draw.
-func (d *Display) turnOnDisplay() {
+func (d *Display) turnOnDisplay() error {
+	start := time.Now()
 	// Load LUT from MCU(0x32)
-	d.sendCommand(displayUpdateControl2, 0xC7)
-	d.sendCommand(masterActivation)
+	if err := d.sendCommand(displayUpdateControl2, d.refreshMode.updateControl()); err != nil {
+		return err
+	}
+	if err := d.sendCommand(masterActivation); err != nil {
+		return err
+	}
 	time.Sleep(2 * time.Millisecond) //!!!The delay here is necessary, 200uS at least!!!
-	d.waitUntilIdle()                //waiting for the electronic paper IC to release the idle signal
+	//waiting for the electronic paper IC to release the idle signal
+	if err := d.waitUntilIdle(); err != nil {
+		return err
+	}
+	d.metrics.refreshed(time.Since(start))
+	return nil
 }
 
-// Init initializes the display config. It should be used if the device is asleep and needs reinitialization.
-func (d *Display) Init() {
-	d.Reset()
+// MeasureRefresh redraws the panel from its RAM and returns how long the refresh took, from
+// activation until the busy pin reports idle.
+//
+// Refresh time varies between panels and with temperature (colder panels are slower), so
+// the result can be used in place of DefaultWait. An error is returned if the busy pin never
+// reports a refresh in progress, since the measurement would be meaningless. The refresh uses
+// the mode set by SetRefreshMode.
+func (d *Display) MeasureRefresh() (time.Duration, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	start := time.Now()
+	d.sendCommand(displayUpdateControl2, d.refreshMode.updateControl())
+	d.sendCommand(masterActivation)
+	time.Sleep(2 * time.Millisecond)
+	if d.hw.Busy().Read() != gpio.Low {
+		return 0, fmt.Errorf("busy pin %v did not report a refresh in progress", d.hw.Busy())
+	}
+	if err := d.waitUntilIdle(); err != nil {
+		return 0, err
+	}
+	d.stats.Wait = time.Since(start)
+	d.metrics.refreshed(d.stats.Wait)
+	return d.stats.Wait, nil
+}
 
-	d.sendCommand(displayRefresh)
-	d.waitUntilIdle()
+// initStep is a command sent to the panel during initialization, optionally followed by a wait
+// for the panel to be idle.
+type initStep struct {
+	cmd      command
+	data     []byte
+	waitIdle bool
+}
+
+// The initialization sequences for this panel. Init sends resetSteps, clearRAMSteps, and
+// configureSteps in that order, and InitFast skips clearRAMSteps. A panel with the same
+// controller but a different resolution or color set needs only new tables.
+var (
+	resetSteps = []initStep{
+		{cmd: displayRefresh, waitIdle: true},
+	}
 
-	d.sendCommand(autoWriteRamRed, 0xF7)
-	d.waitUntilIdle()
-	d.sendCommand(autoWriteRamBW, 0xF7)
-	d.waitUntilIdle()
+	clearRAMSteps = []initStep{
+		{cmd: autoWriteRamRed, data: []byte{0xF7}, waitIdle: true},
+		{cmd: autoWriteRamBW, data: []byte{0xF7}, waitIdle: true},
+	}
+
+	configureSteps = []initStep{
+		{cmd: softStart, data: []byte{0xAE, 0xC7, 0xC3, 0xC0, 0x40}},
+		// set MUX as 527
+		{cmd: setGateDriver, data: []byte{0xAF, 0x02, 0x01}},
+		{cmd: dataEntryMode, data: []byte{0x01}},
+		// RAM x address starts at 0
+		// RAM x address ends at 36Fh -> 879
+		{cmd: setRamXStart, data: []byte{0x00, 0x00, 0x6F, 0x03}},
+		// RAM y address starts at 20Fh
+		// RAM y address ends at 00h
+		{cmd: setRamYStart, data: []byte{0xAF, 0x02, 0x00, 0x00}},
+		// VBD, LUT1 for white.
+		{cmd: borderWaveformControl, data: []byte{0x01}},
+		{cmd: tempSensorControl, data: []byte{0x80}},
+		//Load Temperature and waveform setting.
+		{cmd: displayUpdateControl2, data: []byte{0xB1}},
+		{cmd: masterActivation, waitIdle: true},
+		{cmd: setRamXAddressCtr, data: []byte{0x00, 0x00}},
+		{cmd: setRamYAddressCtr, data: []byte{0xAF, 0x02}},
+	}
+)
 
-	d.sendCommand(softStart, 0xAE, 0xC7, 0xC3, 0xC0, 0x40)
+// runSteps sends each step in order. It stops at the first command that fails to send, or the
+// first wait that times out.
+func (d *Display) runSteps(steps []initStep) error {
+	for _, s := range steps {
+		if err := d.sendCommand(s.cmd, s.data...); err != nil {
+			return err
+		}
+		if s.waitIdle {
+			if err := d.waitUntilIdle(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
 
-	// set MUX as 527
-	d.sendCommand(setGateDriver, 0xAF, 0x02, 0x01)
+// Init initializes the display config. It should be used if the device is asleep and needs reinitialization.
+//
+// It returns an error if a command cannot be sent, or one wrapping ErrRefreshTimeout if the
+// panel stays busy for longer than the watchdog set by WithWatchdog. In either case the panel
+// is not ready to draw to.
+func (d *Display) Init() error {
+	defer func(start time.Time) {
+		d.logf("Init: %s", time.Since(start).String())
+	}(time.Now())
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.init()
+}
 
-	d.sendCommand(dataEntryMode, 0x01)
+// init resets the panel and sends the full initialization sequence.
+func (d *Display) init() error {
+	d.reset()
+	if err := d.runSteps(resetSteps); err != nil {
+		return err
+	}
+	if err := d.runSteps(clearRAMSteps); err != nil {
+		return err
+	}
+	return d.configure()
+}
 
-	// RAM x address starts at 0
-	// RAM x address ends at 36Fh -> 879
-	d.sendCommand(setRamXStart, 0x00, 0x00, 0x6F, 0x03)
-	// RAM y address starts at 20Fh
-	// RAM y address ends at 00h
-	d.sendCommand(setRamYStart, 0xAF, 0x02, 0x00, 0x00)
+// InitFast is a lighter Init for devices that wake, show a single frame, and sleep again.
+//
+// InitFast omits the automatic pattern writes to the red and black/white RAM that Init
+// performs, along with the two busy waits that follow them. Those writes only put the RAM in a
+// known state; every Upload overwrites the full RAM anyway. The reset, driver configuration,
+// and temperature and waveform load are unchanged, as a full refresh depends on them.
+//
+// If images start to show ghosting or artifacts, call Init before the next refresh. A
+// periodic full Init (for example, once a day) is recommended on long-running devices.
+//
+// Errors are returned as they are by Init.
+func (d *Display) InitFast() error {
+	defer func(start time.Time) {
+		d.logf("InitFast: %s", time.Since(start).String())
+	}(time.Now())
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.reset()
+	if err := d.runSteps(resetSteps); err != nil {
+		return err
+	}
+	return d.configure()
+}
 
-	// VBD, LUT1 for white.
-	d.sendCommand(borderWaveformControl, 0x01)
+// SoftReset resets the controller with its software reset command, and then sends the same
+// configuration as InitFast, without pulsing the reset pin or clearing the panel's RAM.
+//
+// The software reset returns every setting to its default, just as Reset does, so the
+// configuration must be sent again either way. SoftReset is the cheaper choice for a panel
+// that still accepts commands but is stuck or misconfigured, such as after a refresh that
+// never completed or a bad RawCommand. Use Reset followed by Init for a panel that ignores
+// commands, or that is in deep sleep, which only the reset pin wakes it from. If only the RAM
+// window or address counters are wrong, ResetWindow is enough.
+func (d *Display) SoftReset() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.runSteps(resetSteps); err != nil {
+		return err
+	}
+	return d.configure()
+}
 
-	d.sendCommand(tempSensorControl, 0x80)
-	//Load Temperature and waveform setting.
-	d.sendCommand(displayUpdateControl2, 0xB1)
-	d.sendCommand(masterActivation)
-	d.waitUntilIdle()
+// ResetWindow restores the full RAM window and moves the address counters to its start, as
+// configured by Init. No other settings are changed.
+//
+// Uploads and refreshes expect the full window, so call ResetWindow after using RawCommand to
+// change it, or after an upload that returned an error.
+func (d *Display) ResetWindow() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.resetWindow()
+}
 
-	d.sendCommand(setRamXAddressCtr, 0x00, 0x00)
-	d.sendCommand(setRamYAddressCtr, 0xAF, 0x02)
+// configure sends the driver, RAM, and waveform settings shared by Init and InitFast, followed
+// by any voltages set on the Display. The voltages come after the waveform load, which would
+// otherwise replace them with the values from OTP.
+func (d *Display) configure() error {
+	if err := d.runSteps(configureSteps); err != nil {
+		return err
+	}
+	if d.scan != ScanNormal {
+		d.sendScanDirection()
+	}
+	d.sendVoltages()
+	return nil
 }
 
 // Clear clears the screen.
-func (d *Display) Clear() {
-	d.buffer.Reset()
-	d.Refresh()
+func (d *Display) Clear() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	bg, err := d.background()
+	if err != nil {
+		return err
+	}
+	d.buffer.fill(bg)
+	return d.refresh()
+}
+
+// background returns Background as a native Color.
+func (d *Display) background() (Color, error) {
+	if d.Background == nil {
+		return White, nil
+	}
+	if c, ok := d.Background.(Color); ok && c.C <= 2 {
+		return c, nil
+	}
+	c := d.palette().Convert(d.Background)
+	r0, g0, b0, a0 := c.RGBA()
+	r1, g1, b1, a1 := d.Background.RGBA()
+	if r0 != r1 || g0 != g1 || b0 != b1 || a0 != a1 {
+		return White, fmt.Errorf("background %v is not white, black, or the highlight color", d.Background)
+	}
+	return nativeColor(c), nil
 }
 
 // Upload updates the screen from the provided io.ByteReaders.
 //
 // The epd7in5bhd does not support partial refreshes. If the provided buffer is
-// smaller than the image, then the rest will be filled with white.
+// smaller than the image, then the rest will be filled with white. If it is
+// larger than BufSize, it is truncated to BufSize and an error is returned after
+// the truncated image is shown.
 //
 // The epd7in5bhd expects a bit per pixel for each color.
 //
-// For blackImg, 0b1 is a black pixel, and 0b0 is a white pixel. For redImg,
+// For blackImg, 0b0 is a black pixel, and 0b1 is a white pixel, as Encode writes it. For redImg,
 // 0b1 is a red pixel, and 0b0 is a not-red pixel (no change will occur).
 //
 // Black will always be drawn on the screen before red.
-func (d *Display) Upload(blackImg, redImg []byte) {
-	d.sendCommand(setRamYAddressCtr, 0xAF, 0x02)
+//
+// The time taken by each step is available from LastRefreshStats.
+func (d *Display) Upload(blackImg, redImg []byte) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.upload(blackImg, redImg)
+}
+
+func (d *Display) upload(blackImg, redImg []byte) error {
+	d.pace()
+	return d.sendFrame(blackImg, redImg)
+}
+
+// sendFrame uploads both planes and refreshes the panel, as upload does, without waiting for
+// MinRefreshInterval.
+func (d *Display) sendFrame(blackImg, redImg []byte) error {
+	var err error
+	if len(blackImg) > BufSize || len(redImg) > BufSize {
+		err = fmt.Errorf("Upload() got %d black and %d red bytes, truncated to BufSize %d", len(blackImg), len(redImg), BufSize)
+		d.logf("%v", err)
+	}
+	if d.InvertPlanes {
+		blackImg, redImg = redImg, blackImg
+	}
+	black, uerr := d.uploadBlack(blackImg)
+	if uerr != nil {
+		return uerr
+	}
+	red, uerr := d.uploadHighlight(redImg)
+	if uerr != nil {
+		return uerr
+	}
+	d.record(black, red)
+
+	start := time.Now()
+	werr := d.turnOnDisplay()
+	d.stats.Wait = time.Since(start)
+	d.stats.Recovered = false
+	if werr != nil {
+		werr = d.recoverRefresh(black, red, werr)
+	}
+	if werr != nil {
+		return werr
+	}
+	return err
+}
+
+// UploadBlack writes b to the panel's black RAM without refreshing the display. The highlight
+// RAM keeps whatever was last written to it. b is padded or truncated to BufSize as it is by
+// Upload, and an error is returned if it was truncated.
+//
+// Call Trigger to show the result. Frames uploaded a plane at a time are not recorded in
+// History.
+func (d *Display) UploadBlack(b []byte) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, err := d.uploadBlack(b); err != nil {
+		return err
+	}
+	return checkPlaneSize("UploadBlack", b)
+}
+
+// UploadHighlight writes r to the panel's highlight RAM without refreshing the display, and
+// otherwise behaves like UploadBlack.
+func (d *Display) UploadHighlight(r []byte) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, err := d.uploadHighlight(r); err != nil {
+		return err
+	}
+	return checkPlaneSize("UploadHighlight", r)
+}
+
+// Trigger refreshes the display from the panel's RAM, as loaded by UploadBlack and
+// UploadHighlight.
+func (d *Display) Trigger() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.pace()
+	start := time.Now()
+	err := d.turnOnDisplay()
+	d.stats.Wait = time.Since(start)
+	return err
+}
 
+// uploadBlack writes b to the black RAM, and returns what was sent.
+func (d *Display) uploadBlack(b []byte) ([]byte, error) {
+	if err := d.homeCounters(); err != nil {
+		return nil, err
+	}
+	start := time.Now()
 	// 1 is white, 0 is black.
-	blackPad := bytes.Repeat([]byte{0xFF}, BufSize-len(blackImg))
-	d.sendCommand(writeRAMBW, append(blackImg, blackPad...)...)
+	black := fitBuffer(b, 0xFF)
+	err := d.sendCommand(writeRAMBW, black...)
+	d.stats.BlackUpload = time.Since(start)
+	return black, err
+}
 
+// uploadHighlight writes r to the highlight RAM, and returns what was sent.
+func (d *Display) uploadHighlight(r []byte) ([]byte, error) {
+	if err := d.homeCounters(); err != nil {
+		return nil, err
+	}
+	start := time.Now()
 	// 0 is white or black, 1 is red.
-	redPad := bytes.Repeat([]byte{0x00}, BufSize-len(redImg))
-	d.sendCommand(writeRAMRed, append(redImg, redPad...)...)
-	d.turnOnDisplay()
+	red := fitBuffer(r, 0x00)
+	err := d.sendCommand(writeRAMRed, red...)
+	d.stats.HighlightUpload = time.Since(start)
+	return red, err
+}
+
+// checkPlaneSize returns an error if b was truncated by fitBuffer.
+func checkPlaneSize(method string, b []byte) error {
+	if len(b) > BufSize {
+		return fmt.Errorf("%s() got %d bytes, truncated to BufSize %d", method, len(b), BufSize)
+	}
+	return nil
+}
+
+// fitBuffer returns a copy of b that is exactly BufSize bytes long, truncating b or padding it
+// with fill.
+func fitBuffer(b []byte, fill byte) []byte {
+	if len(b) > BufSize {
+		b = b[:BufSize]
+	}
+	return append(append(make([]byte, 0, BufSize), b...), bytes.Repeat([]byte{fill}, BufSize-len(b))...)
+}
+
+// LastRefreshStats returns the timing of the most recent Upload or Refresh.
+func (d *Display) LastRefreshStats() RefreshStats {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.stats
 }
 
 // Refresh uploads the buffer to the display.
-func (d *Display) Refresh() {
-	d.Upload(d.buffer.Black, d.buffer.Highlight)
+func (d *Display) Refresh() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.refresh()
+}
+
+func (d *Display) refresh() error {
+	return d.upload(d.planes())
 }
 
 // DrawAndRefresh is a convenience method for Draw and Refresh.
-func (d *Display) DrawAndRefresh(img image.Image) {
-	d.Draw(img)
-	d.Refresh()
+func (d *Display) DrawAndRefresh(img image.Image) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.draw(img)
+	return d.refresh()
+}
+
+// RefreshHighlightOnly uploads only the highlight plane of the buffer, and then refreshes the
+// display using the black plane already in the controller's RAM. This halves the upload for
+// overlays, such as a red banner over an otherwise unchanged frame.
+//
+// The controller keeps its RAM between refreshes and through Sleep, which uses deep sleep
+// mode 1, but not through a power cycle. Call Refresh at least once after powering up so that
+// the black plane in RAM matches the buffer; changes to the buffer's black plane are not shown
+// until the next Refresh.
+func (d *Display) RefreshHighlightOnly() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.pace()
+	if err := d.homeCounters(); err != nil {
+		return err
+	}
+
+	start := time.Now()
+	_, red := d.planes()
+	err := d.sendCommand(writeRAMRed, fitBuffer(red, 0x00)...)
+	d.stats.BlackUpload = 0
+	d.stats.HighlightUpload = time.Since(start)
+	if err != nil {
+		return err
+	}
+
+	start = time.Now()
+	err = d.turnOnDisplay()
+	d.stats.Wait = time.Since(start)
+	return err
+}
+
+// ramYTop is the RAM Y address of the top row of the display. Y addresses count down from
+// here, as configured by dataEntryMode, unless the scan direction is flipped.
+const ramYTop = 0x2AF
+
+// RefreshRegion uploads only the part of the buffer within r, and then refreshes the display.
+//
+// The panel always redraws in full, but only the RAM for r is rewritten, so small changes
+// upload faster. r is clipped to the display and widened to whole bytes horizontally. It is in
+// panel coordinates, after Mirror and FlipVertical are applied.
+func (d *Display) RefreshRegion(r image.Rectangle) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	r = r.Intersect(d.buffer.Rect)
+	if r.Empty() {
+		return nil
+	}
+	d.pace()
+	// Byte columns of the region within each row of the buffer.
+	c0, c1 := r.Min.X/8, (r.Max.X+7)/8
+	// The full window is restored even if a write fails, so that later uploads are not
+	// confined to r.
+	defer d.resetWindow()
+	if err := d.setWindow(c0*8, c1*8-1, r.Min.Y, r.Max.Y-1); err != nil {
+		return err
+	}
+
+	bp, rp := d.planes()
+	var black, red []byte
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		row := y * d.buffer.rectWidthBytes
+		black = append(black, bp[row+c0:row+c1]...)
+		red = append(red, rp[row+c0:row+c1]...)
+	}
+	start := time.Now()
+	err := d.sendCommand(writeRAMBW, black...)
+	d.stats.BlackUpload = time.Since(start)
+	if err != nil {
+		return err
+	}
+	start = time.Now()
+	err = d.sendCommand(writeRAMRed, red...)
+	d.stats.HighlightUpload = time.Since(start)
+	if err != nil {
+		return err
+	}
+
+	start = time.Now()
+	err = d.turnOnDisplay()
+	d.stats.Wait = time.Since(start)
+	return err
+}
+
+// setWindow limits RAM writes to the buffer pixels from (x0, y0) to (x1, y1) inclusive, and
+// moves the address counters to (x0, y0). Addresses are sent little-endian, X in pixels, and
+// mapped to RAM addresses according to the scan direction. It stops at the first command that
+// fails to send.
+func (d *Display) setWindow(x0, x1, y0, y1 int) error {
+	xs, xe := d.scan.ramX(x0), d.scan.ramX(x1)
+	ys, ye := d.scan.ramY(y0), d.scan.ramY(y1)
+	if err := d.sendCommand(setRamXStart, byte(xs), byte(xs>>8), byte(xe), byte(xe>>8)); err != nil {
+		return err
+	}
+	if err := d.sendCommand(setRamYStart, byte(ys), byte(ys>>8), byte(ye), byte(ye>>8)); err != nil {
+		return err
+	}
+	if err := d.sendCommand(setRamXAddressCtr, byte(xs), byte(xs>>8)); err != nil {
+		return err
+	}
+	return d.sendCommand(setRamYAddressCtr, byte(ys), byte(ys>>8))
+}
+
+// resetWindow restores the full RAM window and address counters set by configure.
+func (d *Display) resetWindow() error {
+	// configure opens the window down to RAM address 0, past the bottom row of the display.
+	// Counting up, the window starts at the bottom row instead.
+	y1 := ramYTop
+	if d.scan.flipped() {
+		y1 = DisplayHeight - 1
+	}
+	return d.setWindow(0, DisplayWidth-1, 0, y1)
+}
+
+// homeCounters moves the address counters to the start of the full RAM window.
+func (d *Display) homeCounters() error {
+	x, y := d.scan.ramX(0), d.scan.ramY(0)
+	if err := d.sendCommand(setRamXAddressCtr, byte(x), byte(x>>8)); err != nil {
+		return err
+	}
+	return d.sendCommand(setRamYAddressCtr, byte(y), byte(y>>8))
+}
+
+// RawCommand sends cmd followed by data, for experimenting with commands that Display does not
+// otherwise wrap.
+//
+// Warning: RawCommand bypasses everything Display knows about the panel's state. A wrong
+// command or argument can leave the panel misconfigured until the next Init, and driving
+// voltages or waveforms outside of the panel's ratings can permanently damage it. Each command
+// is logged.
+func (d *Display) RawCommand(cmd byte, data ...byte) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	err := d.sendCommand(command(cmd), data...)
+	d.logf("RawCommand(%#02x, % x) = %v", cmd, data, err)
+	return err
 }
 
 // DrawAndRefresh draws an image to the display buffer in 3 colors (black, white and red/yellow).
 //
-// If img is a *image.Paletted with exactly 3 colors, each color will be assigned to its
-// nearest by euclidean distance. Otherwise, colors will be assigned by a per-pixel calculation.
+// Each color is drawn as its nearest by euclidean distance of white, black, and
+// HighlightColor. If img is a *image.Paletted with exactly 3 colors, each color is assigned to
+// a different one of them. Transparent pixels, such as in an *image.NRGBA with an alpha
+// channel, are composited over Background first. HighlightMargin and HighlightMatches change
+// how colors are matched, such as to draw the reds of a photo as Highlight.
+//
+// The image is flipped according to Mirror and FlipVertical as it is drawn. If img does not
+// cover the display, the rest of the display is filled with Background, so drawing an empty
+// image fills all of it.
 func (d *Display) Draw(img image.Image) {
-	if pi, ok := img.(*image.Paletted); ok && len(pi.Palette) == 3 {
-		d.buffer.drawExactColors(pi)
-		return
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.draw(img)
+}
+
+func (d *Display) draw(img image.Image) {
+	bg, err := d.background()
+	if err != nil {
+		d.logf("Draw: %v, using white", err)
+	}
+	if !d.buffer.Rect.In(img.Bounds()) {
+		d.buffer.fill(bg)
+	}
+	d.drawOver(d.target(), img, bg)
+	d.drawFooter(bg)
+}
+
+// DrawAt draws img to the display buffer with its top left corner at pt, leaving the rest of
+// the buffer as it was. Colors are matched as they are by Draw, and transparent pixels are
+// composited over Background. Parts of img that fall outside of the display are not drawn.
+//
+// pt is in the same coordinates as Draw, so the image is flipped according to Mirror and
+// FlipVertical. Like Draw, DrawAt does not refresh the display; call Refresh, or RefreshRegion
+// with the drawn rectangle, to show the result.
+func (d *Display) DrawAt(img image.Image, pt image.Point) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	bg, err := d.background()
+	if err != nil {
+		d.logf("DrawAt: %v, using white", err)
+	}
+	d.drawOver(d.target(), translate(img, pt), bg)
+}
+
+// Overlay draws img over the display buffer with its top left corner at pt, as draw.Draw does
+// with draw.Over. Transparent pixels of img leave the buffer as it was, so text with a
+// transparent background can be drawn over a photo already in the buffer. Partly transparent
+// pixels are blended with the buffer's colors before their colors are matched.
+//
+// pt is in the same coordinates as Draw, and Overlay does not refresh the display.
+func (d *Display) Overlay(img image.Image, pt image.Point) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	src := translate(img, pt)
+	dst := d.target()
+	r := dst.Bounds().Intersect(src.Bounds())
+	draw.Draw(dst, r, src, r.Min, draw.Over)
+}
+
+// DrawFunc calls fn with the display buffer, so a frame can be built up with any drawing
+// operations without first being drawn to a separate image. Like Draw, DrawFunc does not
+// refresh the display; call Refresh to show the result.
+//
+// The buffer is flipped according to Mirror and FlipVertical, and only holds the display's
+// colors, so other colors are matched as they are set. fn runs with the display locked, so it
+// must not call other Display methods, and must not keep the buffer after it returns.
+func (d *Display) DrawFunc(fn func(draw.Image)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	fn(d.target())
+}
+
+// target returns the buffer as seen through the configured flips. Colors set through it are
+// matched against the panel's colors.
+func (d *Display) target() indexedImage {
+	d.buffer.Palette = d.palette()
+	return d.flipped(d.buffer)
+}
+
+// flipped returns img as seen through the configured flips.
+func (d *Display) flipped(img *Image) indexedImage {
+	if !d.Mirror && !d.FlipVertical {
+		return img
 	}
-	draw.Draw(d.buffer, d.buffer.Bounds(), img, image.Point{0, 0}, draw.Src)
+	return &flipped{Image: img, h: d.Mirror, v: d.FlipVertical}
+}
+
+// DrawString draws text to the display buffer, wrapped and aligned according to opts.
+//
+// The embedded font is used unless opts.Face is set. Like Draw, DrawString does not
+// refresh the display; call Refresh to show the result.
+func (d *Display) DrawString(text string, opts render.TextOptions) error {
+	img, err := render.Text(d.Size(), text, opts)
+	if err != nil {
+		return err
+	}
+	d.Draw(img)
+	return nil
 }
 
 // Sleep tells the Display to enter deepSleepMode.
 //
 // The display can be reawakened with Reset(), and re-initialized with Init().
 func (d *Display) Sleep() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
 	d.sendCommand(deepSleepMode, 0x01) //deep sleep
 }
 
-// Convert converts the input image into a byte buffer suitable for Display.Upload.
-func convert(img image.Image, p color.Palette) *Image {
+// convert draws img into dst, flipped according to Mirror and FlipVertical, using only the
+// colors in p. dst's palette is left unchanged.
+func (d *Display) convert(dst *Image, img image.Image, p color.Palette) {
 	now := time.Now()
 	defer func(start time.Time) {
-		log.Printf("Convert: %s", time.Since(start).String())
+		d.logf("Convert: %s", time.Since(start).String())
 	}(now)
-	dst := NewImage(DisplayBounds)
+	palette := dst.Palette
 	dst.Palette = p
-	draw.Draw(dst, dst.Bounds(), img, image.Point{0, 0}, draw.Src)
-	return dst
+	draw.Draw(d.flipped(dst), dst.Bounds(), img, image.Point{0, 0}, draw.Src)
+	dst.Palette = palette
+}
+
+// scratchImage returns an Image the size of the buffer filled with c, for intermediate
+// conversions. It is reused across calls, and reallocated only if the buffer's bounds change.
+func (d *Display) scratchImage(c Color) *Image {
+	if d.scratch == nil || d.scratch.Rect != d.buffer.Rect {
+		d.scratch = NewImage(d.buffer.Rect)
+	}
+	d.scratch.fill(c)
+	return d.scratch
 }
 
-// DrawAndRefreshImages renders a black image and a red/yellow image on the display.
-func (d *Display) DrawAndRefreshImages(black, redyellow image.Image) {
+// DrawAndRefreshImages renders a black image and a red/yellow image on the display. Like Draw,
+// the images are flipped according to Mirror and FlipVertical.
+func (d *Display) DrawAndRefreshImages(black, redyellow image.Image) error {
 	now := time.Now()
 	defer func(start time.Time) {
-		log.Printf("DrawAndRefreshImages: %s", time.Since(start).String())
+		d.logf("DrawAndRefreshImages: %s", time.Since(start).String())
 	}(now)
-	bi, hi := convert(black, color.Palette{White, Black}), convert(redyellow, color.Palette{White, Highlight})
-	d.buffer.Black = bi.Black
-	d.buffer.Highlight = hi.Highlight
-	d.Refresh()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.drawImages(black, redyellow); err != nil {
+		return err
+	}
+	return d.refresh()
+}
+
+// drawImages replaces the buffer's black plane with black, and its highlight plane with
+// redyellow.
+func (d *Display) drawImages(black, redyellow image.Image) error {
+	bg, err := d.background()
+	if err != nil {
+		return err
+	}
+	p := d.palette()
+	d.buffer.fill(bg)
+	d.convert(d.buffer, black, color.Palette{White, Black})
+	hi := d.scratchImage(bg)
+	d.convert(hi, redyellow, color.Palette{White, p[2]})
+	copy(d.buffer.Highlight, hi.Highlight)
+	return nil
 }
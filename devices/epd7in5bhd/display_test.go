@@ -2,11 +2,21 @@ package epd7in5bhd
 
 import (
 	"bytes"
+	"errors"
 	"image"
 	"image/color"
+	"io/ioutil"
+	"log"
+	"os"
 	"testing"
+	"time"
 
+	"github.com/toothrot/gink/render"
 	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/gpio/gpiotest"
 )
 
 func BenchmarkEncode(b *testing.B) {
@@ -82,3 +92,692 @@ func BenchmarkDrawPalettedNativeColor(b *testing.B) {
 		draw.Draw(img, r, p, image.Point{0, 0}, draw.Src)
 	}
 }
+
+func TestDrawFlip(t *testing.T) {
+	cases := []struct {
+		desc         string
+		mirror, flip bool
+		src          image.Image
+		want         image.Point
+	}{
+		{
+			desc:   "mirror",
+			mirror: true,
+			src:    leftEdgeImage(image.NewRGBA(DisplayBounds)),
+			want:   image.Point{DisplayWidth - 1, 10},
+		},
+		{
+			desc:   "mirror exact colors",
+			mirror: true,
+			src:    leftEdgeImage(image.NewPaletted(DisplayBounds, color.Palette{color.White, color.Black, color.RGBA{255, 0, 0, 255}})),
+			want:   image.Point{DisplayWidth - 1, 10},
+		},
+		{
+			desc: "flip vertical",
+			flip: true,
+			src:  leftEdgeImage(image.NewRGBA(DisplayBounds)),
+			want: image.Point{0, DisplayHeight - 11},
+		},
+		{
+			desc:   "mirror and flip vertical",
+			mirror: true,
+			flip:   true,
+			src:    leftEdgeImage(image.NewRGBA(DisplayBounds)),
+			want:   image.Point{DisplayWidth - 1, DisplayHeight - 11},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			d := &Display{buffer: NewImage(DisplayBounds), Mirror: c.mirror, FlipVertical: c.flip}
+			d.Draw(c.src)
+			if got := d.buffer.At(c.want.X, c.want.Y); got != Black {
+				t.Errorf("d.buffer.At(%d, %d) = %v, wanted %v", c.want.X, c.want.Y, got, Black)
+			}
+			if got := d.buffer.At(0, 10); got != White {
+				t.Errorf("d.buffer.At(%d, %d) = %v, wanted %v", 0, 10, got, White)
+			}
+		})
+	}
+}
+
+// leftEdgeImage fills img with white, with a single black pixel on the left edge at (0, 10).
+func leftEdgeImage(img draw.Image) image.Image {
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	img.Set(0, 10, color.Black)
+	return img
+}
+
+func BenchmarkDisplayDrawPalettedNativeColor(b *testing.B) {
+	p := image.NewPaletted(DisplayBounds, color.Palette{White, Black, Highlight})
+	d := &Display{buffer: NewImage(DisplayBounds)}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		d.Draw(p)
+	}
+}
+
+func TestUploadOversized(t *testing.T) {
+	hw, bus := newFakeHardware()
+	d := &Display{hw: hw, buffer: NewImage(DisplayBounds)}
+
+	if err := d.Upload(make([]byte, BufSize+100), make([]byte, BufSize+100)); err == nil {
+		t.Errorf("Upload() = nil, wanted error for oversized buffers")
+	}
+	var sent int
+	for _, c := range bus.commands() {
+		if c.cmd == writeRAMBW || c.cmd == writeRAMRed {
+			sent++
+			if len(c.data) != BufSize {
+				t.Errorf("%v sent %d bytes, wanted %d", c.cmd, len(c.data), BufSize)
+			}
+		}
+	}
+	if sent != 2 {
+		t.Errorf("sent %d RAM writes, wanted 2", sent)
+	}
+}
+
+func TestUploadInvertPlanes(t *testing.T) {
+	hw, bus := newFakeHardware()
+	d := &Display{hw: hw, buffer: NewImage(DisplayBounds), InvertPlanes: true}
+
+	black, red := bytes.Repeat([]byte{0x11}, BufSize), bytes.Repeat([]byte{0x22}, BufSize)
+	if err := d.Upload(black, red); err != nil {
+		t.Fatalf("Upload() = %v, wanted nil", err)
+	}
+	want := map[command][]byte{writeRAMBW: red, writeRAMRed: black}
+	var sent int
+	for _, c := range bus.commands() {
+		w, ok := want[c.cmd]
+		if !ok {
+			continue
+		}
+		sent++
+		if !bytes.Equal(c.data, w) {
+			t.Errorf("%v sent %#x..., wanted %#x...", c.cmd, c.data[:1], w[:1])
+		}
+	}
+	if sent != 2 {
+		t.Errorf("sent %d RAM writes, wanted 2", sent)
+	}
+}
+
+func TestHistory(t *testing.T) {
+	hw, _ := newFakeHardware()
+	o := options{}
+	WithHistory(2)(&o)
+	d := &Display{hw: hw, buffer: NewImage(DisplayBounds), history: make([]*Image, 0, o.history)}
+
+	if got := d.History(); got != nil {
+		t.Errorf("History() = %v, wanted nil before any upload", got)
+	}
+	for i := 0; i < 3; i++ {
+		d.buffer.Reset()
+		d.buffer.Set(i, 0, Black)
+		if err := d.Refresh(); err != nil {
+			t.Fatalf("Refresh() = %v, wanted no error", err)
+		}
+	}
+	got := d.History()
+	if len(got) != 2 {
+		t.Fatalf("len(History()) = %d, wanted 2", len(got))
+	}
+	// The first frame was dropped; the remaining frames are oldest first.
+	for i, frame := range got {
+		if c := frame.At(i+1, 0); c != Black {
+			t.Errorf("History()[%d].At(%d, 0) = %v, wanted %v", i, i+1, c, Black)
+		}
+	}
+
+	d.buffer.Set(2, 0, White)
+	if c := got[1].At(2, 0); c != Black {
+		t.Errorf("History()[1].At(2, 0) = %v after changing the buffer, wanted %v", c, Black)
+	}
+}
+
+func TestMeasureRefreshNotBusy(t *testing.T) {
+	hw, _ := newFakeHardware()
+	d := &Display{hw: hw, buffer: NewImage(DisplayBounds)}
+	if _, err := d.MeasureRefresh(); err == nil {
+		t.Errorf("MeasureRefresh() = _, nil, wanted error when the busy pin never reports busy")
+	}
+}
+
+func TestBusy(t *testing.T) {
+	hw, _ := newFakeHardware()
+	d := &Display{hw: hw, buffer: NewImage(DisplayBounds)}
+	if d.Busy() {
+		t.Errorf("Busy() = true with the busy pin high, wanted false")
+	}
+	hw.Busy().Out(gpio.Low)
+	// Busy must not wait for a refresh holding the lock.
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.Busy() {
+		t.Errorf("Busy() = false with the busy pin low, wanted true")
+	}
+}
+
+func TestWaitUntilIdleEdges(t *testing.T) {
+	hw, _ := newFakeHardware()
+	d := &Display{hw: hw, buffer: NewImage(DisplayBounds)}
+	busy := hw.Busy().(*gpiotest.Pin)
+	busy.EdgesChan = make(chan gpio.Level)
+
+	// The pin is already high, so no edge will come. Waiting on one would block until the
+	// watchdog, if not forever.
+	d.watchdog = time.Second
+	start := time.Now()
+	if err := d.waitUntilIdle(); err != nil {
+		t.Errorf("waitUntilIdle() = %v with the busy pin already high, wanted nil", err)
+	}
+	if took := time.Since(start); took > 500*time.Millisecond {
+		t.Errorf("waitUntilIdle() took %v with the busy pin already high, wanted no wait", took)
+	}
+
+	busy.Out(gpio.Low)
+	go func() { busy.EdgesChan <- gpio.High }()
+	if err := d.waitUntilIdle(); err != nil {
+		t.Errorf("waitUntilIdle() = %v after a rising edge, wanted nil", err)
+	}
+	if busy.Read() != gpio.High {
+		t.Errorf("busy pin is %v after waitUntilIdle(), wanted %v", busy.Read(), gpio.High)
+	}
+}
+
+func TestRefreshRegionAddressing(t *testing.T) {
+	hw, bus := newFakeHardware()
+	d := &Display{hw: hw, buffer: NewImage(DisplayBounds)}
+	if err := d.RefreshRegion(image.Rect(16, 0, 32, 10)); err != nil {
+		t.Fatalf("RefreshRegion() = %v, wanted no error", err)
+	}
+
+	want := []fakeCommand{
+		// X is in pixels, rounded out to whole bytes: 16 to 31.
+		{cmd: setRamXStart, data: []byte{0x10, 0x00, 0x1F, 0x00}},
+		// Y counts down from 0x2AF: rows 0 to 9 are 0x2AF to 0x2A6.
+		{cmd: setRamYStart, data: []byte{0xAF, 0x02, 0xA6, 0x02}},
+		{cmd: setRamXAddressCtr, data: []byte{0x10, 0x00}},
+		{cmd: setRamYAddressCtr, data: []byte{0xAF, 0x02}},
+		// 2 bytes per row, for 10 rows.
+		{cmd: writeRAMBW, data: bytes.Repeat([]byte{0xFF}, 20)},
+		{cmd: writeRAMRed, data: make([]byte, 20)},
+		{cmd: displayUpdateControl2, data: []byte{0xC7}},
+		{cmd: masterActivation},
+		// The full window is restored afterwards.
+		{cmd: setRamXStart, data: []byte{0x00, 0x00, 0x6F, 0x03}},
+		{cmd: setRamYStart, data: []byte{0xAF, 0x02, 0x00, 0x00}},
+		{cmd: setRamXAddressCtr, data: []byte{0x00, 0x00}},
+		{cmd: setRamYAddressCtr, data: []byte{0xAF, 0x02}},
+	}
+	got := bus.commands()
+	if len(got) != len(want) {
+		t.Fatalf("RefreshRegion() sent %d commands, wanted %d: %v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i].cmd != want[i].cmd || !bytes.Equal(got[i].data, want[i].data) {
+			t.Errorf("command %d = %v % X, wanted %v % X", i, got[i].cmd, got[i].data, want[i].cmd, want[i].data)
+		}
+	}
+}
+
+func TestRefreshHighlightOnly(t *testing.T) {
+	hw, bus := newFakeHardware()
+	d := &Display{hw: hw, buffer: NewImage(DisplayBounds)}
+	d.buffer.Set(0, 0, Highlight)
+	if err := d.RefreshHighlightOnly(); err != nil {
+		t.Fatalf("RefreshHighlightOnly() = %v, wanted no error", err)
+	}
+	var red int
+	for _, c := range bus.commands() {
+		switch c.cmd {
+		case writeRAMBW:
+			t.Errorf("RefreshHighlightOnly() sent %v, wanted only the highlight plane", c.cmd)
+		case writeRAMRed:
+			red++
+			if len(c.data) != BufSize {
+				t.Fatalf("%v sent %d bytes, wanted %d", c.cmd, len(c.data), BufSize)
+			}
+			if c.data[0] != 0x80 {
+				t.Errorf("%v sent first byte %#x, wanted %#x", c.cmd, c.data[0], 0x80)
+			}
+		}
+	}
+	if red != 1 {
+		t.Errorf("RefreshHighlightOnly() sent %d highlight planes, wanted 1", red)
+	}
+}
+
+func TestDrawImages(t *testing.T) {
+	black := image.NewRGBA(DisplayBounds)
+	draw.Draw(black, black.Bounds(), image.White, image.Point{}, draw.Src)
+	black.Set(1, 0, color.Black)
+	red := image.NewRGBA(DisplayBounds)
+	draw.Draw(red, red.Bounds(), image.White, image.Point{}, draw.Src)
+	red.Set(2, 0, color.RGBA{0xff, 0, 0, 0xff})
+
+	// A scratch Image of the wrong size is replaced rather than reused.
+	d := &Display{buffer: NewImage(DisplayBounds), scratch: NewImage(image.Rect(0, 0, 8, 8))}
+	for i := 0; i < 2; i++ {
+		d.drawImages(black, red)
+		want := []Color{White, Black, Highlight, White}
+		for x, c := range want {
+			if got := d.buffer.At(x, 0); got != c {
+				t.Errorf("pass %d: At(%d, 0) = %v, wanted %v", i, x, got, c)
+			}
+		}
+	}
+	if d.scratch.Rect != DisplayBounds {
+		t.Errorf("scratch.Rect = %v, wanted %v", d.scratch.Rect, DisplayBounds)
+	}
+}
+
+func TestDrawImagesFlipped(t *testing.T) {
+	black := image.NewRGBA(DisplayBounds)
+	draw.Draw(black, black.Bounds(), image.White, image.Point{}, draw.Src)
+	black.Set(1, 0, color.Black)
+	red := image.NewRGBA(DisplayBounds)
+	draw.Draw(red, red.Bounds(), image.White, image.Point{}, draw.Src)
+	red.Set(2, 0, color.RGBA{0xff, 0, 0, 0xff})
+
+	d := &Display{buffer: NewImage(DisplayBounds), Mirror: true, FlipVertical: true}
+	d.drawImages(black, red)
+	want := map[image.Point]Color{
+		{DisplayWidth - 2, DisplayHeight - 1}: Black,
+		{DisplayWidth - 3, DisplayHeight - 1}: Highlight,
+		{1, 0}:                                White,
+		{2, 0}:                                White,
+	}
+	for pt, c := range want {
+		if got := d.buffer.At(pt.X, pt.Y); got != c {
+			t.Errorf("At(%d, %d) = %v after drawImages() with Mirror and FlipVertical, wanted %v", pt.X, pt.Y, got, c)
+		}
+	}
+}
+
+func BenchmarkDrawImages(b *testing.B) {
+	img := image.NewRGBA(DisplayBounds)
+	d := &Display{buffer: NewImage(DisplayBounds)}
+	log.SetOutput(ioutil.Discard)
+	defer log.SetOutput(os.Stderr)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		d.drawImages(img, img)
+	}
+}
+
+func TestInit(t *testing.T) {
+	configure := []fakeCommand{
+		{cmd: softStart, data: []byte{0xAE, 0xC7, 0xC3, 0xC0, 0x40}},
+		{cmd: setGateDriver, data: []byte{0xAF, 0x02, 0x01}},
+		{cmd: dataEntryMode, data: []byte{0x01}},
+		{cmd: setRamXStart, data: []byte{0x00, 0x00, 0x6F, 0x03}},
+		{cmd: setRamYStart, data: []byte{0xAF, 0x02, 0x00, 0x00}},
+		{cmd: borderWaveformControl, data: []byte{0x01}},
+		{cmd: tempSensorControl, data: []byte{0x80}},
+		{cmd: displayUpdateControl2, data: []byte{0xB1}},
+		{cmd: masterActivation},
+		{cmd: setRamXAddressCtr, data: []byte{0x00, 0x00}},
+		{cmd: setRamYAddressCtr, data: []byte{0xAF, 0x02}},
+	}
+	cases := []struct {
+		name string
+		init func(*Display)
+		want []fakeCommand
+	}{
+		{
+			name: "Init",
+			init: func(d *Display) { d.Init() },
+			want: append([]fakeCommand{
+				{cmd: displayRefresh},
+				{cmd: autoWriteRamRed, data: []byte{0xF7}},
+				{cmd: autoWriteRamBW, data: []byte{0xF7}},
+			}, configure...),
+		},
+		{
+			name: "InitFast",
+			init: func(d *Display) { d.InitFast() },
+			want: append([]fakeCommand{{cmd: displayRefresh}}, configure...),
+		},
+		{
+			name: "SoftReset",
+			init: func(d *Display) { d.SoftReset() },
+			want: append([]fakeCommand{{cmd: displayRefresh}}, configure...),
+		},
+		{
+			name: "ResetWindow",
+			init: (*Display).ResetWindow,
+			want: append(configure[3:5:5], configure[9:]...),
+		},
+	}
+	for _, c := range cases {
+		hw, bus := newFakeHardware()
+		d := &Display{hw: hw, buffer: NewImage(DisplayBounds)}
+		c.init(d)
+		got := bus.commands()
+		if len(got) != len(c.want) {
+			t.Errorf("%s() sent %d commands, wanted %d: %v", c.name, len(got), len(c.want), got)
+			continue
+		}
+		for i := range c.want {
+			if got[i].cmd != c.want[i].cmd || !bytes.Equal(got[i].data, c.want[i].data) {
+				t.Errorf("%s() command %d = %v % X, wanted %v % X", c.name, i, got[i].cmd, got[i].data, c.want[i].cmd, c.want[i].data)
+			}
+		}
+	}
+}
+
+func TestInitBusError(t *testing.T) {
+	for name, init := range map[string]func(*Display) error{"Init": (*Display).Init, "InitFast": (*Display).InitFast} {
+		hw, bus := newFakeHardware()
+		d := &Display{hw: hw, buffer: NewImage(DisplayBounds), SettleDelay: -1}
+		bus.Err = errors.New("bus unplugged")
+		if err := init(d); !errors.Is(err, bus.Err) {
+			t.Errorf("%s() = %v, wanted %v", name, err, bus.Err)
+		}
+	}
+
+	hw, bus := newFakeHardware()
+	d := &Display{hw: hw, buffer: NewImage(DisplayBounds), SettleDelay: -1}
+	bus.Err = errors.New("bus unplugged")
+	if err := d.turnOnDisplay(); !errors.Is(err, bus.Err) {
+		t.Errorf("turnOnDisplay() = %v, wanted %v", err, bus.Err)
+	}
+}
+
+func TestSettleDelay(t *testing.T) {
+	cases := []struct {
+		settle time.Duration
+		want   time.Duration
+	}{
+		{settle: 0, want: DefaultSettleDelay},
+		{settle: -1, want: 0},
+		{settle: 20 * time.Millisecond, want: 20 * time.Millisecond},
+	}
+	for _, c := range cases {
+		d := &Display{SettleDelay: c.settle}
+		if got := d.settleDelay(); got != c.want {
+			t.Errorf("settleDelay() = %v with SettleDelay %v, wanted %v", got, c.settle, c.want)
+		}
+	}
+
+	hw, _ := newFakeHardware()
+	d := &Display{hw: hw, SettleDelay: -1}
+	start := time.Now()
+	d.Reset()
+	if got := time.Since(start); got >= 2*DefaultSettleDelay {
+		t.Errorf("Reset() took %v with no settle delay, wanted less than %v", got, 2*DefaultSettleDelay)
+	}
+}
+
+func TestClearBackground(t *testing.T) {
+	cases := []struct {
+		bg   color.Color
+		want Color
+	}{
+		{bg: nil, want: White},
+		{bg: color.Black, want: Black},
+		{bg: Highlight, want: Highlight},
+	}
+	for _, c := range cases {
+		hw, _ := newFakeHardware()
+		d := &Display{hw: hw, buffer: NewImage(DisplayBounds), Background: c.bg}
+		if err := d.Clear(); err != nil {
+			t.Fatalf("Clear() with background %v = %v, wanted no error", c.bg, err)
+		}
+		if h := Histogram(d.buffer); h[c.want.C] != DisplayWidth*DisplayHeight {
+			t.Errorf("Clear() with background %v left colors %v, wanted all %v", c.bg, h, c.want)
+		}
+	}
+
+	hw, _ := newFakeHardware()
+	d := &Display{hw: hw, buffer: NewImage(DisplayBounds), Background: color.RGBA{0, 0, 0xff, 0xff}}
+	if err := d.Clear(); err == nil {
+		t.Errorf("Clear() with background %v = nil, wanted error", d.Background)
+	}
+}
+
+func TestDrawBackground(t *testing.T) {
+	d := &Display{buffer: NewImage(DisplayBounds), Background: Black}
+	src := image.NewPaletted(image.Rect(0, 0, 8, 8), color.Palette{White, Black, Highlight})
+	d.Draw(src)
+	if got := d.buffer.At(0, 0); got != White {
+		t.Errorf("At(0, 0) = %v, wanted %v from the drawn image", got, White)
+	}
+	if got := d.buffer.At(8, 8); got != Black {
+		t.Errorf("At(8, 8) = %v, wanted the background %v", got, Black)
+	}
+}
+
+func TestDrawAlpha(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 4, 1))
+	src.Set(0, 0, color.NRGBA{0, 0, 0, 0xff})
+	// Half-transparent black is a mid gray over white, just past the midpoint towards black.
+	src.Set(1, 0, color.NRGBA{0, 0, 0, 0x80})
+	src.Set(2, 0, color.NRGBA{0, 0, 0, 0x40})
+	src.Set(3, 0, color.NRGBA{0, 0, 0, 0})
+
+	cases := []struct {
+		bg   color.Color
+		want []Color
+	}{
+		{bg: nil, want: []Color{Black, Black, White, White}},
+		{bg: Highlight, want: []Color{Black, Black, Highlight, Highlight}},
+	}
+	for _, c := range cases {
+		d := &Display{buffer: NewImage(DisplayBounds), Background: c.bg}
+		d.Draw(src)
+		for x, want := range c.want {
+			if got := d.buffer.At(x, 0); got != want {
+				t.Errorf("Draw() with Background %v: At(%d, 0) = %v, wanted %v", c.bg, x, got, want)
+			}
+		}
+	}
+}
+
+func TestDrawAlphaPaletted(t *testing.T) {
+	// A logo with a transparent background, in each size of palette that has a fast path.
+	palettes := []color.Palette{
+		{color.Transparent, color.Black},
+		{color.Transparent, color.Black, color.White},
+		{color.Transparent, color.Black, color.White, color.RGBA{0, 0, 0xff, 0xff}},
+	}
+	for _, p := range palettes {
+		src := image.NewPaletted(image.Rect(0, 0, 2, 1), p)
+		src.SetColorIndex(1, 0, 1)
+		for _, bg := range []Color{White, Highlight} {
+			d := &Display{buffer: NewImage(DisplayBounds), Background: bg}
+			d.Draw(src)
+			if got := d.buffer.At(0, 0); got != bg {
+				t.Errorf("Draw() of %d colors with Background %v: transparent pixel is %v, wanted %v", len(p), bg, got, bg)
+			}
+			if got := d.buffer.At(1, 0); got != Black {
+				t.Errorf("Draw() of %d colors with Background %v: black pixel is %v, wanted %v", len(p), bg, got, Black)
+			}
+			var black, red bytes.Buffer
+			Encode(&black, &red, src)
+			if black.Bytes()[0]&0x80 == 0 {
+				t.Errorf("Encode() of %d colors: transparent pixel is black, wanted white", len(p))
+			}
+		}
+	}
+}
+
+func TestDrawFunc(t *testing.T) {
+	d := &Display{buffer: NewImage(DisplayBounds), Mirror: true}
+	d.DrawFunc(func(dst draw.Image) {
+		if dst.Bounds() != DisplayBounds {
+			t.Errorf("DrawFunc() buffer bounds = %v, wanted %v", dst.Bounds(), DisplayBounds)
+		}
+		draw.Draw(dst, image.Rect(0, 0, 2, 1), image.Black, image.Point{}, draw.Src)
+		dst.Set(0, 1, color.RGBA{0xff, 0, 0, 0xff})
+	})
+	want := map[image.Point]Color{
+		{DisplayWidth - 1, 0}: Black,
+		{DisplayWidth - 2, 0}: Black,
+		{DisplayWidth - 3, 0}: White,
+		{DisplayWidth - 1, 1}: Highlight,
+		{0, 0}:                White,
+	}
+	for pt, c := range want {
+		if got := d.buffer.At(pt.X, pt.Y); got != c {
+			t.Errorf("At(%d, %d) = %v after DrawFunc() with Mirror, wanted %v", pt.X, pt.Y, got, c)
+		}
+	}
+}
+
+func TestUploadPlanes(t *testing.T) {
+	setX := fakeCommand{cmd: setRamXAddressCtr, data: []byte{0x00, 0x00}}
+	setY := fakeCommand{cmd: setRamYAddressCtr, data: []byte{0xAF, 0x02}}
+	trigger := []fakeCommand{
+		{cmd: displayUpdateControl2, data: []byte{0xC7}},
+		{cmd: masterActivation},
+	}
+	cases := []struct {
+		name   string
+		upload func(*Display) error
+		want   []fakeCommand
+	}{
+		{
+			name:   "UploadBlack",
+			upload: func(d *Display) error { return d.UploadBlack([]byte{0x0F}) },
+			want:   []fakeCommand{setX, setY, {cmd: writeRAMBW, data: []byte{0x0F}}},
+		},
+		{
+			name:   "UploadHighlight",
+			upload: func(d *Display) error { return d.UploadHighlight([]byte{0xF0}) },
+			want:   []fakeCommand{setX, setY, {cmd: writeRAMRed, data: []byte{0xF0}}},
+		},
+		{
+			name:   "Trigger",
+			upload: (*Display).Trigger,
+			want:   trigger,
+		},
+		{
+			name:   "Upload",
+			upload: func(d *Display) error { return d.Upload([]byte{0x0F}, []byte{0xF0}) },
+			want: append([]fakeCommand{
+				setX, setY, {cmd: writeRAMBW, data: []byte{0x0F}},
+				setX, setY, {cmd: writeRAMRed, data: []byte{0xF0}},
+			}, trigger...),
+		},
+	}
+	for _, c := range cases {
+		hw, bus := newFakeHardware()
+		d := &Display{hw: hw, buffer: NewImage(DisplayBounds)}
+		if err := c.upload(d); err != nil {
+			t.Fatalf("%s() = %v, wanted no error", c.name, err)
+		}
+		got := bus.commands()
+		if len(got) != len(c.want) {
+			t.Errorf("%s() sent %d commands, wanted %d: %v", c.name, len(got), len(c.want), got)
+			continue
+		}
+		for i, w := range c.want {
+			// Planes are padded to BufSize, so only compare the start of their data.
+			if got[i].cmd != w.cmd || len(got[i].data) < len(w.data) || !bytes.Equal(got[i].data[:len(w.data)], w.data) {
+				t.Errorf("%s() command %d = %v % x, wanted %v % x", c.name, i, got[i].cmd, got[i].data, w.cmd, w.data)
+			}
+		}
+	}
+
+	hw, _ := newFakeHardware()
+	d := &Display{hw: hw, buffer: NewImage(DisplayBounds)}
+	if err := d.UploadBlack(make([]byte, BufSize+1)); err == nil {
+		t.Errorf("UploadBlack() = nil, wanted error for an oversized plane")
+	}
+}
+
+func TestDrawAt(t *testing.T) {
+	pt := image.Pt(100, 100)
+	want := image.Rectangle{Min: pt, Max: pt.Add(image.Pt(10, 10))}
+	paletted := image.NewPaletted(image.Rect(0, 0, 10, 10), color.Palette{White, Black, Highlight})
+	for i := range paletted.Pix {
+		paletted.Pix[i] = 1
+	}
+	rgba := image.NewRGBA(image.Rect(5, 5, 15, 15))
+	draw.Draw(rgba, rgba.Rect, image.Black, image.Point{}, draw.Src)
+	// Cross-check the generic path with an image that does not start at the origin.
+	for _, src := range []image.Image{paletted, rgba} {
+		d := &Display{buffer: NewImage(DisplayBounds)}
+		d.buffer.SetColorIndex(0, 0, 2)
+		before := NewImage(DisplayBounds)
+		copy(before.Black, d.buffer.Black)
+		copy(before.Highlight, d.buffer.Highlight)
+
+		d.DrawAt(src, pt)
+		// Every pixel of want changed, and nothing else did.
+		diff, n := DiffImage(before, d.buffer)
+		if n != want.Dx()*want.Dy() {
+			t.Errorf("DrawAt(%T, %v) changed %d pixels, wanted %d", src, pt, n, want.Dx()*want.Dy())
+		}
+		for _, p := range []image.Point{want.Min, want.Max.Sub(image.Pt(1, 1))} {
+			if diff.At(p.X, p.Y) != Highlight {
+				t.Errorf("DrawAt(%T, %v) did not change pixel %v, wanted it changed", src, pt, p)
+			}
+		}
+		if got := d.buffer.At(0, 0); got != Highlight {
+			t.Errorf("DrawAt(%T, %v) changed At(0, 0) to %v, wanted %v", src, pt, got, Highlight)
+		}
+	}
+}
+
+func TestDrawAtEdge(t *testing.T) {
+	d := &Display{buffer: NewImage(DisplayBounds)}
+	src := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	draw.Draw(src, src.Rect, image.Black, image.Point{}, draw.Src)
+	d.DrawAt(src, image.Pt(DisplayWidth-5, -5))
+	_, n := DiffImage(NewImage(DisplayBounds), d.buffer)
+	if n != 25 {
+		t.Errorf("DrawAt() over the corner changed %d pixels, wanted 25", n)
+	}
+}
+
+func TestOverlay(t *testing.T) {
+	// A 50% gray photo, dithered to a checkerboard.
+	gray := image.NewPaletted(DisplayBounds, color.Palette{White, Black, Highlight})
+	for y := 0; y < DisplayHeight; y++ {
+		for x := 0; x < DisplayWidth; x++ {
+			gray.SetColorIndex(x, y, uint8((x+y)%2))
+		}
+	}
+	// Highlight text on a transparent background.
+	text := image.NewRGBA(image.Rect(0, 0, 200, 40))
+	face, err := render.DefaultMonoFace(32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dr := font.Drawer{Dst: text, Src: image.NewUniform(Highlight), Face: face, Dot: fixed.P(0, 32)}
+	dr.DrawString("gink")
+
+	d := &Display{buffer: NewImage(DisplayBounds)}
+	d.Draw(gray)
+	pt := image.Pt(300, 200)
+	d.Overlay(text, pt)
+
+	var highlights int
+	for y := 0; y < DisplayHeight; y++ {
+		for x := 0; x < DisplayWidth; x++ {
+			got := d.buffer.At(x, y)
+			if got == Highlight {
+				highlights++
+			}
+			p := image.Pt(x, y)
+			if !p.In(text.Rect.Add(pt)) {
+				if want := gray.At(x, y); got != want {
+					t.Fatalf("Overlay() changed %v outside of the text to %v, wanted %v", p, got, want)
+				}
+				continue
+			}
+			if _, _, _, a := text.At(x-pt.X, y-pt.Y).RGBA(); a == 0 {
+				if want := gray.At(x, y); got != want {
+					t.Fatalf("Overlay() changed transparent pixel %v to %v, wanted %v", p, got, want)
+				}
+			}
+		}
+	}
+	if highlights == 0 {
+		t.Errorf("Overlay() drew no highlight pixels, wanted the text")
+	}
+}
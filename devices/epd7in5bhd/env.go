@@ -0,0 +1,59 @@
+package epd7in5bhd
+
+import (
+	"os"
+	"strconv"
+
+	"periph.io/x/periph/conn/physic"
+)
+
+// Environment variables read by New to tune the SPI bus without recompiling, such as for the
+// same binary on boards with different wiring. Each is a positive integer. An option passed to
+// New takes precedence over its variable, which takes precedence over the default.
+const (
+	// EnvSPIHz overrides the SPI clock in Hz, as set by WithSPISpeed.
+	EnvSPIHz = "EPD_SPI_HZ"
+	// EnvTxLimit overrides the maximum bytes per SPI transfer, as set by WithTxLimit.
+	EnvTxLimit = "EPD_TX_LIMIT"
+)
+
+// WithSPISpeed sets the SPI clock. The default is 20Mhz, the controller's maximum for writes.
+// Long or poor wiring may need a slower clock. It overrides EnvSPIHz.
+func WithSPISpeed(f physic.Frequency) Option {
+	return func(o *options) {
+		o.speed = f
+	}
+}
+
+// WithTxLimit sets the maximum number of bytes sent in a single SPI transfer, as SetTxLimit
+// does. It overrides EnvTxLimit.
+func WithTxLimit(n int) Option {
+	return func(o *options) {
+		o.txLimit = n
+	}
+}
+
+// WithSPIPort opens the SPI port with the given spireg name, alias, or number, such as
+// "SPI1.0", instead of the default port. This is needed on boards with more than one SPI
+// controller, such as a Compute Module. New returns an error listing the available ports if
+// name is not one of them.
+func WithSPIPort(name string) Option {
+	return func(o *options) {
+		o.port = name
+	}
+}
+
+// envInt returns the positive integer in the environment variable name, or 0 if it is unset.
+// Other values are logged and ignored, so that the default is used instead.
+func (d *Display) envInt(name string) int64 {
+	v, ok := os.LookupEnv(name)
+	if !ok || v == "" {
+		return 0
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n <= 0 {
+		d.logf("Ignoring %s=%q, want a positive integer", name, v)
+		return 0
+	}
+	return n
+}
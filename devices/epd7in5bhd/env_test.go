@@ -0,0 +1,39 @@
+package epd7in5bhd
+
+import (
+	"os"
+	"testing"
+)
+
+func TestEnvInt(t *testing.T) {
+	const name = "EPD_TEST_ENV_INT"
+	defer os.Unsetenv(name)
+	cases := []struct {
+		value   string
+		set     bool
+		want    int64
+		wantLog bool
+	}{
+		{set: false, want: 0},
+		{value: "", set: true, want: 0},
+		{value: "4096", set: true, want: 4096},
+		{value: "10000000", set: true, want: 10000000},
+		{value: "0", set: true, want: 0, wantLog: true},
+		{value: "-1", set: true, want: 0, wantLog: true},
+		{value: "20MHz", set: true, want: 0, wantLog: true},
+	}
+	for _, c := range cases {
+		os.Unsetenv(name)
+		if c.set {
+			os.Setenv(name, c.value)
+		}
+		l := &fakeLogger{}
+		d := &Display{logger: l}
+		if got := d.envInt(name); got != c.want {
+			t.Errorf("envInt() with %s=%q = %d, wanted %d", name, c.value, got, c.want)
+		}
+		if logged := len(l.lines) > 0; logged != c.wantLog {
+			t.Errorf("envInt() with %s=%q logged %q, wanted a warning: %v", name, c.value, l.lines, c.wantLog)
+		}
+	}
+}
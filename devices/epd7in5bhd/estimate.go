@@ -0,0 +1,69 @@
+package epd7in5bhd
+
+import (
+	"image"
+	"sync/atomic"
+	"time"
+
+	"github.com/toothrot/gink/devices/internal/driver"
+	"periph.io/x/periph/conn/physic"
+)
+
+// EstimateRefresh returns how many bytes would be uploaded to show img, and an estimate of
+// how long the upload and refresh would take, without drawing to the buffer or talking to the
+// panel.
+//
+// img is converted as it is by Draw, and compared with the buffer. uploadBytes covers both
+// planes of the smallest region holding every changed pixel, as uploaded by RefreshRegion;
+// Refresh always uploads BufSize*2. If nothing changed, both results are zero.
+//
+// The upload time assumes the configured SPI speed with no gaps between transfers. The
+// refresh time is the one last measured (see Stats and MeasureRefresh), or DefaultWait if the
+// panel has not refreshed yet.
+func (d *Display) EstimateRefresh(img image.Image) (uploadBytes int, estDuration time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	next := NewImage(d.buffer.Rect)
+	copy(next.Black, d.buffer.Black)
+	copy(next.Highlight, d.buffer.Highlight)
+	bg, _ := d.background()
+	if !next.Rect.In(img.Bounds()) {
+		next.fill(bg)
+	}
+	d.drawOver(d.flipped(next), img, bg)
+
+	r := changedBytes(d.buffer, next)
+	if r.Empty() {
+		return 0, 0
+	}
+	uploadBytes = 2 * r.Dx() * r.Dy()
+
+	speed := d.speed
+	if speed == 0 {
+		speed = driver.DefaultSpeed
+	}
+	estDuration = time.Duration(uploadBytes*8) * time.Second / time.Duration(speed/physic.Hertz)
+	if last := atomic.LoadInt64(&d.metrics.lastRefresh); last > 0 {
+		estDuration += time.Duration(last)
+	} else {
+		estDuration += DefaultWait
+	}
+	return uploadBytes, estDuration
+}
+
+// changedBytes returns the byte columns and rows in which a and b differ, in either plane. a
+// and b must have the same Rect.
+func changedBytes(a, b *Image) image.Rectangle {
+	var r image.Rectangle
+	w := a.rectWidthBytes
+	for y := 0; y < a.Rect.Dy(); y++ {
+		row := y * w
+		for x := 0; x < w; x++ {
+			if a.Black[row+x] == b.Black[row+x] && a.Highlight[row+x] == b.Highlight[row+x] {
+				continue
+			}
+			r = r.Union(image.Rect(x, y, x+1, y+1))
+		}
+	}
+	return r
+}
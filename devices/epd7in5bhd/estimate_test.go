@@ -0,0 +1,73 @@
+package epd7in5bhd
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+	"time"
+
+	"periph.io/x/periph/conn/physic"
+)
+
+func TestEstimateRefresh(t *testing.T) {
+	black := image.NewUniform(color.Black)
+	white := image.NewUniform(color.White)
+	corner := image.NewRGBA(DisplayBounds)
+	draw.Draw(corner, corner.Bounds(), white, image.Point{}, draw.Src)
+	draw.Draw(corner, image.Rect(0, 0, 8, 2), black, image.Point{}, draw.Src)
+
+	cases := []struct {
+		desc        string
+		img         image.Image
+		speed       physic.Frequency
+		lastRefresh time.Duration
+		wantBytes   int
+		want        time.Duration
+	}{
+		{
+			desc: "unchanged",
+			img:  white,
+		},
+		{
+			desc:      "corner",
+			img:       corner,
+			wantBytes: 4,
+			want:      DefaultWait + 1600*time.Nanosecond,
+		},
+		{
+			desc:        "measured refresh",
+			img:         corner,
+			speed:       physic.MegaHertz,
+			lastRefresh: 10 * time.Second,
+			wantBytes:   4,
+			want:        10*time.Second + 32*time.Microsecond,
+		},
+		{
+			desc:      "full",
+			img:       black,
+			speed:     8 * physic.MegaHertz,
+			wantBytes: 2 * BufSize,
+			want:      DefaultWait + 2*BufSize*time.Microsecond,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			hw, bus := newFakeHardware()
+			d := &Display{hw: hw, buffer: NewImage(DisplayBounds), speed: c.speed}
+			d.buffer.fill(White)
+			d.metrics.lastRefresh = int64(c.lastRefresh)
+
+			gotBytes, got := d.EstimateRefresh(c.img)
+			if gotBytes != c.wantBytes || got != c.want {
+				t.Errorf("EstimateRefresh() = %d, %v, wanted %d, %v", gotBytes, got, c.wantBytes, c.want)
+			}
+			if d.buffer.At(0, 0) != White {
+				t.Errorf("EstimateRefresh() changed the buffer")
+			}
+			if cmds := bus.commands(); len(cmds) != 0 {
+				t.Errorf("EstimateRefresh() sent %v, wanted no commands", cmds)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package epd7in5bhd
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+
+	"github.com/disintegration/imaging"
+)
+
+// Resample is the filter used to scale images in FitCentered and Fill. Lanczos gives the
+// smoothest result, and Nearest is the fastest, which can also keep text sharper once dithered.
+type Resample int
+
+const (
+	// ResampleLanczos is the default.
+	ResampleLanczos Resample = iota
+	ResampleLinear
+	ResampleNearest
+)
+
+// ParseResample returns the Resample named by s, one of "lanczos", "linear", or "nearest".
+// Case is ignored.
+func ParseResample(s string) (Resample, error) {
+	switch strings.ToLower(s) {
+	case "lanczos":
+		return ResampleLanczos, nil
+	case "linear":
+		return ResampleLinear, nil
+	case "nearest":
+		return ResampleNearest, nil
+	}
+	return 0, fmt.Errorf("unknown resampling %q, want lanczos, linear, or nearest", s)
+}
+
+func (r Resample) filter() imaging.ResampleFilter {
+	switch r {
+	case ResampleLinear:
+		return imaging.Linear
+	case ResampleNearest:
+		return imaging.NearestNeighbor
+	}
+	return imaging.Lanczos
+}
+
+// FitOption changes how FitCentered and Fill scale an image.
+type FitOption func(*fitOptions)
+
+type fitOptions struct {
+	resample Resample
+}
+
+// WithResample scales images with r instead of ResampleLanczos.
+func WithResample(r Resample) FitOption {
+	return func(o *fitOptions) {
+		o.resample = r
+	}
+}
+
+func newFitOptions(opts []FitOption) fitOptions {
+	var o fitOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// FitCentered scales img down to fit within DisplayBounds, keeping its aspect ratio, and
+// centers it on a white canvas the size of the display. Images that already fit are not
+// scaled up. Display.FitCentered fits to a particular Display instead.
+func FitCentered(img image.Image, opts ...FitOption) image.Image {
+	return fitCentered(img, DisplayBounds.Size(), color.White, opts)
+}
+
+// Fill scales img to cover DisplayBounds, keeping its aspect ratio, and crops whatever falls
+// outside of the display, keeping the center. Display.Fill fills a particular Display instead.
+func Fill(img image.Image, opts ...FitOption) image.Image {
+	return fill(img, DisplayBounds.Size(), opts)
+}
+
+// FitCentered is like the package's FitCentered, but fits img within d's Size, and centers it
+// on a canvas of d's Background, as the panel shows it.
+func (d *Display) FitCentered(img image.Image, opts ...FitOption) image.Image {
+	d.mu.Lock()
+	bg, err := d.background()
+	if err != nil {
+		d.logf("FitCentered: %v, using white", err)
+	}
+	size, c := d.Size(), d.palette()[bg.C]
+	d.mu.Unlock()
+	return fitCentered(img, size, c, opts)
+}
+
+// Fill is like the package's Fill, but covers d's Size.
+func (d *Display) Fill(img image.Image, opts ...FitOption) image.Image {
+	d.mu.Lock()
+	size := d.Size()
+	d.mu.Unlock()
+	return fill(img, size, opts)
+}
+
+// fitCentered scales img down to fit within size, and centers it on a canvas of bg.
+func fitCentered(img image.Image, size image.Point, bg color.Color, opts []FitOption) image.Image {
+	o := newFitOptions(opts)
+	fit := imaging.Fit(img, size.X, size.Y, o.resample.filter())
+	return imaging.PasteCenter(imaging.New(size.X, size.Y, bg), fit)
+}
+
+// fill scales img to cover size, cropping whatever falls outside of it.
+func fill(img image.Image, size image.Point, opts []FitOption) image.Image {
+	o := newFitOptions(opts)
+	return imaging.Fill(img, size.X, size.Y, imaging.Center, o.resample.filter())
+}
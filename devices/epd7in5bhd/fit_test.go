@@ -0,0 +1,125 @@
+package epd7in5bhd
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/disintegration/imaging"
+)
+
+func TestFitCentered(t *testing.T) {
+	cases := []struct {
+		name string
+		img  image.Image
+		// black is a point that should be covered by the image, and white one that should not.
+		black, white image.Point
+	}{
+		{
+			name:  "small",
+			img:   imaging.New(100, 50, color.Black),
+			black: image.Pt(DisplayWidth/2, DisplayHeight/2),
+			white: image.Pt(DisplayWidth/2-51, DisplayHeight/2),
+		},
+		{
+			name:  "wide",
+			img:   imaging.New(DisplayWidth*2, DisplayHeight, color.Black),
+			black: image.Pt(0, DisplayHeight/2),
+			white: image.Pt(0, 0),
+		},
+	}
+	for _, c := range cases {
+		got := FitCentered(c.img)
+		if got.Bounds() != DisplayBounds {
+			t.Errorf("%s: FitCentered().Bounds() = %v, wanted %v", c.name, got.Bounds(), DisplayBounds)
+		}
+		if m := Model.Convert(got.At(c.black.X, c.black.Y)); m != Black {
+			t.Errorf("%s: FitCentered().At(%v) = %v, wanted %v", c.name, c.black, m, Black)
+		}
+		if m := Model.Convert(got.At(c.white.X, c.white.Y)); m != White {
+			t.Errorf("%s: FitCentered().At(%v) = %v, wanted %v", c.name, c.white, m, White)
+		}
+	}
+}
+
+func TestDisplayFit(t *testing.T) {
+	r := image.Rect(0, 0, 200, 100)
+	yellow := HighlightColors["yellow"]
+	d := &Display{buffer: NewImage(r), Background: Highlight, HighlightColor: yellow}
+	img := imaging.New(50, 50, color.Black)
+
+	got := d.FitCentered(img)
+	if got.Bounds() != r {
+		t.Errorf("FitCentered().Bounds() = %v, wanted %v", got.Bounds(), r)
+	}
+	if c := color.RGBAModel.Convert(got.At(0, 0)); c != yellow {
+		t.Errorf("FitCentered().At(0, 0) = %v, wanted the background %v", c, yellow)
+	}
+	if c := color.GrayModel.Convert(got.At(100, 50)); c != color.GrayModel.Convert(color.Black) {
+		t.Errorf("FitCentered().At(100, 50) = %v, wanted %v", c, color.Black)
+	}
+	if got := d.Fill(img); got.Bounds() != r {
+		t.Errorf("Fill().Bounds() = %v, wanted %v", got.Bounds(), r)
+	}
+}
+
+func TestFill(t *testing.T) {
+	img := imaging.New(DisplayWidth, DisplayHeight*2, color.White)
+	// A black band across the middle, which survives the crop, and a red one at the top, which
+	// doesn't.
+	for x := 0; x < DisplayWidth; x++ {
+		img.Set(x, DisplayHeight, color.Black)
+		img.Set(x, 0, color.RGBA{0xff, 0, 0, 0xff})
+	}
+	got := Fill(img)
+	if got.Bounds() != DisplayBounds {
+		t.Errorf("Fill().Bounds() = %v, wanted %v", got.Bounds(), DisplayBounds)
+	}
+	if m := Model.Convert(got.At(DisplayWidth/2, DisplayHeight/2)); m != Black {
+		t.Errorf("Fill().At(%d, %d) = %v, wanted %v", DisplayWidth/2, DisplayHeight/2, m, Black)
+	}
+	if h := Histogram(got); h[2] != 0 {
+		t.Errorf("Fill() kept %d highlight pixels, wanted the top of the image cropped", h[2])
+	}
+}
+
+func TestFitResample(t *testing.T) {
+	// A checkerboard of single pixels is gray once smoothed, but stays black or white when
+	// sampled.
+	img := image.NewGray(image.Rect(0, 0, DisplayWidth*2, DisplayHeight*2))
+	for y := 0; y < img.Rect.Dy(); y++ {
+		for x := 0; x < img.Rect.Dx(); x++ {
+			if (x+y)%2 == 0 {
+				img.Pix[img.PixOffset(x, y)] = 0xff
+			}
+		}
+	}
+	cases := []struct {
+		name     string
+		opts     []FitOption
+		wantGray bool
+	}{
+		{name: "default", wantGray: true},
+		{name: "linear", opts: []FitOption{WithResample(ResampleLinear)}, wantGray: true},
+		{name: "nearest", opts: []FitOption{WithResample(ResampleNearest)}},
+	}
+	for _, c := range cases {
+		for fn, got := range map[string]image.Image{"FitCentered": FitCentered(img, c.opts...), "Fill": Fill(img, c.opts...)} {
+			r, _, _, _ := got.At(DisplayWidth/2, DisplayHeight/2).RGBA()
+			if gray := r != 0 && r != 0xffff; gray != c.wantGray {
+				t.Errorf("%s: %s().At(center) = %#x, wanted gray: %v", c.name, fn, r, c.wantGray)
+			}
+		}
+	}
+}
+
+func TestParseResample(t *testing.T) {
+	for s, want := range map[string]Resample{"lanczos": ResampleLanczos, "Linear": ResampleLinear, "NEAREST": ResampleNearest} {
+		if got, err := ParseResample(s); got != want || err != nil {
+			t.Errorf("ParseResample(%q) = %v, %v, wanted %v, nil", s, got, err, want)
+		}
+	}
+	if _, err := ParseResample("bicubic"); err == nil {
+		t.Errorf("ParseResample(%q) = _, nil, wanted error", "bicubic")
+	}
+}
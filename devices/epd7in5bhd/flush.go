@@ -0,0 +1,26 @@
+package epd7in5bhd
+
+import "time"
+
+// Flush makes the panel show the buffer now, such as from a shutdown hook that must leave the
+// screen matching what was drawn. A frame still waiting from Enqueue is drawn first. Then both
+// planes of the whole buffer are uploaded and the panel is refreshed, as by Refresh, even if
+// the panel was last updated by RefreshRegion or RefreshHighlightOnly. Flush returns once the
+// panel reports that the refresh has finished.
+//
+// Flush does not wait for MinRefreshInterval, so the refresh starts at once. It still counts as
+// a refresh, so the next one waits out the interval from it.
+func (d *Display) Flush() error {
+	d.q.mu.Lock()
+	img := d.q.next
+	d.q.next = nil
+	d.q.mu.Unlock()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if img != nil {
+		d.draw(img)
+	}
+	d.lastRefresh = time.Now()
+	return d.sendFrame(d.planes())
+}
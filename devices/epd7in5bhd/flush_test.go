@@ -0,0 +1,40 @@
+package epd7in5bhd
+
+import (
+	"image"
+	"testing"
+	"time"
+)
+
+func TestFlush(t *testing.T) {
+	hw, bus := newFakeHardware()
+	d := &Display{hw: hw, buffer: NewImage(DisplayBounds), MinRefreshInterval: time.Hour, lastRefresh: time.Now()}
+	queued := image.NewPaletted(DisplayBounds, defaultPalette)
+	queued.SetColorIndex(0, 0, 1)
+	d.q.next = queued
+
+	start := time.Now()
+	if err := d.Flush(); err != nil {
+		t.Fatalf("Flush() = %v, wanted nil", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Minute {
+		t.Errorf("Flush() took %v, wanted it to skip MinRefreshInterval", elapsed)
+	}
+	if d.q.next != nil {
+		t.Errorf("Flush() left the enqueued frame waiting")
+	}
+	if got := d.buffer.At(0, 0); got != Black {
+		t.Errorf("buffer.At(0, 0) = %v after Flush(), wanted the enqueued frame's %v", got, Black)
+	}
+	cmds := bus.commands()
+	black, red := d.planes()
+	if n := countCommands(cmds, writeRAMBW, black...); n != 1 {
+		t.Errorf("sent the buffer's black plane %d times, wanted 1", n)
+	}
+	if n := countCommands(cmds, writeRAMRed, red...); n != 1 {
+		t.Errorf("sent the buffer's red plane %d times, wanted 1", n)
+	}
+	if n := countCommands(cmds, masterActivation); n != 1 {
+		t.Errorf("sent %v %d times, wanted 1", masterActivation, n)
+	}
+}
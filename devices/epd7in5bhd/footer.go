@@ -0,0 +1,64 @@
+package epd7in5bhd
+
+import (
+	"image"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/toothrot/gink/render"
+)
+
+// DefaultFooterScale is the footer's font scale when Display.FooterScale is unset, giving
+// 10x14 pixel characters.
+const DefaultFooterScale = 2
+
+// drawFooter draws d.Footer over the bottom of the buffer, in black on bg, or white on a black
+// bg. The strip behind it is filled with bg, and is a glyph's scale taller and wider than the
+// text on each side.
+func (d *Display) drawFooter(bg Color) {
+	if d.Footer == "" {
+		return
+	}
+	scale := d.FooterScale
+	if scale <= 0 {
+		scale = DefaultFooterScale
+	}
+	fg := Black
+	if bg == Black {
+		fg = White
+	}
+	lines := strings.Split(d.Footer, "\n")
+	dst := d.target()
+	b := dst.Bounds()
+	strip := image.Rect(b.Min.X, b.Max.Y-(len(lines)*GlyphHeight+2)*scale, b.Max.X, b.Max.Y).Intersect(b)
+	fillIndex(dst, strip, bg.C)
+
+	for n, line := range lines {
+		w := utf8.RuneCountInString(line) * GlyphWidth * scale
+		x := b.Min.X + (b.Dx()-w)/2
+		switch d.FooterAlign {
+		case render.AlignLeft:
+			x = b.Min.X + scale
+		case render.AlignRight:
+			x = b.Max.X - w - scale
+		}
+		text := NewImage(image.Rect(0, 0, w/scale, GlyphHeight))
+		text.DrawText(0, 0, line, Black)
+		y := strip.Min.Y + (1+n*GlyphHeight)*scale
+		text.Each(func(tx, ty int, idx uint8) {
+			if idx == 1 {
+				fillIndex(dst, image.Rect(x+tx*scale, y+ty*scale, x+(tx+1)*scale, y+(ty+1)*scale), fg.C)
+			}
+		})
+	}
+}
+
+// fillIndex sets the pixels of dst within r to the native color index.
+func fillIndex(dst indexedImage, r image.Rectangle, index uint8) {
+	r = r.Intersect(dst.Bounds())
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			dst.SetColorIndex(x, y, index)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package epd7in5bhd
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/toothrot/gink/render"
+)
+
+func TestFooter(t *testing.T) {
+	black := image.NewUniform(color.Black)
+	// "I" is a column of pixels two from the left of its glyph, rows 0 to 6.
+	stripTop := DisplayHeight - (GlyphHeight+2)*DefaultFooterScale
+	centerX := (DisplayWidth-GlyphWidth*DefaultFooterScale)/2 + 2*DefaultFooterScale
+	// With a scale of 2, aligned right with a margin of one font pixel.
+	rightTop := DisplayHeight - (GlyphHeight+2)*2
+	rightX := DisplayWidth - (GlyphWidth+1)*2 + 2*2
+	cases := []struct {
+		desc string
+		d    *Display
+		want map[image.Point]Color
+	}{
+		{
+			desc: "no footer",
+			want: map[image.Point]Color{{0, DisplayHeight - 1}: Black, {centerX, stripTop + 4*DefaultFooterScale}: Black},
+		},
+		{
+			desc: "centered",
+			d:    &Display{Footer: "I"},
+			want: map[image.Point]Color{
+				{0, stripTop - 1}: Black,
+				{0, stripTop}:     White,
+				{centerX, stripTop + 4*DefaultFooterScale}:                        Black,
+				{centerX - 2*DefaultFooterScale, stripTop + 4*DefaultFooterScale}: White,
+			},
+		},
+		{
+			desc: "right, scaled",
+			d:    &Display{Footer: "I", FooterScale: 2, FooterAlign: render.AlignRight},
+			want: map[image.Point]Color{
+				{rightX, rightTop + 4*2}:           Black,
+				{rightX + 2, rightTop + 4*2}:       White,
+				{DisplayWidth - 1, rightTop + 4*2}: White,
+				{0, rightTop - 1}:                  Black,
+			},
+		},
+		{
+			desc: "black background",
+			d:    &Display{Footer: "I", Background: Black},
+			want: map[image.Point]Color{
+				{0, stripTop}: Black,
+				{centerX, stripTop + 4*DefaultFooterScale}: White,
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			hw, _ := newFakeHardware()
+			d := c.d
+			if d == nil {
+				d = &Display{}
+			}
+			d.hw, d.buffer = hw, NewImage(DisplayBounds)
+			d.Draw(black)
+			for p, want := range c.want {
+				if got := d.buffer.At(p.X, p.Y); got != want {
+					t.Errorf("At(%d, %d) = %v, wanted %v", p.X, p.Y, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+//go:build go1.18
+// +build go1.18
+
+package epd7in5bhd
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+)
+
+// FuzzEncode is only built with Go 1.18 and later, which added testing.F, so that the rest of
+// the package's tests still run on the Go 1.16 that go.mod declares.
+func FuzzEncode(f *testing.F) {
+	f.Add(uint16(17), uint16(3), int16(0), int16(0), []byte{0xff, 0xff, 0xff, 0, 0, 0, 0xff, 0, 0}, []byte{0, 1, 2})
+	f.Add(uint16(DisplayWidth), uint16(1), int16(-4), int16(9), []byte{0, 0, 0, 0xff, 0xff, 0xff}, []byte{1, 0})
+	f.Add(uint16(0), uint16(0), int16(0), int16(0), []byte{}, []byte{})
+	f.Fuzz(func(t *testing.T, w, h uint16, x, y int16, palette, pix []byte) {
+		// Keep images small enough to convert quickly, while still allowing full display rows.
+		w, h = w%(DisplayWidth+9), h%32
+		r := image.Rect(int(x), int(y), int(x)+int(w), int(y)+int(h))
+
+		var img image.Image
+		if len(palette) >= 3 {
+			var p color.Palette
+			for i := 0; i+3 <= len(palette) && len(p) < 256; i += 3 {
+				p = append(p, color.RGBA{palette[i], palette[i+1], palette[i+2], 0xff})
+			}
+			pi := image.NewPaletted(r, p)
+			for i := range pi.Pix {
+				if len(pix) > 0 {
+					pi.Pix[i] = uint8(int(pix[i%len(pix)]) % len(p))
+				}
+			}
+			img = pi
+		} else {
+			rgba := image.NewRGBA(r)
+			for i := range rgba.Pix {
+				if len(pix) > 0 {
+					rgba.Pix[i] = pix[i%len(pix)]
+				}
+			}
+			img = rgba
+		}
+
+		wantLen := int(h) * ((int(w) + 7) / 8)
+		var black, red bytes.Buffer
+		Encode(&black, &red, img)
+		if black.Len() != wantLen || red.Len() != wantLen {
+			t.Errorf("Encode() of %v wrote %d, %d bytes, wanted %d", r, black.Len(), red.Len(), wantLen)
+		}
+		var sblack, sred bytes.Buffer
+		if err := EncodeStream(&sblack, &sred, img); err != nil {
+			t.Fatalf("EncodeStream() of %v = %v", r, err)
+		}
+		if !bytes.Equal(sblack.Bytes(), black.Bytes()) || !bytes.Equal(sred.Bytes(), red.Bytes()) {
+			t.Errorf("EncodeStream() of %v differs from Encode()", r)
+		}
+
+		d := &Display{buffer: NewImage(DisplayBounds), Mirror: x%2 == 0, FlipVertical: y%2 == 0}
+		d.Draw(img)
+		if len(d.buffer.Black) != BufSize || len(d.buffer.Highlight) != BufSize {
+			t.Errorf("Draw() of %v left buffers of %d, %d bytes, wanted %d", r, len(d.buffer.Black), len(d.buffer.Highlight), BufSize)
+		}
+	})
+}
@@ -0,0 +1,44 @@
+package epd7in5bhd
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/toothrot/gink/devices/internal/driver"
+	"github.com/toothrot/gink/devices/internal/driver/drivertest"
+)
+
+// fakeBus wraps drivertest.Bus to report commands with their names.
+type fakeBus struct {
+	*drivertest.Bus
+}
+
+// commands returns each command sent on the bus, in order, along with the data that followed it.
+func (b fakeBus) commands() []fakeCommand {
+	var cmds []fakeCommand
+	for _, c := range b.Commands() {
+		cmds = append(cmds, fakeCommand{cmd: command(c.Cmd), data: c.Data})
+	}
+	return cmds
+}
+
+type fakeCommand struct {
+	cmd  command
+	data []byte
+}
+
+// newFakeHardware returns hardware backed by fake pins and a recording bus. The busy pin
+// reports that the panel is idle.
+func newFakeHardware() (*driver.Hardware, fakeBus) {
+	hw, bus := drivertest.NewHardware()
+	return hw, fakeBus{bus}
+}
+
+func TestNewContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := NewContext(ctx, DefaultPins); !errors.Is(err, context.Canceled) {
+		t.Errorf("NewContext() = _, %v, wanted %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,172 @@
+package epd7in5bhd
+
+import (
+	"image"
+	"image/color"
+)
+
+const (
+	// DefaultHighlightThreshold is the distance used when Display.HighlightThreshold is zero.
+	// It matches moderately darker or lighter shades of a color, but not other hues.
+	DefaultHighlightThreshold = 64
+	// DefaultLuminanceThreshold is the threshold used when Display.LuminanceThreshold is zero,
+	// the middle of the range.
+	DefaultLuminanceThreshold = 128
+)
+
+// drawOver draws img over dst as drawImageOver does, matching colors against the panel's. If
+// HighlightMatches or HighlightMargin is set, each color is instead matched as they describe,
+// except in images drawn by a fast path when only HighlightMargin is set.
+func (d *Display) drawOver(dst indexedImage, img image.Image, bg Color) {
+	cv := d.converter(bg)
+	if len(d.HighlightMatches) == 0 && d.HighlightMargin == 0 {
+		drawImageOver(dst, img, cv)
+		return
+	}
+	if len(d.HighlightMatches) == 0 {
+		if pi, ok := img.(*image.Paletted); !ok || !isTranslucent(pi.Palette) {
+			if drawFastPath(dst, img, cv) {
+				return
+			}
+		}
+	}
+	if _, ok := img.(*Image); ok {
+		drawImageOver(dst, img, cv)
+		return
+	}
+	m := d.matcher(bg)
+	r := dst.Bounds().Intersect(img.Bounds())
+	if pi, ok := img.(*image.Paletted); ok {
+		native := make([]uint8, len(pi.Palette))
+		for i, c := range pi.Palette {
+			native[i] = m.index(c)
+		}
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			pix := pi.Pix[pi.PixOffset(r.Min.X, y):]
+			for x := r.Min.X; x < r.Max.X; x++ {
+				dst.SetColorIndex(x, y, native[pix[x-r.Min.X]])
+			}
+		}
+		return
+	}
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			dst.SetColorIndex(x, y, m.index(img.At(x, y)))
+		}
+	}
+}
+
+// converter returns a converter that matches colors against the panel's colors, as set by
+// HighlightColor, compositing over bg.
+func (d *Display) converter(bg Color) *converter {
+	return &converter{palette: d.palette(), bg: bg}
+}
+
+// palette returns the colors shown by the panel, in native index order.
+func (d *Display) palette() color.Palette {
+	return panelPalette(d.HighlightColor)
+}
+
+// matcher returns the colorMatcher configured by d's fields, compositing over bg.
+func (d *Display) matcher(bg Color) *colorMatcher {
+	m := &colorMatcher{
+		margin:    d.HighlightMargin,
+		luminance: d.LuminanceThreshold,
+		bg:        bg,
+	}
+	// The channels that are high in the highlight color are the ones that must exceed the
+	// others, such as red and green for a yellow panel.
+	hc := m.rgb(d.palette()[2])
+	for i, v := range hc {
+		m.high[i] = v >= 0x80
+	}
+	if m.luminance == 0 {
+		m.luminance = DefaultLuminanceThreshold
+	}
+	if len(d.HighlightMatches) > 0 {
+		threshold := d.HighlightThreshold
+		if threshold == 0 {
+			threshold = DefaultHighlightThreshold
+		}
+		m.limit = threshold * threshold
+		for _, c := range d.HighlightMatches {
+			m.highlights = append(m.highlights, m.rgb(c))
+		}
+	}
+	return m
+}
+
+// colorMatcher matches colors to native color indexes a pixel at a time, as described by
+// Display.HighlightMatches, or by Display.HighlightMargin if there are none.
+type colorMatcher struct {
+	// highlights are the 8-bit RGB values of the highlight colors. If there are none, pixels
+	// are matched by margin.
+	highlights [][3]int
+	// limit is the square of the highlight threshold.
+	limit  int
+	margin int
+	// high is which of the red, green, and blue channels are high in the panel's highlight
+	// color.
+	high      [3]bool
+	luminance int
+	// bg is the color that translucent colors are composited over.
+	bg color.Color
+}
+
+// rgb returns the 8-bit RGB values of c composited over bg.
+func (m *colorMatcher) rgb(c color.Color) [3]int {
+	r, g, b, a := c.RGBA()
+	if a < 0xffff {
+		br, bg, bb, _ := m.bg.RGBA()
+		r += br * (0xffff - a) / 0xffff
+		g += bg * (0xffff - a) / 0xffff
+		b += bb * (0xffff - a) / 0xffff
+	}
+	return [3]int{int(r >> 8), int(g >> 8), int(b >> 8)}
+}
+
+// index returns the native color index of c: 2 if it is highlight, otherwise 1 if it is dark,
+// and 0 if it is light.
+func (m *colorMatcher) index(c color.Color) uint8 {
+	v := m.rgb(c)
+	if m.isHighlight(v) {
+		return 2
+	}
+	// Luma, as used by color.GrayModel.
+	if 299*v[0]+587*v[1]+114*v[2] < m.luminance*1000 {
+		return 1
+	}
+	return 0
+}
+
+func (m *colorMatcher) isHighlight(v [3]int) bool {
+	if len(m.highlights) == 0 {
+		return m.margin > 0 && m.dominance(v) >= m.margin
+	}
+	for _, h := range m.highlights {
+		dr, dg, db := v[0]-h[0], v[1]-h[1], v[2]-h[2]
+		if dr*dr+dg*dg+db*db <= m.limit {
+			return true
+		}
+	}
+	return false
+}
+
+// dominance returns how much the lowest of the channels of v that are high in the panel's
+// highlight color exceeds the highest of the others. It is negative if the highlight color
+// has no high or no low channels, such as if it is white or black.
+func (m *colorMatcher) dominance(v [3]int) int {
+	lo, hi := 256, -1
+	for i, high := range m.high {
+		if high && v[i] < lo {
+			lo = v[i]
+		}
+		if !high && v[i] > hi {
+			hi = v[i]
+		}
+	}
+	if lo == 256 || hi == -1 {
+		return -1
+	}
+	return lo - hi
+}
@@ -0,0 +1,109 @@
+package epd7in5bhd
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestHighlightMatches(t *testing.T) {
+	palette := color.Palette{
+		color.White,
+		color.Black,
+		color.RGBA{255, 0, 0, 255},     // red
+		color.RGBA{220, 30, 30, 255},   // a darker red, 55 from red
+		color.RGBA{200, 200, 200, 255}, // light gray
+	}
+	img := image.NewPaletted(DisplayBounds, palette)
+	for i := range palette {
+		img.SetColorIndex(i, 0, uint8(i))
+	}
+	want := []Color{White, Black, Highlight, Highlight, White}
+
+	d := &Display{buffer: NewImage(DisplayBounds), HighlightMatches: []color.Color{color.RGBA{255, 0, 0, 255}}}
+	d.Draw(img)
+	for i, w := range want {
+		if got := d.buffer.At(i, 0); got != w {
+			t.Errorf("At(%d, 0) = %v for %v, wanted %v", i, got, palette[i], w)
+		}
+	}
+
+	// The same colors, from an image that isn't paletted.
+	rgba := image.NewRGBA(DisplayBounds)
+	for i, c := range palette {
+		rgba.Set(i, 0, c)
+	}
+	d.Draw(rgba)
+	for i, w := range want {
+		if got := d.buffer.At(i, 0); got != w {
+			t.Errorf("RGBA: At(%d, 0) = %v for %v, wanted %v", i, got, palette[i], w)
+		}
+	}
+
+	// A tighter threshold leaves the darker red to luminance, which is dark.
+	d.HighlightThreshold = 20
+	d.Draw(img)
+	if got := d.buffer.At(3, 0); got != Black {
+		t.Errorf("HighlightThreshold 20: At(3, 0) = %v, wanted %v", got, Black)
+	}
+}
+
+func TestHighlightMargin(t *testing.T) {
+	colors := []color.Color{
+		color.RGBA{255, 0, 0, 255},     // pure red
+		color.RGBA{230, 60, 40, 255},   // a saturated red, 170 over green
+		color.RGBA{127, 0, 0, 255},     // dark red, dark
+		color.RGBA{255, 170, 170, 255}, // pink, light
+		color.RGBA{0, 0, 255, 255},     // blue, dark
+		color.RGBA{160, 160, 160, 255}, // gray, light
+		color.RGBA{100, 100, 100, 255}, // gray, dark
+	}
+	img := image.NewRGBA(image.Rect(0, 0, len(colors), 1))
+	for i, c := range colors {
+		img.Set(i, 0, c)
+	}
+	cases := []struct {
+		desc              string
+		margin, luminance int
+		want              []Color
+	}{
+		{desc: "nearest", want: []Color{Highlight, Highlight, Black, White, Black, White, Black}},
+		{desc: "middle", margin: 128, want: []Color{Highlight, Highlight, Black, White, Black, White, Black}},
+		{desc: "strict", margin: 200, want: []Color{Highlight, Black, Black, White, Black, White, Black}},
+		{desc: "loose", margin: 100, want: []Color{Highlight, Highlight, Highlight, White, Black, White, Black}},
+		{desc: "dark", margin: 128, luminance: 200, want: []Color{Highlight, Highlight, Black, Black, Black, Black, Black}},
+	}
+	for _, c := range cases {
+		d := &Display{buffer: NewImage(DisplayBounds), HighlightMargin: c.margin, LuminanceThreshold: c.luminance}
+		d.Draw(img)
+		for x, want := range c.want {
+			if got := d.buffer.At(x, 0); got != want {
+				t.Errorf("%s: At(%d, 0) = %v for %v, wanted %v", c.desc, x, got, colors[x], want)
+			}
+		}
+	}
+}
+
+func TestDrawYellowPanel(t *testing.T) {
+	colors := []color.Color{
+		color.RGBA{255, 255, 0, 255},   // yellow
+		color.RGBA{230, 210, 40, 255},  // a darker yellow, 170 over blue
+		color.RGBA{255, 0, 0, 255},     // red, dark
+		color.RGBA{250, 250, 250, 255}, // white
+		color.RGBA{20, 20, 20, 255},    // black
+	}
+	img := image.NewRGBA(image.Rect(0, 0, len(colors), 1))
+	for i, c := range colors {
+		img.Set(i, 0, c)
+	}
+	want := []Color{Highlight, Highlight, Black, White, Black}
+	for _, margin := range []int{0, 128} {
+		d := &Display{buffer: NewImage(DisplayBounds), HighlightColor: HighlightColors["yellow"], HighlightMargin: margin}
+		d.Draw(img)
+		for x, w := range want {
+			if got := d.buffer.At(x, 0); got != w {
+				t.Errorf("HighlightMargin %d: At(%d, 0) = %v for %v, wanted %v", margin, x, got, colors[x], w)
+			}
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package epd7in5bhd
+
+import (
+	"image"
+)
+
+// Histogram counts the pixels of img that are nearest to White, Black, and Highlight, in that
+// order, over the part of img within DisplayBounds.
+//
+// It measures the source image before conversion, such as to tune its brightness or contrast
+// so that conversion or dithering keeps the intended detail.
+func Histogram(img image.Image) [3]int {
+	var h [3]int
+	r := img.Bounds().Intersect(DisplayBounds)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			h[defaultPalette.Index(img.At(x, y))]++
+		}
+	}
+	return h
+}
@@ -0,0 +1,23 @@
+package epd7in5bhd
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"golang.org/x/image/draw"
+)
+
+func TestHistogram(t *testing.T) {
+	img := image.NewRGBA(image.Rect(-10, 0, 20, 10))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+	// Dark gray is nearest to black, and orange is nearest to red.
+	draw.Draw(img, image.Rect(0, 0, 5, 10), image.NewUniform(color.Gray{0x30}), image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(5, 0, 7, 10), image.NewUniform(color.RGBA{0xf0, 0x40, 0x10, 0xff}), image.Point{}, draw.Src)
+
+	// Columns left of 0 are outside of the display.
+	want := [3]int{13 * 10, 5 * 10, 2 * 10}
+	if got := Histogram(img); got != want {
+		t.Errorf("Histogram() = %v, wanted %v", got, want)
+	}
+}
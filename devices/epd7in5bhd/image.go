@@ -2,9 +2,11 @@ package epd7in5bhd
 
 import (
 	"bytes"
+	"fmt"
 	"image"
 	"image/color"
 	"io"
+	"strings"
 
 	"golang.org/x/image/draw"
 )
@@ -17,6 +19,14 @@ var (
 	Model = color.ModelFunc(model)
 
 	defaultPalette = color.Palette{White, Black, Highlight}
+
+	// HighlightColors are the highlight colors of known panels, by name, for
+	// Display.HighlightColor.
+	HighlightColors = map[string]color.Color{
+		"red":    color.RGBA{0xff, 0, 0, 0xff},
+		"yellow": color.RGBA{0xff, 0xff, 0, 0xff},
+		"blue":   color.RGBA{0, 0, 0xff, 0xff},
+	}
 )
 
 type Color struct {
@@ -24,6 +34,52 @@ type Color struct {
 	C uint8
 }
 
+// IsWhite reports whether c is White.
+func (c Color) IsWhite() bool { return c == White }
+
+// IsBlack reports whether c is Black.
+func (c Color) IsBlack() bool { return c == Black }
+
+// IsHighlight reports whether c is Highlight.
+func (c Color) IsHighlight() bool { return c == Highlight }
+
+// ParseColor returns the Color named by s, one of "white", "black", or "highlight". "red" is
+// accepted as an alias for "highlight". Case is ignored.
+func ParseColor(s string) (Color, error) {
+	switch strings.ToLower(s) {
+	case "white":
+		return White, nil
+	case "black":
+		return Black, nil
+	case "highlight", "red":
+		return Highlight, nil
+	}
+	return Color{}, fmt.Errorf("unknown color %q, want white, black, red, or highlight", s)
+}
+
+// ParseHighlight returns the highlight color named by s, one of the names in HighlightColors,
+// for Display.HighlightColor. Case is ignored.
+func ParseHighlight(s string) (color.Color, error) {
+	if c, ok := HighlightColors[strings.ToLower(s)]; ok {
+		return c, nil
+	}
+	return nil, fmt.Errorf("unknown highlight color %q, want red, yellow, or blue", s)
+}
+
+// ParsePanelColor returns the color named by s, as for ParseColor, as shown by a panel whose
+// highlight plane is highlight, such as one of HighlightColors. A nil highlight means red.
+// Use it for images rendered before they are drawn, so that they match the panel.
+func ParsePanelColor(s string, highlight color.Color) (color.Color, error) {
+	c, err := ParseColor(s)
+	if err != nil {
+		return nil, err
+	}
+	if c == Highlight && highlight != nil {
+		return highlight, nil
+	}
+	return c, nil
+}
+
 func (c Color) RGBA() (r, g, b, a uint32) {
 	switch c.C {
 	case 0:
@@ -36,15 +92,52 @@ func (c Color) RGBA() (r, g, b, a uint32) {
 	return 0, 0, 0, 0
 }
 
+// panelColor is a native Color shown as the RGBA color of a particular panel, such as
+// Highlight on a panel with a yellow highlight plane, so that other colors are matched against
+// what the panel shows.
+type panelColor struct {
+	Color
+	rgba color.Color
+}
+
+func (c panelColor) RGBA() (r, g, b, a uint32) {
+	return c.rgba.RGBA()
+}
+
+// panelPalette returns the colors that other colors are matched against for a panel whose
+// highlight plane shows highlight, in native index order. Nil means red, the default palette.
+func panelPalette(highlight color.Color) color.Palette {
+	if highlight == nil {
+		return defaultPalette
+	}
+	return color.Palette{White, Black, panelColor{Highlight, highlight}}
+}
+
+// nativeColor returns c, a color from an Image's Palette, as a native Color.
+func nativeColor(c color.Color) Color {
+	if p, ok := c.(panelColor); ok {
+		return p.Color
+	}
+	return c.(Color)
+}
+
 func model(c color.Color) color.Color {
 	return defaultPalette.Convert(c)
 }
 
+// NewImage returns a white Image with bounds r.
+//
+// If r is empty, including when r.Min is greater than r.Max, the Image has no pixels: its
+// planes are empty, Set does nothing, and At returns White.
 func NewImage(r image.Rectangle) *Image {
 	widthByte := r.Dx() / 8
 	if r.Dx()%8 != 0 {
 		widthByte += 1
 	}
+	if r.Empty() {
+		// Inverted rectangles would otherwise give a negative size.
+		widthByte = 0
+	}
 	bufSize := r.Dy() * widthByte
 	return &Image{
 		Black:          bytes.Repeat([]byte{0xff}, bufSize),
@@ -61,18 +154,35 @@ type Image struct {
 	Black []byte
 	// Highlights are represented as 0 white, 1 highlight.
 	// Images are stored as a bit per pixel.
-	Highlight      []byte
-	Rect           image.Rectangle
+	Highlight []byte
+	Rect      image.Rectangle
+	// Palette holds the colors that Set matches other colors against. Each must be White,
+	// Black, or Highlight.
 	Palette        color.Palette
 	rectWidthBytes int
 }
 
+// pixOffset returns the index of the byte holding (x, y) in Black and Highlight, and the bit
+// for (x, y) within that byte. ok is false if (x, y) is outside of i.Rect.
+func (i *Image) pixOffset(x, y int) (px int, bit byte, ok bool) {
+	if !(image.Point{x, y}).In(i.Rect) {
+		return 0, 0, false
+	}
+	x, y = x-i.Rect.Min.X, y-i.Rect.Min.Y
+	return (x / 8) + (y * i.rectWidthBytes), byte(0x80 >> (uint32(x) % 8)), true
+}
+
 func (i *Image) SetColorIndex(x, y int, index uint8) {
-	px := (x / 8) + (y * i.rectWidthBytes)
-	if px >= len(i.Black) {
+	px, bit, ok := i.pixOffset(x, y)
+	if !ok {
 		return
 	}
-	bit := byte(0x80 >> (uint32(x) % 8))
+	i.setIndex(px, bit, index)
+}
+
+// setIndex sets the pixel at bit of byte px in each plane to the native color index. Other
+// indexes are ignored.
+func (i *Image) setIndex(px int, bit byte, index uint8) {
 	switch index {
 	case 0:
 		i.Black[px] |= bit
@@ -84,33 +194,90 @@ func (i *Image) SetColorIndex(x, y int, index uint8) {
 		i.Black[px] |= bit
 		i.Highlight[px] |= bit
 	}
-	return
+}
+
+// ColorIndexAt returns the native color index of the pixel at (x, y): 0 for white, 1 for black,
+// and 2 for highlight. Pixels outside of the image are white.
+func (i *Image) ColorIndexAt(x, y int) uint8 {
+	px, bit, ok := i.pixOffset(x, y)
+	if !ok {
+		return 0
+	}
+	return i.indexAt(px, bit)
+}
+
+// indexAt returns the native color index of the pixel at bit of byte px in each plane.
+func (i *Image) indexAt(px int, bit byte) uint8 {
+	if i.Highlight[px]&bit != 0 {
+		return 2
+	}
+	if i.Black[px]&bit != 0 {
+		return 0
+	}
+	return 1
+}
+
+// Each calls fn with every pixel of i and its native color index, as returned by ColorIndexAt:
+// 0 for white, 1 for black, and 2 for highlight. Pixels are visited a row at a time, from the
+// top left.
+//
+// The planes are read directly, without the bounds checks of ColorIndexAt or the color
+// conversion of At, and nothing is allocated.
+func (i *Image) Each(fn func(x, y int, idx uint8)) {
+	for y := i.Rect.Min.Y; y < i.Rect.Max.Y; y++ {
+		px := (y - i.Rect.Min.Y) * i.rectWidthBytes
+		bit := byte(0x80)
+		for x := i.Rect.Min.X; x < i.Rect.Max.X; x++ {
+			fn(x, y, i.indexAt(px, bit))
+			if bit >>= 1; bit == 0 {
+				bit = 0x80
+				px++
+			}
+		}
+	}
+}
+
+// EachSet is like Each, but sets each pixel to the native color index fn returns, such as to
+// apply a mask or a pattern to the buffer. Returning an index other than 0, 1, or 2 leaves the
+// pixel as it was.
+func (i *Image) EachSet(fn func(x, y int, idx uint8) uint8) {
+	for y := i.Rect.Min.Y; y < i.Rect.Max.Y; y++ {
+		px := (y - i.Rect.Min.Y) * i.rectWidthBytes
+		bit := byte(0x80)
+		for x := i.Rect.Min.X; x < i.Rect.Max.X; x++ {
+			i.setIndex(px, bit, fn(x, y, i.indexAt(px, bit)))
+			if bit >>= 1; bit == 0 {
+				bit = 0x80
+				px++
+			}
+		}
+	}
+}
+
+// ToPaletted returns a copy of i as an *image.Paletted with the palette {White, Black,
+// Highlight}, so it can be encoded with the standard library's GIF or PNG encoders.
+func (i *Image) ToPaletted() *image.Paletted {
+	p := image.NewPaletted(i.Rect, color.Palette{White, Black, Highlight})
+	for y := i.Rect.Min.Y; y < i.Rect.Max.Y; y++ {
+		for x := i.Rect.Min.X; x < i.Rect.Max.X; x++ {
+			p.SetColorIndex(x, y, i.ColorIndexAt(x, y))
+		}
+	}
+	return p
 }
 
 func (i *Image) Set(x, y int, c color.Color) {
-	px := (x / 8) + (y * i.rectWidthBytes)
-	if px >= len(i.Black) {
+	px, bit, ok := i.pixOffset(x, y)
+	if !ok {
 		return
 	}
 	var cc Color
 	if native, ok := c.(Color); ok {
 		cc = native
 	} else {
-		cc = i.Palette.Convert(c).(Color)
-	}
-	bit := byte(0x80 >> (uint32(x) % 8))
-	switch cc.C {
-	case 0:
-		i.Black[px] |= bit
-		i.Highlight[px] &= ^bit
-	case 1:
-		i.Black[px] &= ^bit
-		i.Highlight[px] &= ^bit
-	case 2:
-		i.Black[px] |= bit
-		i.Highlight[px] |= bit
+		cc = nativeColor(i.Palette.Convert(c))
 	}
-	return
+	i.setIndex(px, bit, cc.C)
 }
 
 func (i *Image) ColorModel() color.Model {
@@ -122,51 +289,436 @@ func (i *Image) Bounds() image.Rectangle {
 }
 
 func (i *Image) At(x, y int) color.Color {
-	if !(image.Point{x, y}).In(i.Rect) {
-		return White
+	return Color{i.ColorIndexAt(x, y)}
+}
+
+// Reset sets every pixel to White, in place.
+func (i *Image) Reset() {
+	i.fill(White)
+}
+
+// fill sets every pixel to c, in place.
+func (i *Image) fill(c Color) {
+	var b, h byte = 0xff, 0x00
+	switch c {
+	case Black:
+		b = 0x00
+	case Highlight:
+		h = 0xff
 	}
-	px := (x / 8) + y*DisplayWidthBytes
-	bit := byte(0x80 >> (uint32(x) % 8))
-	bbit := i.Black[px] & bit
-	hbit := i.Highlight[px] & bit
-	if hbit >= 1 {
-		return Highlight
+	for j := range i.Black {
+		i.Black[j] = b
 	}
-	if bbit >= 1 {
-		return White
+	for j := range i.Highlight {
+		i.Highlight[j] = h
 	}
-	return Black
 }
 
-func (i *Image) Reset() {
-	i.Black = bytes.Repeat([]byte{0xff}, len(i.Black))
-	i.Highlight = make([]byte, len(i.Highlight), len(i.Highlight))
+// indexedImage is a draw.Image that can also be written to by native color index.
+type indexedImage interface {
+	draw.Image
+	SetColorIndex(x, y int, index uint8)
 }
 
-// drawExactColors is a fast-path for when we have exactly 3 colors in the src image.
+// drawImage draws src over the bounds of dst, as draw.Draw does with draw.Src.
 //
-// If src is a *image.Paletted with exactly 3 colors, each color will be assigned to its
-// nearest by euclidean distance. Otherwise, colors will be assigned by a per-pixel calculation.
-func (i *Image) drawExactColors(src *image.Paletted) {
-	white, black, highlight := exactColorIndex(src)
-	for y := 0; y < DisplayBounds.Dy(); y++ {
-		for x := 0; x < DisplayBounds.Dx(); x++ {
+// If src is a *image.Paletted whose palette is exactly {White, Black, Highlight}, its color
+// indexes are copied as-is. If src is a *image.Paletted with any other 3 colors, each color will
+// be assigned to its nearest by euclidean distance. Otherwise, colors will be assigned by a
+// per-pixel calculation.
+//
+// If src is a *image.Paletted with 2 colors that are nearest to black or white, only the
+// black plane is written. If src is a *image.Paletted with more than 3 colors, but at most 3 of
+// them are used, it is drawn as if its palette only held the used colors. An *Image with the
+// same bounds as dst, such as one from a FrameCache, is copied a plane at a time.
+//
+// Other images with transparent pixels are composited over white before their colors are
+// matched, as drawImageOver does.
+func drawImage(dst indexedImage, src image.Image) {
+	drawImageOver(dst, src, defaultConverter)
+}
+
+// converter matches colors to native color indexes.
+type converter struct {
+	// palette holds the colors shown by native indexes 0, 1, and 2.
+	palette color.Palette
+	// bg is the color that translucent colors are composited over.
+	bg Color
+}
+
+// defaultConverter matches colors against the default palette, compositing over white.
+var defaultConverter = &converter{palette: defaultPalette, bg: White}
+
+// index returns the native color index of the color in the palette nearest to c composited
+// over bg. A native Color is kept as it is.
+func (cv *converter) index(c color.Color) uint8 {
+	if native, ok := c.(Color); ok {
+		return native.C
+	}
+	if _, _, _, a := c.RGBA(); a != 0xffff {
+		c = over(c, cv.bg)
+	}
+	return uint8(cv.palette.Index(c))
+}
+
+// drawImageOver is like drawImage, but matches colors with cv, and composites images with
+// transparent pixels, such as an *image.NRGBA with an alpha channel, over its background.
+// Without this, transparent pixels would be matched by their premultiplied color, which is
+// nearest to black.
+func drawImageOver(dst indexedImage, src image.Image, cv *converter) {
+	if pi, ok := src.(*image.Paletted); ok && isTranslucent(pi.Palette) {
+		drawPalettedOver(dst, pi, cv)
+		return
+	}
+	if drawFastPath(dst, src, cv) {
+		return
+	}
+	r := dst.Bounds().Intersect(src.Bounds())
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			dst.SetColorIndex(x, y, cv.index(src.At(x, y)))
+		}
+	}
+}
+
+// drawFastPath draws src over dst if it is an *Image or *image.Paletted that drawImage matches
+// a palette at a time, and reports whether it did.
+func drawFastPath(dst indexedImage, src image.Image, cv *converter) bool {
+	if si, ok := src.(*Image); ok {
+		if di, ok := dst.(*Image); ok && di.Rect == si.Rect {
+			copy(di.Black, si.Black)
+			copy(di.Highlight, si.Highlight)
+			return true
+		}
+	}
+	pi, ok := src.(*image.Paletted)
+	if !ok {
+		return false
+	}
+	if len(pi.Palette) > 3 && drawUsedColors(dst, pi, cv) {
+		return true
+	}
+	switch len(pi.Palette) {
+	case 2:
+		return drawTwoColors(dst, pi, cv)
+	case 3:
+		if isNativePalette(pi.Palette) {
+			drawNativeColors(dst, pi)
+			return true
+		}
+		drawExactColors(dst, pi, cv)
+		return true
+	}
+	return false
+}
+
+// isTranslucent reports whether any color in p is not fully opaque.
+func isTranslucent(p color.Palette) bool {
+	for _, c := range p {
+		if _, _, _, a := c.RGBA(); a != 0xffff {
+			return true
+		}
+	}
+	return false
+}
+
+// drawPalettedOver draws src, a paletted image with translucent colors such as a logo with a
+// transparent background, by matching each color of its palette with cv.
+func drawPalettedOver(dst indexedImage, src *image.Paletted, cv *converter) {
+	var native [256]uint8
+	for i, c := range src.Palette {
+		native[i] = cv.index(c)
+	}
+	drawIndexes(dst, src, &native)
+}
+
+// over returns c composited over bg.
+func over(c color.Color, bg Color) color.Color {
+	r, g, b, a := c.RGBA()
+	br, bgg, bb, _ := bg.RGBA()
+	return color.RGBA64{
+		R: uint16(r + br*(0xffff-a)/0xffff),
+		G: uint16(g + bgg*(0xffff-a)/0xffff),
+		B: uint16(b + bb*(0xffff-a)/0xffff),
+		A: 0xffff,
+	}
+}
+
+// isOpaque reports whether img is fully opaque. Images that can't report it are assumed to be.
+func isOpaque(img image.Image) bool {
+	o, ok := img.(interface{ Opaque() bool })
+	return !ok || o.Opaque()
+}
+
+// drawExactColors is a fast-path for when we have exactly 3 colors in the src image. Only
+// pixels within both dst and src are drawn.
+func drawExactColors(dst indexedImage, src *image.Paletted, cv *converter) {
+	white, black, highlight := exactColorIndex(src, cv.palette)
+	r := dst.Bounds().Intersect(src.Bounds())
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
 			switch int(src.ColorIndexAt(x, y)) {
 			case white:
-				i.SetColorIndex(x, y, 0)
+				dst.SetColorIndex(x, y, 0)
 			case black:
-				i.SetColorIndex(x, y, 1)
+				dst.SetColorIndex(x, y, 1)
 			case highlight:
-				i.SetColorIndex(x, y, 2)
+				dst.SetColorIndex(x, y, 2)
 			}
 		}
 	}
 }
 
-func exactColorIndex(src *image.Paletted) (white, black, highlight int) {
+// drawUsedColors is a fast-path for when src uses at most 3 of the colors in its palette, as
+// described by usedColorIndexes. It reports false without drawing anything if src uses more
+// than 3 colors.
+func drawUsedColors(dst indexedImage, src *image.Paletted, cv *converter) bool {
+	native, ok := usedColorIndexes(src, cv)
+	if ok {
+		drawIndexes(dst, src, &native)
+	}
+	return ok
+}
+
+// usedColorIndexes returns the native color index of each palette index of src, if src uses
+// at most 3 of the colors in its palette. 3 used colors are matched as drawExactColors matches
+// them, and fewer are each matched with cv.
+//
+// The colors used anywhere in src are counted, not just those within the part being drawn, so
+// that drawing src a part at a time, as EncodeStream does, matches drawing it whole.
+func usedColorIndexes(src *image.Paletted, cv *converter) (native [256]uint8, ok bool) {
+	r := src.Bounds()
+	var seen [256]bool
+	var used []uint8
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		pix := src.Pix[src.PixOffset(r.Min.X, y):]
+		for _, idx := range pix[:r.Dx()] {
+			if seen[idx] {
+				continue
+			}
+			if len(used) == 3 {
+				return native, false
+			}
+			seen[idx] = true
+			used = append(used, idx)
+		}
+	}
+
+	if len(used) == 3 {
+		sub := &image.Paletted{Palette: color.Palette{src.Palette[used[0]], src.Palette[used[1]], src.Palette[used[2]]}}
+		white, black, highlight := exactColorIndex(sub, cv.palette)
+		native[used[white]], native[used[black]], native[used[highlight]] = 0, 1, 2
+	} else {
+		for _, idx := range used {
+			native[idx] = cv.index(src.Palette[idx])
+		}
+	}
+	return native, true
+}
+
+// drawIndexes draws the pixels of src within dst, mapping each palette index to the native
+// color index in native.
+func drawIndexes(dst indexedImage, src *image.Paletted, native *[256]uint8) {
+	r := dst.Bounds().Intersect(src.Bounds())
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		pix := src.Pix[src.PixOffset(r.Min.X, y):]
+		for x := r.Min.X; x < r.Max.X; x++ {
+			dst.SetColorIndex(x, y, native[pix[x-r.Min.X]])
+		}
+	}
+}
+
+// flipped is an Image with its coordinates mirrored horizontally, vertically, or both.
+type flipped struct {
+	*Image
+	h, v bool
+}
+
+func (f *flipped) flip(x, y int) (int, int) {
+	if f.h {
+		x = f.Rect.Min.X + f.Rect.Max.X - 1 - x
+	}
+	if f.v {
+		y = f.Rect.Min.Y + f.Rect.Max.Y - 1 - y
+	}
+	return x, y
+}
+
+func (f *flipped) At(x, y int) color.Color {
+	return f.Image.At(f.flip(x, y))
+}
+
+func (f *flipped) Set(x, y int, c color.Color) {
+	x, y = f.flip(x, y)
+	f.Image.Set(x, y, c)
+}
+
+func (f *flipped) SetColorIndex(x, y int, index uint8) {
+	x, y = f.flip(x, y)
+	f.Image.SetColorIndex(x, y, index)
+}
+
+// translate returns img moved so that its bounds start at pt. A *image.Paletted is returned as
+// one sharing the same pixels, so that drawImage can still use its fast paths.
+func translate(img image.Image, pt image.Point) image.Image {
+	off := pt.Sub(img.Bounds().Min)
+	if off == (image.Point{}) {
+		return img
+	}
+	if p, ok := img.(*image.Paletted); ok {
+		moved := *p
+		moved.Rect = p.Rect.Add(off)
+		return &moved
+	}
+	return &translated{Image: img, off: off}
+}
+
+// translated is an image moved by off.
+type translated struct {
+	image.Image
+	off image.Point
+}
+
+func (t *translated) Bounds() image.Rectangle {
+	return t.Image.Bounds().Add(t.off)
+}
+
+func (t *translated) At(x, y int) color.Color {
+	return t.Image.At(x-t.off.X, y-t.off.Y)
+}
+
+func (t *translated) Opaque() bool {
+	return isOpaque(t.Image)
+}
+
+// isNativePalette reports whether p is exactly {White, Black, Highlight}, in that order.
+func isNativePalette(p color.Palette) bool {
+	if len(p) != len(defaultPalette) {
+		return false
+	}
+	for i, c := range p {
+		if c != defaultPalette[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// drawNativeColors is a fast-path for when src uses the native palette, so that its color
+// indexes can be written directly.
+func drawNativeColors(dst indexedImage, src *image.Paletted) {
+	r := dst.Bounds().Intersect(src.Bounds())
+	img, ok := dst.(*Image)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		pix := src.Pix[src.PixOffset(r.Min.X, y):]
+		if !ok {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				dst.SetColorIndex(x, y, pix[x-r.Min.X])
+			}
+			continue
+		}
+		px, _, _ := img.pixOffset(r.Min.X, y)
+		bit := byte(0x80 >> (uint32(r.Min.X-img.Rect.Min.X) % 8))
+		for _, idx := range pix[:r.Dx()] {
+			switch idx {
+			case 0:
+				img.Black[px] |= bit
+				img.Highlight[px] &^= bit
+			case 1:
+				img.Black[px] &^= bit
+				img.Highlight[px] &^= bit
+			case 2:
+				img.Black[px] |= bit
+				img.Highlight[px] |= bit
+			}
+			if bit >>= 1; bit == 0 {
+				bit = 0x80
+				px++
+			}
+		}
+	}
+}
+
+// drawTwoColors is a fast-path for when src has 2 colors, neither of which cv matches to the
+// highlight color. It reports false without drawing anything if either color is.
+//
+// When dst is an *Image, only the black plane is written pixel by pixel, and whole bytes of it
+// are packed at once where the row allows. The highlight plane is cleared a byte at a time.
+func drawTwoColors(dst indexedImage, src *image.Paletted, cv *converter) bool {
+	var native [256]uint8
+	// blackBit is the bit each index sets in the black plane, which is 1 for white.
+	var blackBit [256]byte
+	for idx, c := range src.Palette {
+		n := cv.index(c)
+		if n == 2 {
+			return false
+		}
+		native[idx] = n
+		if n == 0 {
+			blackBit[idx] = 1
+		}
+	}
+	r := dst.Bounds().Intersect(src.Bounds())
+	img, ok := dst.(*Image)
+	if !ok {
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				dst.SetColorIndex(x, y, native[src.ColorIndexAt(x, y)])
+			}
+		}
+		return true
+	}
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		pix := src.Pix[src.PixOffset(r.Min.X, y) : src.PixOffset(r.Min.X, y)+r.Dx()]
+		px, bit, _ := img.pixOffset(r.Min.X, y)
+		for len(pix) > 0 {
+			if bit == 0x80 && len(pix) >= 8 {
+				var b byte
+				for _, idx := range pix[:8] {
+					b = b<<1 | blackBit[idx]
+				}
+				img.Black[px] = b
+				px++
+				pix = pix[8:]
+				continue
+			}
+			if blackBit[pix[0]] == 0 {
+				img.Black[px] &^= bit
+			} else {
+				img.Black[px] |= bit
+			}
+			if bit >>= 1; bit == 0 {
+				bit = 0x80
+				px++
+			}
+			pix = pix[1:]
+		}
+		img.clearHighlight(y, r.Min.X, r.Max.X)
+	}
+	return true
+}
+
+// clearHighlight clears the highlight plane on row y from x0 up to, but not including, x1.
+// The row and columns must be within i.Rect.
+func (i *Image) clearHighlight(y, x0, x1 int) {
+	y, x0, x1 = y-i.Rect.Min.Y, x0-i.Rect.Min.X, x1-i.Rect.Min.X
+	row := i.Highlight[y*i.rectWidthBytes : (y+1)*i.rectWidthBytes]
+	for x := x0; x < x1; {
+		if x%8 == 0 && x+8 <= x1 {
+			row[x/8] = 0
+			x += 8
+			continue
+		}
+		row[x/8] &^= byte(0x80 >> (uint32(x) % 8))
+		x++
+	}
+}
+
+// exactColorIndex returns the indexes of the colors in src's palette of 3 that are matched to
+// the colors of panel, which holds white, black, and the highlight color, in that order.
+func exactColorIndex(src *image.Paletted, panel color.Palette) (white, black, highlight int) {
 	// This order is significant. We want to try to assign white and black before our third color,
 	// as they may be closer to a totally non-red color (blue).
-	colors := []color.Color{color.White, color.Black, color.RGBA{255, 0, 0, 255}}
 	p := color.Palette{}
 	ip := make(color.Palette, len(src.Palette))
 	copy(ip, src.Palette)
@@ -174,7 +726,7 @@ func exactColorIndex(src *image.Paletted) (white, black, highlight int) {
 	// src.Palette lightest, src.Palette darkest, src.Palette remaining
 	// Iterate over colors, popping as we go to avoid duplicates.
 	// We don't want both faint red and white to be white.
-	for _, c := range colors {
+	for _, c := range panel {
 		ci := ip.Index(c)
 		p = append(p, ip[ci])
 		ip = append(ip[:ci], ip[ci+1:]...)
@@ -183,15 +735,107 @@ func exactColorIndex(src *image.Paletted) (white, black, highlight int) {
 	return src.Palette.Index(p[0]), src.Palette.Index(p[1]), src.Palette.Index(p[2])
 }
 
-// Encode encodes an image to the display's wire format.
-func Encode(dstBlack, dstRed io.Writer, img image.Image) {
-	bounds := img.Bounds()
-	dst := NewImage(bounds)
-	if pi, ok := img.(*image.Paletted); ok && len(pi.Palette) == 3 {
-		dst.drawExactColors(pi)
-	} else {
-		draw.Draw(dst, bounds, img, image.Point{0, 0}, draw.Src)
+// EncodeOption changes the output of Encode and EncodeStream.
+type EncodeOption func(*encodeOptions)
+
+type encodeOptions struct {
+	invertBlack     bool
+	invertHighlight bool
+}
+
+// InvertBlack inverts every bit of the black plane, so that a black pixel is 1 and any other
+// pixel is 0.
+func InvertBlack() EncodeOption {
+	return func(o *encodeOptions) {
+		o.invertBlack = true
+	}
+}
+
+// InvertHighlight inverts every bit of the highlight plane, so that a highlight pixel is 0 and
+// any other pixel is 1. This matches the red images of the vendor's C examples, which invert
+// them as they are sent.
+func InvertHighlight() EncodeOption {
+	return func(o *encodeOptions) {
+		o.invertHighlight = true
+	}
+}
+
+// invert inverts the planes of black and red in place as set by opts.
+func invert(black, red []byte, opts []EncodeOption) {
+	var o encodeOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	for _, p := range []struct {
+		b   []byte
+		inv bool
+	}{{black, o.invertBlack}, {red, o.invertHighlight}} {
+		if !p.inv {
+			continue
+		}
+		for i := range p.b {
+			p.b[i] = ^p.b[i]
+		}
+	}
+}
+
+// Encode encodes an image to the display's wire format. Nothing is written for an empty image.
+// Colors are matched as Convert matches them.
+//
+// Each plane has a bit per pixel, with the leftmost pixel in the most significant bit and each
+// row padded to a whole byte. In the black plane a black pixel is 0 and any other pixel is 1. In
+// the highlight plane a highlight pixel is 1 and any other pixel is 0. Either plane can be
+// inverted with InvertBlack or InvertHighlight, including its padding bits.
+//
+// Each plane is sized to img's bounds, (Dx+7)/8*Dy bytes, so only an image the size of the
+// display gives planes of BufSize. Upload pads shorter planes, but expects rows of the full
+// display width, so encode a display-sized image, such as one from FitCentered, for Upload.
+func Encode(dstBlack, dstRed io.Writer, img image.Image, opts ...EncodeOption) {
+	black, red := Convert(img)
+	invert(black, red, opts)
+	dstBlack.Write(black)
+	dstRed.Write(red)
+}
+
+// EncodeStream is like Encode, but converts and writes img a row at a time, so only a row of
+// each plane is held in memory. Its output is identical to Encode's, so nothing is written for
+// an empty image.
+func EncodeStream(dstBlack, dstRed io.Writer, img image.Image, opts ...EncodeOption) error {
+	r := img.Bounds()
+	if r.Empty() {
+		return nil
+	}
+	draw := func(row *Image) { drawImage(row, img) }
+	// The colors a large palette uses are found once, rather than for each row.
+	if p, ok := img.(*image.Paletted); ok && len(p.Palette) > 3 {
+		if native, ok := usedColorIndexes(p, defaultConverter); ok {
+			draw = func(row *Image) { drawIndexes(row, p, &native) }
+		}
 	}
-	dstBlack.Write(dst.Black)
-	dstRed.Write(dst.Highlight)
+	row := NewImage(image.Rect(r.Min.X, r.Min.Y, r.Max.X, r.Min.Y+1))
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		row.Rect = image.Rect(r.Min.X, y, r.Max.X, y+1)
+		row.Reset()
+		draw(row)
+		invert(row.Black, row.Highlight, opts)
+		if _, err := dstBlack.Write(row.Black); err != nil {
+			return fmt.Errorf("writing black row %d: %w", y, err)
+		}
+		if _, err := dstRed.Write(row.Highlight); err != nil {
+			return fmt.Errorf("writing highlight row %d: %w", y, err)
+		}
+	}
+	return nil
+}
+
+// Convert converts an image to the display's wire format, returning the planes that
+// Display.Upload expects. It is the same conversion as Encode.
+//
+// Colors are matched as a Display with the default settings matches them, to the nearest of
+// white, black, and red. Draw the image with a Display to match them against its
+// HighlightColor, HighlightMargin, and HighlightMatches instead.
+func Convert(img image.Image) (black, red []byte) {
+	dst := NewImage(img.Bounds())
+	drawImage(dst, img)
+	return dst.Black, dst.Highlight
 }
@@ -1,9 +1,12 @@
 package epd7in5bhd
 
 import (
+	"bytes"
 	"image"
 	"image/color"
 	"testing"
+
+	"golang.org/x/image/draw"
 )
 
 type pixel struct {
@@ -86,3 +89,630 @@ func TestImageSet(t *testing.T) {
 		})
 	}
 }
+
+func TestDrawTwoColors(t *testing.T) {
+	cases := []struct {
+		desc    string
+		palette color.Palette
+		r       image.Rectangle
+	}{
+		{
+			desc:    "black and white",
+			palette: color.Palette{color.White, color.Black},
+			r:       image.Rect(0, 0, 24, 3),
+		},
+		{
+			desc:    "gray and white, unaligned",
+			palette: color.Palette{color.Gray{0xF0}, color.Gray{0x20}},
+			r:       image.Rect(3, 0, 21, 3),
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			src := image.NewPaletted(c.r, c.palette)
+			for i := range src.Pix {
+				src.Pix[i] = uint8(i % 3 % 2)
+			}
+			got, want := NewImage(image.Rect(0, 0, 24, 3)), NewImage(image.Rect(0, 0, 24, 3))
+			// Start with highlight everywhere to check that only the drawn region is cleared.
+			for _, img := range []*Image{got, want} {
+				for i := range img.Highlight {
+					img.Highlight[i] = 0xFF
+				}
+			}
+			if !drawTwoColors(got, src, defaultConverter) {
+				t.Fatalf("drawTwoColors() = false, wanted true")
+			}
+			draw.Draw(want, want.Bounds(), src, image.Point{}, draw.Src)
+			if _, n := DiffImage(got, want); n != 0 {
+				t.Errorf("drawTwoColors() differs from draw.Draw() in %d pixels", n)
+			}
+		})
+	}
+}
+
+func TestDrawTwoColorsHighlight(t *testing.T) {
+	src := image.NewPaletted(image.Rect(0, 0, 8, 1), color.Palette{color.White, color.RGBA{255, 0, 0, 255}})
+	if drawTwoColors(NewImage(src.Rect), src, defaultConverter) {
+		t.Errorf("drawTwoColors() = true for a palette with a highlight color, wanted false")
+	}
+}
+
+func TestImageSetOutOfBounds(t *testing.T) {
+	pts := []image.Point{
+		{-1, 1},
+		{-8, 1},
+		{12, 0},
+		{16, 0},
+		{0, -1},
+		{0, 2},
+	}
+	for _, pt := range pts {
+		t.Run(pt.String(), func(t *testing.T) {
+			img := NewImage(image.Rect(0, 0, 12, 2))
+			want := NewImage(image.Rect(0, 0, 12, 2))
+			img.Set(pt.X, pt.Y, color.Black)
+			img.SetColorIndex(pt.X, pt.Y, 2)
+			if !img.Equal(want) {
+				t.Errorf("setting %v changed img to Black: %08b, Highlight: %08b, wanted no change", pt, img.Black, img.Highlight)
+			}
+		})
+	}
+}
+
+func TestImageSetOffset(t *testing.T) {
+	img := NewImage(image.Rect(10, 20, 26, 22))
+	img.Set(10, 20, color.Black)
+	img.SetColorIndex(25, 21, 2)
+	if img.Black[0] != 0b0111_1111 {
+		t.Errorf("img.Black[0] = %08b, wanted %08b", img.Black[0], 0b0111_1111)
+	}
+	if img.Highlight[3] != 0b0000_0001 {
+		t.Errorf("img.Highlight[3] = %08b, wanted %08b", img.Highlight[3], 0b0000_0001)
+	}
+	if got := img.ColorIndexAt(25, 21); got != 2 {
+		t.Errorf("img.ColorIndexAt(25, 21) = %d, wanted 2", got)
+	}
+}
+
+func TestDrawNativeColors(t *testing.T) {
+	r := image.Rect(0, 0, 16, 2)
+	cases := []struct {
+		p  color.Palette
+		sr image.Rectangle
+	}{
+		{p: color.Palette{White, Black, Highlight}, sr: r},
+		{p: color.Palette{White, Black, Highlight}, sr: image.Rect(3, 1, 13, 2)},
+		// The native colors in another order are matched by exactColorIndex instead.
+		{p: color.Palette{Highlight, White, Black}, sr: r},
+		{p: color.Palette{Highlight, White, Black}, sr: image.Rect(3, 1, 13, 2)},
+	}
+	for _, c := range cases {
+		src := image.NewPaletted(c.sr, c.p)
+		for i := range src.Pix {
+			src.Pix[i] = uint8(i % 3)
+		}
+		got, want := NewImage(r), NewImage(r)
+		drawImage(got, src)
+		draw.Draw(want, r, src, image.Point{}, draw.Src)
+		if _, n := DiffImage(got, want); n != 0 {
+			t.Errorf("drawImage() of %v with palette %v differs from draw.Draw() in %d pixels", c.sr, c.p, n)
+		}
+	}
+}
+
+func TestDrawExactColorsBounds(t *testing.T) {
+	sr := image.Rect(200, 100, 300, 200)
+	// Index 0 is black, so pixels read from outside of src would also be drawn black.
+	src := image.NewPaletted(sr, color.Palette{color.Black, color.White, color.RGBA{0xff, 0x20, 0x20, 0xff}})
+	img := NewImage(DisplayBounds)
+	drawImage(img, src)
+
+	diff, n := DiffImage(img, NewImage(DisplayBounds))
+	if n != sr.Dx()*sr.Dy() {
+		t.Errorf("drawImage() changed %d pixels, wanted %d", n, sr.Dx()*sr.Dy())
+	}
+	for _, pt := range []image.Point{sr.Min, sr.Max.Sub(image.Pt(1, 1))} {
+		if c := diff.At(pt.X, pt.Y); c != Highlight {
+			t.Errorf("pixel %v was not changed, wanted it changed", pt)
+		}
+	}
+}
+
+// awkwardWidths are image widths that do not fill their last byte of each row.
+var awkwardWidths = []int{1, 7, 9, 17, 100}
+
+func TestImageAwkwardWidths(t *testing.T) {
+	for _, w := range awkwardWidths {
+		img := NewImage(image.Rect(0, 0, w, 3))
+		wb := img.rectWidthBytes
+		img.SetColorIndex(w-1, 0, 2)
+		img.SetColorIndex(w-1, 2, 1)
+
+		for y := 0; y < 3; y++ {
+			for x := 0; x < w; x++ {
+				want := White
+				switch {
+				case x == w-1 && y == 0:
+					want = Highlight
+				case x == w-1 && y == 2:
+					want = Black
+				}
+				if got := img.At(x, y); got != want {
+					t.Errorf("width %d: At(%d, %d) = %v, wanted %v", w, x, y, got, want)
+				}
+			}
+		}
+		// The right edge of row 0 must not bleed into the first byte of row 1, which is white.
+		if img.Black[wb] != 0xff || img.Highlight[wb] != 0 {
+			t.Errorf("width %d: row 1 starts with black %08b, highlight %08b, wanted %08b, %08b", w, img.Black[wb], img.Highlight[wb], 0xff, 0)
+		}
+		if got := img.At(w, 0); got != White {
+			t.Errorf("width %d: At(%d, 0) = %v outside of the image, wanted %v", w, w, got, White)
+		}
+	}
+}
+
+func TestDrawAwkwardWidths(t *testing.T) {
+	palettes := []color.Palette{
+		{White, Black, Highlight},
+		{color.Black, color.White, color.RGBA{0xff, 0x20, 0x20, 0xff}},
+		{color.White, color.Black},
+	}
+	for _, w := range awkwardWidths {
+		r := image.Rect(0, 0, w, 3)
+		for _, p := range palettes {
+			src := image.NewPaletted(r, p)
+			// Fill only the right-most column with each color in turn.
+			for y := 0; y < 3; y++ {
+				src.SetColorIndex(w-1, y, uint8(y%len(p)))
+			}
+			got, want := NewImage(r), NewImage(r)
+			drawImage(got, src)
+			for y := 0; y < 3; y++ {
+				for x := 0; x < w; x++ {
+					want.Set(x, y, src.At(x, y))
+				}
+			}
+			if _, n := DiffImage(got, want); n != 0 {
+				t.Errorf("width %d: drawImage() with palette %v differs from Set() in %d pixels", w, p, n)
+			}
+			if !bytes.Equal(got.Black, want.Black) || !bytes.Equal(got.Highlight, want.Highlight) {
+				t.Errorf("width %d: drawImage() with palette %v wrote planes %x, %x, wanted %x, %x", w, p, got.Black, got.Highlight, want.Black, want.Highlight)
+			}
+		}
+	}
+}
+
+func TestParseColor(t *testing.T) {
+	cases := []struct {
+		in      string
+		want    Color
+		wantErr bool
+	}{
+		{in: "white", want: White},
+		{in: "black", want: Black},
+		{in: "highlight", want: Highlight},
+		{in: "Red", want: Highlight},
+		{in: "yellow", wantErr: true},
+		{in: "", wantErr: true},
+	}
+	for _, c := range cases {
+		got, err := ParseColor(c.in)
+		if (err != nil) != c.wantErr {
+			t.Errorf("ParseColor(%q) = _, %v, wanted error: %v", c.in, err, c.wantErr)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("ParseColor(%q) = %v, wanted %v", c.in, got, c.want)
+		}
+	}
+	if !White.IsWhite() || White.IsBlack() || White.IsHighlight() {
+		t.Errorf("White predicates are wrong")
+	}
+	if !Black.IsBlack() || !Highlight.IsHighlight() {
+		t.Errorf("Black or Highlight predicates are wrong")
+	}
+}
+
+func TestParseHighlight(t *testing.T) {
+	for s, want := range map[string]color.Color{"red": HighlightColors["red"], "Yellow": HighlightColors["yellow"], "BLUE": HighlightColors["blue"]} {
+		if got, err := ParseHighlight(s); got != want || err != nil {
+			t.Errorf("ParseHighlight(%q) = %v, %v, wanted %v, nil", s, got, err, want)
+		}
+	}
+	if _, err := ParseHighlight("green"); err == nil {
+		t.Errorf("ParseHighlight(%q) = _, nil, wanted error", "green")
+	}
+}
+
+func TestParsePanelColor(t *testing.T) {
+	yellow := HighlightColors["yellow"]
+	cases := []struct {
+		in        string
+		highlight color.Color
+		want      color.Color
+		wantErr   bool
+	}{
+		{in: "white", highlight: yellow, want: White},
+		{in: "black", highlight: yellow, want: Black},
+		{in: "highlight", highlight: yellow, want: yellow},
+		{in: "red", highlight: yellow, want: yellow},
+		{in: "highlight", want: Highlight},
+		{in: "yellow", highlight: yellow, wantErr: true},
+	}
+	for _, c := range cases {
+		got, err := ParsePanelColor(c.in, c.highlight)
+		if (err != nil) != c.wantErr {
+			t.Errorf("ParsePanelColor(%q, %v) = _, %v, wanted error: %v", c.in, c.highlight, err, c.wantErr)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("ParsePanelColor(%q, %v) = %v, wanted %v", c.in, c.highlight, got, c.want)
+		}
+	}
+}
+
+func TestHighlightColor(t *testing.T) {
+	yellow := HighlightColors["yellow"]
+	palette := color.Palette{color.White, color.Black, yellow}
+	img := image.NewPaletted(DisplayBounds, palette)
+	for i := range palette {
+		img.SetColorIndex(i, 0, uint8(i))
+	}
+	want := []Color{White, Black, Highlight}
+
+	d := &Display{buffer: NewImage(DisplayBounds), HighlightColor: yellow, Background: yellow}
+	d.Draw(img)
+	for i, w := range want {
+		if got := d.buffer.At(i, 0); got != w {
+			t.Errorf("At(%d, 0) = %v for %v, wanted %v", i, got, palette[i], w)
+		}
+	}
+
+	// Colors set directly on the buffer are matched against the panel too.
+	d.DrawFunc(func(dst draw.Image) {
+		dst.Set(0, 1, yellow)
+	})
+	if got := d.buffer.At(0, 1); got != Highlight {
+		t.Errorf("DrawFunc: At(0, 1) = %v, wanted %v", got, Highlight)
+	}
+
+	// Background is matched against the panel's colors.
+	if bg, err := d.background(); err != nil || bg != Highlight {
+		t.Errorf("background() = %v, %v, wanted %v, nil", bg, err, Highlight)
+	}
+}
+
+func TestToPaletted(t *testing.T) {
+	r := image.Rect(3, 5, 40, 17)
+	src := image.NewPaletted(r, defaultPalette)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			src.SetColorIndex(x, y, uint8((x*7+y)%3))
+		}
+	}
+	img := NewImage(r)
+	drawImage(img, src)
+
+	got := img.ToPaletted()
+	if got.Rect != r {
+		t.Fatalf("ToPaletted().Rect = %v, wanted %v", got.Rect, r)
+	}
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			if g, w := got.ColorIndexAt(x, y), src.ColorIndexAt(x, y); g != w {
+				t.Errorf("ToPaletted().ColorIndexAt(%d, %d) = %d, wanted %d", x, y, g, w)
+			}
+		}
+	}
+}
+
+func TestConvert(t *testing.T) {
+	img := image.NewPaletted(image.Rect(0, 0, 16, 2), defaultPalette)
+	img.SetColorIndex(0, 0, 1)
+	img.SetColorIndex(9, 1, 2)
+
+	black, red := Convert(img)
+	wantBlack := []byte{0x7f, 0xff, 0xff, 0xff}
+	wantRed := []byte{0x00, 0x00, 0x00, 0x40}
+	if !bytes.Equal(black, wantBlack) || !bytes.Equal(red, wantRed) {
+		t.Errorf("Convert() = % x, % x, wanted % x, % x", black, red, wantBlack, wantRed)
+	}
+
+	var bbuf, rbuf bytes.Buffer
+	Encode(&bbuf, &rbuf, img)
+	if !bytes.Equal(bbuf.Bytes(), black) || !bytes.Equal(rbuf.Bytes(), red) {
+		t.Errorf("Encode() = % x, % x, wanted the same as Convert() % x, % x", bbuf.Bytes(), rbuf.Bytes(), black, red)
+	}
+}
+
+func TestEncodeStream(t *testing.T) {
+	rgba := image.NewRGBA(image.Rect(3, 5, 100, 40))
+	for y := rgba.Rect.Min.Y; y < rgba.Rect.Max.Y; y++ {
+		for x := rgba.Rect.Min.X; x < rgba.Rect.Max.X; x++ {
+			rgba.Set(x, y, color.RGBA{uint8(x * 7), uint8(y * 3), uint8(x * y), 0xff})
+		}
+	}
+	native := image.NewPaletted(image.Rect(0, 0, 17, 4), defaultPalette)
+	exact := image.NewPaletted(image.Rect(0, 0, 33, 3), color.Palette{color.Black, color.White, color.RGBA{0xff, 0x20, 0x20, 0xff}})
+	twoColor := image.NewPaletted(image.Rect(8, 0, 30, 3), color.Palette{color.White, color.Black})
+	for _, p := range []*image.Paletted{native, exact, twoColor} {
+		for i := range p.Pix {
+			p.Pix[i] = uint8(i % len(p.Palette))
+		}
+	}
+	// A larger palette using 3 of its colors, but only white and gray in its first row, which
+	// must be matched as they are in the whole image.
+	large := image.NewPaletted(image.Rect(0, 0, 16, 2), color.Palette{color.White, color.Black, color.Gray{0x80}, color.RGBA{0, 0, 0xff, 0xff}})
+	for x := 0; x < 16; x++ {
+		large.SetColorIndex(x, 0, uint8(x%2*2))
+		large.SetColorIndex(x, 1, 1)
+	}
+
+	for _, img := range []image.Image{rgba, native, exact, twoColor, large, image.NewRGBA(image.Rect(0, 0, 0, 0))} {
+		var wantBlack, wantRed, gotBlack, gotRed bytes.Buffer
+		Encode(&wantBlack, &wantRed, img)
+		if err := EncodeStream(&gotBlack, &gotRed, img); err != nil {
+			t.Fatalf("EncodeStream() of %T %v = %v, wanted no error", img, img.Bounds(), err)
+		}
+		if !bytes.Equal(gotBlack.Bytes(), wantBlack.Bytes()) || !bytes.Equal(gotRed.Bytes(), wantRed.Bytes()) {
+			t.Errorf("EncodeStream() of %T %v = % x, % x, wanted the same as Encode() % x, % x", img, img.Bounds(), gotBlack.Bytes(), gotRed.Bytes(), wantBlack.Bytes(), wantRed.Bytes())
+		}
+	}
+}
+
+func TestEncodeSize(t *testing.T) {
+	cases := []struct {
+		r    image.Rectangle
+		want int
+	}{
+		{r: DisplayBounds, want: BufSize},
+		{r: image.Rect(0, 0, 100, 10), want: 13 * 10},
+		{r: image.Rect(3, 5, 11, 6), want: 1},
+	}
+	for _, c := range cases {
+		img := image.NewRGBA(c.r)
+		var black, red bytes.Buffer
+		Encode(&black, &red, img)
+		if black.Len() != c.want || red.Len() != c.want {
+			t.Errorf("Encode() of %v wrote %d, %d bytes, wanted %d", c.r, black.Len(), red.Len(), c.want)
+		}
+		black.Reset()
+		red.Reset()
+		if err := EncodeStream(&black, &red, img); err != nil {
+			t.Fatalf("EncodeStream() = %v, wanted no error", err)
+		}
+		if black.Len() != c.want || red.Len() != c.want {
+			t.Errorf("EncodeStream() of %v wrote %d, %d bytes, wanted %d", c.r, black.Len(), red.Len(), c.want)
+		}
+	}
+}
+
+func TestEncodeInvert(t *testing.T) {
+	// A row of black, highlight, and six white pixels, over 2 rows of 9 pixels so that each row
+	// has padding.
+	img := NewImage(image.Rect(0, 0, 9, 2))
+	img.SetColorIndex(0, 0, 1)
+	img.SetColorIndex(1, 0, 2)
+	cases := []struct {
+		desc               string
+		opts               []EncodeOption
+		wantBlack, wantRed []byte
+	}{
+		{
+			desc:      "none",
+			wantBlack: []byte{0x7f, 0xff, 0xff, 0xff},
+			wantRed:   []byte{0x40, 0x00, 0x00, 0x00},
+		},
+		{
+			desc:      "black",
+			opts:      []EncodeOption{InvertBlack()},
+			wantBlack: []byte{0x80, 0x00, 0x00, 0x00},
+			wantRed:   []byte{0x40, 0x00, 0x00, 0x00},
+		},
+		{
+			desc:      "highlight",
+			opts:      []EncodeOption{InvertHighlight()},
+			wantBlack: []byte{0x7f, 0xff, 0xff, 0xff},
+			wantRed:   []byte{0xbf, 0xff, 0xff, 0xff},
+		},
+		{
+			desc:      "both",
+			opts:      []EncodeOption{InvertBlack(), InvertHighlight()},
+			wantBlack: []byte{0x80, 0x00, 0x00, 0x00},
+			wantRed:   []byte{0xbf, 0xff, 0xff, 0xff},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			var black, red bytes.Buffer
+			Encode(&black, &red, img, c.opts...)
+			if !bytes.Equal(black.Bytes(), c.wantBlack) || !bytes.Equal(red.Bytes(), c.wantRed) {
+				t.Errorf("Encode() = % x, % x, wanted % x, % x", black.Bytes(), red.Bytes(), c.wantBlack, c.wantRed)
+			}
+			black.Reset()
+			red.Reset()
+			if err := EncodeStream(&black, &red, img, c.opts...); err != nil {
+				t.Fatalf("EncodeStream() = %v, wanted no error", err)
+			}
+			if !bytes.Equal(black.Bytes(), c.wantBlack) || !bytes.Equal(red.Bytes(), c.wantRed) {
+				t.Errorf("EncodeStream() = % x, % x, wanted % x, % x", black.Bytes(), red.Bytes(), c.wantBlack, c.wantRed)
+			}
+		})
+	}
+}
+
+func TestConvertOffset(t *testing.T) {
+	img := image.NewRGBA(image.Rect(8, 2, 16, 3))
+	draw.Draw(img, img.Rect, image.White, image.Point{}, draw.Src)
+	img.Set(8, 2, color.Black)
+	black, _ := Convert(img)
+	if want := []byte{0x7f}; !bytes.Equal(black, want) {
+		t.Errorf("Convert() of %v = % x, _, wanted % x", img.Rect, black, want)
+	}
+}
+
+func TestConvertTransparent(t *testing.T) {
+	// A fully transparent image is white, not the black of its premultiplied color.
+	img := image.NewNRGBA(image.Rect(0, 0, 8, 1))
+	img.Set(0, 0, color.NRGBA{0, 0, 0, 0xff})
+	black, red := Convert(img)
+	if want := []byte{0x7f}; !bytes.Equal(black, want) {
+		t.Errorf("Convert() = % x, _, wanted % x", black, want)
+	}
+	if want := []byte{0x00}; !bytes.Equal(red, want) {
+		t.Errorf("Convert() = _, % x, wanted % x", red, want)
+	}
+}
+
+func TestEmptyImage(t *testing.T) {
+	rects := []image.Rectangle{
+		image.Rect(0, 0, 0, 0),
+		image.Rect(0, 0, 10, 0),
+		image.Rect(0, 0, 0, 10),
+		{Min: image.Pt(10, 10), Max: image.Pt(0, 0)},
+		{Min: image.Pt(5, 5), Max: image.Pt(0, 0)},
+		{Min: image.Pt(10, 0), Max: image.Pt(0, 20)},
+	}
+	for _, r := range rects {
+		img := NewImage(r)
+		if len(img.Black) != 0 || len(img.Highlight) != 0 {
+			t.Errorf("NewImage(%v) has planes of %d, %d bytes, wanted 0", r, len(img.Black), len(img.Highlight))
+		}
+		img.Set(0, 0, color.Black)
+		img.SetColorIndex(1, 1, 2)
+		if got := img.At(0, 0); got != White {
+			t.Errorf("NewImage(%v).At(0, 0) = %v, wanted %v", r, got, White)
+		}
+
+		var black, red bytes.Buffer
+		Encode(&black, &red, img)
+		if black.Len() != 0 || red.Len() != 0 {
+			t.Errorf("Encode() of %v wrote %d, %d bytes, wanted 0", r, black.Len(), red.Len())
+		}
+		if err := EncodeStream(&black, &red, img); err != nil || black.Len() != 0 || red.Len() != 0 {
+			t.Errorf("EncodeStream() of %v wrote %d, %d bytes and returned %v, wanted nothing", r, black.Len(), red.Len(), err)
+		}
+
+		d := &Display{buffer: NewImage(DisplayBounds), Background: Black}
+		d.Draw(img)
+		if h := Histogram(d.buffer); h[Black.C] != DisplayWidth*DisplayHeight {
+			t.Errorf("Draw() of %v left colors %v, wanted all background", r, h)
+		}
+	}
+}
+
+// sparsePalette returns a 16 color image using only the colors at used, in turn.
+func sparsePalette(r image.Rectangle, used ...uint8) *image.Paletted {
+	p := make(color.Palette, 16)
+	for i := range p {
+		p[i] = color.RGBA{uint8(i * 16), 0x80, uint8(255 - i*16), 0xff}
+	}
+	p[5], p[9], p[12] = color.White, color.Black, color.RGBA{0xff, 0, 0, 0xff}
+	img := image.NewPaletted(r, p)
+	for i := range img.Pix {
+		img.Pix[i] = used[i%len(used)]
+	}
+	return img
+}
+
+func TestDrawUsedColors(t *testing.T) {
+	r := image.Rect(0, 0, 17, 3)
+	cases := []struct {
+		used []uint8
+		fast bool
+	}{
+		{used: []uint8{5, 9, 12}, fast: true},
+		{used: []uint8{12, 5}, fast: true},
+		{used: []uint8{9}, fast: true},
+		{used: []uint8{5, 9, 12, 0}, fast: false},
+	}
+	for _, c := range cases {
+		src := sparsePalette(r, c.used...)
+		got, want := NewImage(r), NewImage(r)
+		if fast := drawUsedColors(got, src, defaultConverter); fast != c.fast {
+			t.Errorf("drawUsedColors() using %v = %t, wanted %t", c.used, fast, c.fast)
+		}
+		drawImage(got, src)
+		draw.Draw(want, r, src, image.Point{}, draw.Src)
+		if _, n := DiffImage(got, want); n != 0 {
+			t.Errorf("drawImage() using colors %v differs from draw.Draw() in %d pixels", c.used, n)
+		}
+	}
+}
+
+func BenchmarkDrawSparsePalette(b *testing.B) {
+	src := sparsePalette(DisplayBounds, 5, 9, 12)
+	img := NewImage(DisplayBounds)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		drawImage(img, src)
+	}
+}
+
+func TestImageEach(t *testing.T) {
+	// An offset image with a width that is not a multiple of 8.
+	img := NewImage(image.Rect(3, 2, 16, 7))
+	for y := img.Rect.Min.Y; y < img.Rect.Max.Y; y++ {
+		for x := img.Rect.Min.X; x < img.Rect.Max.X; x++ {
+			img.SetColorIndex(x, y, uint8((x*7+y)%3))
+		}
+	}
+
+	var visited int
+	img.Each(func(x, y int, idx uint8) {
+		visited++
+		if want := img.ColorIndexAt(x, y); idx != want {
+			t.Errorf("Each() gave (%d, %d) index %d, wanted %d", x, y, idx, want)
+		}
+	})
+	if want := img.Rect.Dx() * img.Rect.Dy(); visited != want {
+		t.Errorf("Each() visited %d pixels, wanted %d", visited, want)
+	}
+
+	// Swap black and white, and leave highlight as it is by returning an invalid index.
+	want := NewImage(img.Rect)
+	img.Each(func(x, y int, idx uint8) {
+		want.SetColorIndex(x, y, [3]uint8{1, 0, 2}[idx])
+	})
+	img.EachSet(func(x, y int, idx uint8) uint8 {
+		switch idx {
+		case 0:
+			return 1
+		case 1:
+			return 0
+		}
+		return 255
+	})
+	if _, n := DiffImage(img, want); n != 0 {
+		t.Errorf("EachSet() differs from SetColorIndex() in %d pixels", n)
+	}
+
+	if n := testing.AllocsPerRun(10, func() { img.Each(func(x, y int, idx uint8) {}) }); n != 0 {
+		t.Errorf("Each() allocated %v times, wanted 0", n)
+	}
+}
+
+func TestImageResetInPlace(t *testing.T) {
+	img := NewImage(DisplayBounds)
+	img.fill(Highlight)
+	black, highlight := &img.Black[0], &img.Highlight[0]
+	if n := testing.AllocsPerRun(10, img.Reset); n != 0 {
+		t.Errorf("Reset() allocated %v times, wanted 0", n)
+	}
+	if &img.Black[0] != black || &img.Highlight[0] != highlight {
+		t.Errorf("Reset() replaced the planes, wanted them reused")
+	}
+	if h := Histogram(img); h[0] != DisplayWidth*DisplayHeight {
+		t.Errorf("Reset() left %v white, black, and highlight pixels, wanted all white", h)
+	}
+}
+
+func BenchmarkImageReset(b *testing.B) {
+	img := NewImage(DisplayBounds)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		img.Reset()
+	}
+}
@@ -0,0 +1,72 @@
+package epd7in5bhd
+
+import (
+	"fmt"
+	"log"
+	"sync"
+)
+
+// Logger receives a Display's diagnostic output, such as timings and bus errors. *log.Logger
+// implements it.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// WithLogger sends the Display's diagnostic output to l instead of the standard logger.
+func WithLogger(l Logger) Option {
+	return func(o *options) {
+		o.logger = l
+	}
+}
+
+// logf logs to the Display's Logger, or the standard logger if it has none.
+func (d *Display) logf(format string, v ...interface{}) {
+	if d.logger == nil {
+		log.Printf(format, v...)
+		return
+	}
+	d.logger.Printf(format, v...)
+}
+
+// RingLogger is a Logger that keeps only the most recent lines in memory, such as for serving
+// over HTTP on a device without storage to spare. It is safe for concurrent use.
+type RingLogger struct {
+	mu    sync.Mutex
+	lines []string
+	// next is the index in lines that the next line is written to.
+	next int
+	full bool
+}
+
+// NewRingLogger returns a RingLogger that keeps the last n lines, or only the last line if n is
+// less than 1.
+func NewRingLogger(n int) *RingLogger {
+	if n < 1 {
+		n = 1
+	}
+	return &RingLogger{lines: make([]string, n)}
+}
+
+// Printf records a line, formatted as with fmt.Sprintf, replacing the oldest if the buffer is
+// full.
+func (r *RingLogger) Printf(format string, v ...interface{}) {
+	line := fmt.Sprintf(format, v...)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lines[r.next] = line
+	r.next++
+	if r.next == len(r.lines) {
+		r.next = 0
+		r.full = true
+	}
+}
+
+// Lines returns a copy of the recorded lines, oldest first.
+func (r *RingLogger) Lines() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.full {
+		return append([]string(nil), r.lines[:r.next]...)
+	}
+	return append(append(make([]string, 0, len(r.lines)), r.lines[r.next:]...), r.lines[:r.next]...)
+}
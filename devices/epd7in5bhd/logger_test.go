@@ -0,0 +1,66 @@
+package epd7in5bhd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// fakeLogger records each line logged to it.
+type fakeLogger struct {
+	lines []string
+}
+
+func (l *fakeLogger) Printf(format string, v ...interface{}) {
+	l.lines = append(l.lines, fmt.Sprintf(format, v...))
+}
+
+func TestRawCommand(t *testing.T) {
+	hw, bus := newFakeHardware()
+	o := options{}
+	l := &fakeLogger{}
+	WithLogger(l)(&o)
+	d := &Display{hw: hw, buffer: NewImage(DisplayBounds), logger: o.logger}
+
+	if err := d.RawCommand(0x3C, 0x05); err != nil {
+		t.Fatalf("RawCommand(0x3C, 0x05) = %v, wanted no error", err)
+	}
+	got := bus.commands()
+	if len(got) != 1 || got[0].cmd != borderWaveformControl || !bytes.Equal(got[0].data, []byte{0x05}) {
+		t.Errorf("RawCommand(0x3C, 0x05) sent %v, wanted %v 05", got, borderWaveformControl)
+	}
+	if len(l.lines) != 1 || !strings.Contains(l.lines[0], "RawCommand(0x3c, 05)") {
+		t.Errorf("RawCommand() logged %q, wanted one line for the command", l.lines)
+	}
+
+	bus.Err = errors.New("bus unplugged")
+	if err := d.RawCommand(0x3C, 0x05); !errors.Is(err, bus.Err) {
+		t.Errorf("RawCommand() = %v, wanted %v", err, bus.Err)
+	}
+}
+
+func TestRingLogger(t *testing.T) {
+	r := NewRingLogger(3)
+	if got := r.Lines(); len(got) != 0 {
+		t.Errorf("Lines() = %q, wanted none", got)
+	}
+	var want []string
+	for i := 0; i < 5; i++ {
+		r.Printf("line %d", i)
+		want = append(want, fmt.Sprintf("line %d", i))
+		if len(want) > 3 {
+			want = want[1:]
+		}
+		if got := r.Lines(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+			t.Errorf("after %d lines, Lines() = %q, wanted %q", i+1, got, want)
+		}
+	}
+
+	d := &Display{logger: r}
+	d.logf("Refresh took %v", "1s")
+	if got := r.Lines(); got[len(got)-1] != "Refresh took 1s" {
+		t.Errorf("Lines() = %q, wanted the Display's line last", got)
+	}
+}
@@ -0,0 +1,75 @@
+package epd7in5bhd
+
+import (
+	"image"
+	"image/color"
+)
+
+// CombineMasks returns a paletted image with the palette {White, Black, Highlight} built from
+// a mask for each of the display's colors, which Draw copies without matching colors.
+//
+// A mask pixel is set where it is darker than mid-gray, so black on white masks work as
+// expected. Where both masks are set, black wins. The result covers the union of the masks'
+// bounds, and is white wherever neither mask is set. Either mask may be nil.
+func CombineMasks(black, red *image.Gray) *image.Paletted {
+	var r image.Rectangle
+	for _, m := range []*image.Gray{black, red} {
+		if m != nil {
+			r = r.Union(m.Rect)
+		}
+	}
+	dst := image.NewPaletted(r, color.Palette{White, Black, Highlight})
+	// Red first, so that black is drawn over it.
+	for _, m := range []struct {
+		mask *image.Gray
+		idx  uint8
+	}{{red, 2}, {black, 1}} {
+		if m.mask == nil {
+			continue
+		}
+		mr := m.mask.Rect
+		for y := mr.Min.Y; y < mr.Max.Y; y++ {
+			row := m.mask.Pix[m.mask.PixOffset(mr.Min.X, y):]
+			for i, v := range row[:mr.Dx()] {
+				if isSet(v) {
+					dst.Pix[dst.PixOffset(mr.Min.X+i, y)] = m.idx
+				}
+			}
+		}
+	}
+	return dst
+}
+
+// PackBlack packs mask into a black plane, in which a pixel set in mask is 0 and any other
+// pixel is 1. Pixels are set as they are by CombineMasks. The plane has a bit per pixel with
+// each row padded to a whole byte, as Encode writes it.
+func PackBlack(mask *image.Gray) []byte {
+	return packMask(mask, 1).Black
+}
+
+// PackHighlight packs mask into a highlight plane, in which a pixel set in mask is 1 and any
+// other pixel is 0. This is the opposite polarity to PackBlack, as the panel expects. The
+// plane is laid out as PackBlack's is.
+func PackHighlight(mask *image.Gray) []byte {
+	return packMask(mask, 2).Highlight
+}
+
+// packMask returns an Image with the color index idx wherever mask is set.
+func packMask(mask *image.Gray, idx uint8) *Image {
+	img := NewImage(mask.Rect)
+	for y := mask.Rect.Min.Y; y < mask.Rect.Max.Y; y++ {
+		row := mask.Pix[mask.PixOffset(mask.Rect.Min.X, y):]
+		for i, v := range row[:mask.Rect.Dx()] {
+			if isSet(v) {
+				img.SetColorIndex(mask.Rect.Min.X+i, y, idx)
+			}
+		}
+	}
+	return img
+}
+
+// isSet reports whether a mask pixel of gray level v is set, which is when it is darker than
+// mid-gray.
+func isSet(v uint8) bool {
+	return v < 0x80
+}
@@ -0,0 +1,104 @@
+package epd7in5bhd
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestCombineMasks(t *testing.T) {
+	black := image.NewGray(image.Rect(0, 0, 4, 1))
+	red := image.NewGray(image.Rect(2, 0, 6, 2))
+	for _, m := range []*image.Gray{black, red} {
+		for i := range m.Pix {
+			m.Pix[i] = 0xff
+		}
+	}
+	black.SetGray(1, 0, color.Gray{0x00})
+	black.SetGray(2, 0, color.Gray{0x7f})
+	black.SetGray(3, 0, color.Gray{0x80})
+	red.SetGray(2, 0, color.Gray{0x00})
+	red.SetGray(3, 0, color.Gray{0x00})
+	red.SetGray(5, 1, color.Gray{0x00})
+
+	got := CombineMasks(black, red)
+	if want := image.Rect(0, 0, 6, 2); got.Rect != want {
+		t.Fatalf("CombineMasks() bounds = %v, wanted %v", got.Rect, want)
+	}
+	want := [][]Color{
+		{White, Black, Black, Highlight, White, White},
+		{White, White, White, White, White, Highlight},
+	}
+	for y, row := range want {
+		for x, c := range row {
+			if got := got.At(x, y); got != c {
+				t.Errorf("CombineMasks().At(%d, %d) = %v, wanted %v", x, y, got, c)
+			}
+		}
+	}
+
+	// The result takes the native fast path when drawn.
+	img := NewImage(got.Rect)
+	drawImage(img, got)
+	if c := img.At(3, 0); c != Highlight {
+		t.Errorf("drawImage(CombineMasks()).At(3, 0) = %v, wanted %v", c, Highlight)
+	}
+}
+
+func TestCombineMasksNil(t *testing.T) {
+	red := image.NewGray(image.Rect(0, 0, 2, 1))
+	got := CombineMasks(nil, red)
+	if got.At(0, 0) != Highlight || got.At(1, 0) != Highlight {
+		t.Errorf("CombineMasks(nil, red) = %v, wanted all highlight", got.Pix)
+	}
+	if got := CombineMasks(nil, nil); !got.Rect.Empty() {
+		t.Errorf("CombineMasks(nil, nil) bounds = %v, wanted empty", got.Rect)
+	}
+}
+
+func TestPackMasks(t *testing.T) {
+	r := image.Rect(0, 0, 16, 2)
+	full := image.NewGray(r)
+	empty := image.NewGray(r)
+	for i := range empty.Pix {
+		empty.Pix[i] = 0xff
+	}
+	cases := []struct {
+		desc string
+		pack func(*image.Gray) []byte
+		mask *image.Gray
+		want byte
+	}{
+		{desc: "PackBlack(full)", pack: PackBlack, mask: full, want: 0x00},
+		{desc: "PackBlack(empty)", pack: PackBlack, mask: empty, want: 0xff},
+		{desc: "PackHighlight(full)", pack: PackHighlight, mask: full, want: 0xff},
+		{desc: "PackHighlight(empty)", pack: PackHighlight, mask: empty, want: 0x00},
+	}
+	for _, c := range cases {
+		got := c.pack(c.mask)
+		if len(got) != 4 {
+			t.Errorf("%s = % x, wanted 4 bytes", c.desc, got)
+			continue
+		}
+		for _, b := range got {
+			if b != c.want {
+				t.Errorf("%s = % x, wanted all %#02x", c.desc, got, c.want)
+				break
+			}
+		}
+	}
+
+	// A single set pixel in the second row of an offset mask.
+	mask := image.NewGray(image.Rect(10, 10, 19, 12))
+	for i := range mask.Pix {
+		mask.Pix[i] = 0xff
+	}
+	mask.SetGray(18, 11, color.Gray{0})
+	if got, want := PackBlack(mask), []byte{0xff, 0xff, 0xff, 0x7f}; !bytes.Equal(got, want) {
+		t.Errorf("PackBlack() = % x, wanted % x", got, want)
+	}
+	if got, want := PackHighlight(mask), []byte{0x00, 0x00, 0x00, 0x80}; !bytes.Equal(got, want) {
+		t.Errorf("PackHighlight() = % x, wanted % x", got, want)
+	}
+}
@@ -0,0 +1,50 @@
+package epd7in5bhd
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Stats are counters for the life of a Display, as returned by Display.Stats. They only
+// increase, except for LastRefresh, so they can be exported as Prometheus counters and a gauge.
+type Stats struct {
+	// Refreshes is the number of refreshes the panel completed.
+	Refreshes uint64
+	// FailedWrites is the number of commands that could not be sent, after any reconnects.
+	FailedWrites uint64
+	// WatchdogTimeouts is the number of waits for the panel that were abandoned because of the
+	// limit set by WithWatchdog.
+	WatchdogTimeouts uint64
+	// LastRefresh is how long the most recent completed refresh took, from activation until the
+	// panel was idle.
+	LastRefresh time.Duration
+}
+
+// metrics holds the counters behind Stats. They are updated atomically, so that Stats does not
+// wait for a refresh in progress.
+type metrics struct {
+	refreshes        uint64
+	failedWrites     uint64
+	watchdogTimeouts uint64
+	lastRefresh      int64
+}
+
+// Stats returns the display's counters. Like Busy, it does not wait for other Display methods.
+//
+// The controller's temperature is not included: reading it needs the SPI data line to be
+// readable, which is not the case for all HATs.
+func (d *Display) Stats() Stats {
+	m := &d.metrics
+	return Stats{
+		Refreshes:        atomic.LoadUint64(&m.refreshes),
+		FailedWrites:     atomic.LoadUint64(&m.failedWrites),
+		WatchdogTimeouts: atomic.LoadUint64(&m.watchdogTimeouts),
+		LastRefresh:      time.Duration(atomic.LoadInt64(&m.lastRefresh)),
+	}
+}
+
+// refreshed records a completed refresh that took dur.
+func (m *metrics) refreshed(dur time.Duration) {
+	atomic.AddUint64(&m.refreshes, 1)
+	atomic.StoreInt64(&m.lastRefresh, int64(dur))
+}
@@ -0,0 +1,42 @@
+package epd7in5bhd
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"periph.io/x/periph/conn/gpio"
+)
+
+func TestStats(t *testing.T) {
+	hw, bus := newFakeHardware()
+	d := &Display{hw: hw, buffer: NewImage(DisplayBounds), SettleDelay: -1}
+	if got := d.Stats(); got != (Stats{}) {
+		t.Errorf("Stats() = %+v for a new Display, wanted zero", got)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := d.Refresh(); err != nil {
+			t.Fatalf("Refresh() = %v, wanted no error", err)
+		}
+	}
+	got := d.Stats()
+	if got.Refreshes != 2 || got.LastRefresh <= 0 {
+		t.Errorf("Stats() = %+v after 2 refreshes, wanted 2 Refreshes and a LastRefresh", got)
+	}
+
+	bus.Err = errors.New("bus gone")
+	d.RawCommand(byte(deepSleepMode), 0x01)
+	if got := d.Stats().FailedWrites; got != 1 {
+		t.Errorf("Stats().FailedWrites = %d after a failed write, wanted 1", got)
+	}
+	bus.Err = nil
+
+	d.watchdog = 10 * time.Millisecond
+	hw.Busy().Out(gpio.Low)
+	d.RefreshHighlightOnly()
+	got = d.Stats()
+	if got.WatchdogTimeouts != 1 || got.Refreshes != 2 {
+		t.Errorf("Stats() = %+v after a timed out refresh, wanted 1 WatchdogTimeouts and 2 Refreshes", got)
+	}
+}
@@ -0,0 +1,54 @@
+package epd7in5bhd
+
+import (
+	"fmt"
+)
+
+// otpSize is the number of bytes returned by otpRegisterRead.
+const otpSize = 11
+
+// OTPInfo is the display option data stored in the controller's one-time programmable memory,
+// as returned by Display.ReadOTP.
+//
+// The layout follows the SSD1677 datasheet. The waveform version is the most reliable way to
+// tell panel revisions apart, as the vendor programs it along with the panel's waveform.
+type OTPInfo struct {
+	// VCOMSelection selects whether VCOM is read from OTP or the VCOM register.
+	VCOMSelection byte
+	// VCOM is the VCOM register value programmed into OTP.
+	VCOM byte
+	// DisplayMode holds the programmed display mode options.
+	DisplayMode [5]byte
+	// WaveformVersion identifies the programmed waveform.
+	WaveformVersion [4]byte
+}
+
+// String returns the waveform version in hex, which identifies the panel revision.
+func (o OTPInfo) String() string {
+	return fmt.Sprintf("waveform %x, vcom %#02x", o.WaveformVersion[:], o.VCOM)
+}
+
+// DecodeOTP decodes the bytes returned by Display.ReadOTP.
+func DecodeOTP(b []byte) (OTPInfo, error) {
+	var o OTPInfo
+	if len(b) != otpSize {
+		return o, fmt.Errorf("got %d bytes of OTP data, wanted %d", len(b), otpSize)
+	}
+	o.VCOMSelection = b[0]
+	o.VCOM = b[1]
+	copy(o.DisplayMode[:], b[2:7])
+	copy(o.WaveformVersion[:], b[7:11])
+	return o, nil
+}
+
+// ReadOTP reads the display option bytes from the controller's OTP memory. They can be
+// decoded with DecodeOTP.
+//
+// Reading requires the panel's data line to be readable by the SPI controller, which is not
+// the case for all HATs, and the controller only supports reads at up to 2.5Mhz. If reads are
+// not wired up, ReadOTP typically returns all 0x00 or 0xFF bytes rather than an error.
+func (d *Display) ReadOTP() ([]byte, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.hw.Read(byte(otpRegisterRead), otpSize)
+}
@@ -0,0 +1,37 @@
+package epd7in5bhd
+
+import (
+	"bytes"
+	"testing"
+
+	"periph.io/x/periph/conn/gpio"
+)
+
+func TestReadOTP(t *testing.T) {
+	hw, bus := newFakeHardware()
+	bus.Rx = []byte{0x80, 0x3C, 1, 2, 3, 4, 5, 0xDE, 0xAD, 0xBE, 0xEF}
+	d := &Display{hw: hw}
+	b, err := d.ReadOTP()
+	if err != nil {
+		t.Fatalf("ReadOTP() = _, %v, wanted no error", err)
+	}
+	if len(bus.Txs) != 2 || bus.Txs[0].DC != gpio.Low || !bytes.Equal(bus.Txs[0].W, []byte{byte(otpRegisterRead)}) {
+		t.Errorf("ReadOTP() sent %+v, wanted command %s followed by a read", bus.Txs, otpRegisterRead)
+	}
+	o, err := DecodeOTP(b)
+	if err != nil {
+		t.Fatalf("DecodeOTP(%x) = _, %v, wanted no error", b, err)
+	}
+	want := OTPInfo{
+		VCOMSelection:   0x80,
+		VCOM:            0x3C,
+		DisplayMode:     [5]byte{1, 2, 3, 4, 5},
+		WaveformVersion: [4]byte{0xDE, 0xAD, 0xBE, 0xEF},
+	}
+	if o != want {
+		t.Errorf("DecodeOTP(%x) = %+v, wanted %+v", b, o, want)
+	}
+	if _, err := DecodeOTP(b[:4]); err == nil {
+		t.Errorf("DecodeOTP(%x) = _, nil, wanted error", b[:4])
+	}
+}
@@ -0,0 +1,42 @@
+package epd7in5bhd
+
+import (
+	"image"
+)
+
+// SetMask sets regions of the panel that are always shown as white, such as around stuck or
+// dead pixels, replacing any regions set before. Call it with no regions to remove the masks.
+//
+// The masks are applied as the buffer is uploaded by Refresh, RefreshRegion, and
+// RefreshHighlightOnly, so the buffer itself keeps what was drawn. Upload sends its planes as
+// given. Like RefreshRegion, the regions are in panel coordinates, after Mirror and
+// FlipVertical are applied.
+func (d *Display) SetMask(rs ...image.Rectangle) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.masks = nil
+	for _, r := range rs {
+		if r = r.Intersect(d.buffer.Rect); !r.Empty() {
+			d.masks = append(d.masks, r)
+		}
+	}
+}
+
+// planes returns the planes of the buffer to upload, with the masks applied to a copy. Without
+// masks, the buffer's own planes are returned.
+func (d *Display) planes() (black, highlight []byte) {
+	if len(d.masks) == 0 {
+		return d.buffer.Black, d.buffer.Highlight
+	}
+	img := NewImage(d.buffer.Rect)
+	copy(img.Black, d.buffer.Black)
+	copy(img.Highlight, d.buffer.Highlight)
+	for _, r := range d.masks {
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				img.SetColorIndex(x, y, 0)
+			}
+		}
+	}
+	return img.Black, img.Highlight
+}
@@ -0,0 +1,46 @@
+package epd7in5bhd
+
+import (
+	"image"
+	"testing"
+)
+
+func TestSetMask(t *testing.T) {
+	hw, bus := newFakeHardware()
+	d := &Display{hw: hw, buffer: NewImage(DisplayBounds)}
+	d.buffer.fill(Black)
+	d.SetMask(image.Rect(0, 0, 8, 1), image.Rect(DisplayWidth-4, DisplayHeight-1, DisplayWidth+10, DisplayHeight+10))
+	if err := d.Refresh(); err != nil {
+		t.Fatalf("Refresh() = %v, wanted no error", err)
+	}
+
+	var black []byte
+	for _, c := range bus.commands() {
+		if c.cmd == writeRAMBW {
+			black = c.data
+		}
+	}
+	if len(black) != BufSize {
+		t.Fatalf("Refresh() sent %d black bytes, wanted %d", len(black), BufSize)
+	}
+	want := map[int]byte{
+		0:           0xff,
+		1:           0x00,
+		BufSize - 2: 0x00,
+		// The second mask is clipped to the last 4 pixels of the display.
+		BufSize - 1: 0x0f,
+	}
+	for i, b := range want {
+		if black[i] != b {
+			t.Errorf("Refresh() sent black byte %d = %08b, wanted %08b", i, black[i], b)
+		}
+	}
+	if got := d.buffer.At(0, 0); got != Black {
+		t.Errorf("buffer.At(0, 0) = %v after a masked Refresh(), wanted %v kept in the buffer", got, Black)
+	}
+
+	d.SetMask()
+	if len(d.masks) != 0 {
+		t.Errorf("SetMask() kept %d masks, wanted none", len(d.masks))
+	}
+}
@@ -0,0 +1,79 @@
+package epd7in5bhd
+
+import (
+	"context"
+	"image"
+	"sync"
+)
+
+// queue holds the frame waiting to be shown by Enqueue's worker.
+type queue struct {
+	mu sync.Mutex
+	// next is the most recent frame that has not been drawn yet.
+	next image.Image
+	// done is closed when the running worker exits. It is nil when no worker is running.
+	done chan struct{}
+}
+
+// Enqueue hands img to a background worker that draws and refreshes it, and returns
+// immediately. It is the asynchronous counterpart to DrawAndRefresh.
+//
+// Frames are shown one at a time, and no more often than MinRefreshInterval allows. If frames
+// are enqueued faster than the panel can refresh, only the most recent one is shown. Refresh
+// errors are logged.
+//
+// The worker exits once there is nothing left to show. Use Drain to wait for it before
+// shutting down.
+func (d *Display) Enqueue(img image.Image) {
+	d.q.mu.Lock()
+	defer d.q.mu.Unlock()
+	d.q.next = img
+	if d.q.done == nil {
+		d.q.done = make(chan struct{})
+		go d.runQueue(d.q.done)
+	}
+}
+
+// Pending reports whether an enqueued frame has not finished refreshing.
+func (d *Display) Pending() bool {
+	d.q.mu.Lock()
+	defer d.q.mu.Unlock()
+	return d.q.done != nil
+}
+
+// Drain waits until every enqueued frame has been shown and the worker has exited. If ctx is
+// done first, Drain returns ctx.Err(), and the worker carries on in the background.
+func (d *Display) Drain(ctx context.Context) error {
+	d.q.mu.Lock()
+	done := d.q.done
+	d.q.mu.Unlock()
+	if done == nil {
+		return nil
+	}
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// runQueue shows the latest enqueued frame until there are none left, and then closes done.
+func (d *Display) runQueue(done chan struct{}) {
+	for {
+		d.q.mu.Lock()
+		img := d.q.next
+		d.q.next = nil
+		if img == nil {
+			d.q.done = nil
+			d.q.mu.Unlock()
+			close(done)
+			return
+		}
+		d.q.mu.Unlock()
+
+		if err := d.DrawAndRefresh(img); err != nil {
+			d.logf("DrawAndRefresh() = %v for enqueued frame", err)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package epd7in5bhd
+
+import (
+	"context"
+	"image"
+	"testing"
+	"time"
+)
+
+func TestEnqueue(t *testing.T) {
+	hw, _ := newFakeHardware()
+	d := &Display{hw: hw, buffer: NewImage(DisplayBounds), history: make([]*Image, 0, 5)}
+
+	// Hold the display so that frames pile up behind the first one.
+	d.mu.Lock()
+	var frames []*Image
+	for i := 0; i < 3; i++ {
+		frame := NewImage(DisplayBounds)
+		frame.Set(i, 0, Black)
+		frames = append(frames, frame)
+		d.Enqueue(frame)
+	}
+	if !d.Pending() {
+		t.Errorf("Pending() = false after Enqueue(), wanted true")
+	}
+	d.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := d.Drain(ctx); err != nil {
+		t.Fatalf("Drain() = %v, wanted no error", err)
+	}
+	if d.Pending() {
+		t.Errorf("Pending() = true after Drain(), wanted false")
+	}
+
+	history := d.History()
+	if len(history) == 0 || len(history) > 2 {
+		t.Fatalf("refreshed %d frames, wanted 1 or 2 since later frames coalesce", len(history))
+	}
+	if last := history[len(history)-1]; !last.Equal(frames[2]) {
+		t.Errorf("last refreshed frame is not the last enqueued frame")
+	}
+}
+
+func TestMinRefreshInterval(t *testing.T) {
+	hw, _ := newFakeHardware()
+	d := &Display{hw: hw, buffer: NewImage(DisplayBounds), MinRefreshInterval: 100 * time.Millisecond}
+
+	start := time.Now()
+	for i := 0; i < 2; i++ {
+		d.DrawAndRefresh(image.NewUniform(White))
+	}
+	if elapsed := time.Since(start); elapsed < d.MinRefreshInterval {
+		t.Errorf("2 refreshes took %v, wanted at least MinRefreshInterval %v", elapsed, d.MinRefreshInterval)
+	}
+}
@@ -0,0 +1,50 @@
+package epd7in5bhd
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// UploadRaw reads a plane from each of black and red, and shows them with Upload. Each must
+// hold exactly BufSize bytes, in the format of the buffers that the vendor's Python examples
+// pass to display, such as buffers dumped from them. Comparing such a dump with the output of
+// Encode with InvertHighlight shows whether a difference is in conversion or in driving the
+// panel.
+//
+// In both planes a 0 bit is a black or red pixel. The vendor's display inverts the red buffer
+// as it sends it, as the panel's RAM takes a 1 bit for red, so UploadRaw inverts the red plane
+// too.
+//
+// Both planes are read before anything is sent, and nothing is sent if either is the wrong
+// size.
+func (d *Display) UploadRaw(black, red io.Reader) error {
+	bp, err := readPlane("black", black)
+	if err != nil {
+		return err
+	}
+	rp, err := readPlane("red", red)
+	if err != nil {
+		return err
+	}
+	for i := range rp {
+		rp[i] = ^rp[i]
+	}
+	return d.Upload(bp, rp)
+}
+
+// readPlane reads exactly BufSize bytes from r, and returns an error naming the plane if r holds
+// more or less.
+func readPlane(name string, r io.Reader) ([]byte, error) {
+	b, err := ioutil.ReadAll(io.LimitReader(r, BufSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("reading %s plane: %w", name, err)
+	}
+	switch {
+	case len(b) > BufSize:
+		return nil, fmt.Errorf("%s plane is more than BufSize (%d) bytes", name, BufSize)
+	case len(b) < BufSize:
+		return nil, fmt.Errorf("%s plane is %d bytes, wanted BufSize (%d)", name, len(b), BufSize)
+	}
+	return b, nil
+}
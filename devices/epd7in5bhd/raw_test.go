@@ -0,0 +1,64 @@
+package epd7in5bhd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestUploadRaw(t *testing.T) {
+	full := bytes.Repeat([]byte{0xAA}, BufSize)
+	// A dump in the vendor's format, with the first pixel black and the second red. The rest
+	// is white.
+	vendorBlack := bytes.Repeat([]byte{0xFF}, BufSize)
+	vendorBlack[0] = 0x7F
+	vendorRed := bytes.Repeat([]byte{0xFF}, BufSize)
+	vendorRed[0] = 0xBF
+	wantRed := make([]byte, BufSize)
+	wantRed[0] = 0x40
+	cases := []struct {
+		desc       string
+		black, red []byte
+		// wantBlack and wantRed are the planes written to the panel's RAM.
+		wantBlack, wantRed []byte
+		wantErr            string
+	}{
+		{desc: "vendor dump", black: vendorBlack, red: vendorRed, wantBlack: vendorBlack, wantRed: wantRed},
+		{desc: "short black", black: full[:10], red: full, wantErr: "black plane is 10 bytes"},
+		{desc: "long red", black: full, red: append(full, 0), wantErr: "red plane is more than BufSize"},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			hw, bus := newFakeHardware()
+			d := &Display{hw: hw, buffer: NewImage(DisplayBounds)}
+
+			err := d.UploadRaw(bytes.NewReader(c.black), bytes.NewReader(c.red))
+			if c.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), c.wantErr) {
+					t.Errorf("UploadRaw() = %v, wanted error containing %q", err, c.wantErr)
+				}
+				if cmds := bus.commands(); len(cmds) != 0 {
+					t.Errorf("UploadRaw() sent %d commands, wanted none for a bad plane", len(cmds))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("UploadRaw() = %v, wanted nil", err)
+			}
+			var planes int
+			for _, cmd := range bus.commands() {
+				want := map[command][]byte{writeRAMBW: c.wantBlack, writeRAMRed: c.wantRed}[cmd.cmd]
+				if want == nil {
+					continue
+				}
+				planes++
+				if !bytes.Equal(cmd.data, want) {
+					t.Errorf("%v sent % x..., wanted % x...", cmd.cmd, cmd.data[:4], want[:4])
+				}
+			}
+			if planes != 2 {
+				t.Errorf("UploadRaw() wrote %d planes, wanted 2", planes)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package epd7in5bhd
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// reconnectPolicy bounds the automatic reconnects made when a write fails.
+type reconnectPolicy struct {
+	// attempts is the maximum number of reconnects for a single failed write. Zero disables
+	// automatic reconnects.
+	attempts int
+	// backoff is the wait before the first reconnect. It doubles for each further attempt.
+	backoff time.Duration
+}
+
+// WithAutoReconnect reconnects to the display when a write fails, such as after a USB or SPI
+// glitch leaves the bus unusable. Up to attempts reconnects are made for each failed write,
+// waiting backoff before the first and doubling the wait before each one after that. The
+// failed command is sent again after each successful reconnect.
+//
+// A refresh that is interrupted by a reconnect may show a corrupted frame; the next refresh
+// corrects it. Automatic reconnects are off by default.
+func WithAutoReconnect(attempts int, backoff time.Duration) Option {
+	return func(o *options) {
+		o.reconnect = reconnectPolicy{attempts: attempts, backoff: backoff}
+	}
+}
+
+// Reconnect closes the SPI port, and acquires the pins and port again. The transfer size and
+// chip select settings are kept.
+//
+// Reconnect does not reinitialize the panel. Call Init afterwards if the panel may have lost
+// power or state.
+func (d *Display) Reconnect() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.reopenHardware()
+}
+
+// reopenHardware replaces d.hw with newly acquired hardware. If that fails, d.hw is left
+// closed, and is not closed again by the next attempt.
+func (d *Display) reopenHardware() error {
+	if d.reopen == nil {
+		return errors.New("Reconnect() is only supported for a Display from New")
+	}
+	old := d.hw
+	// Release the port first, as it may not be opened twice.
+	if !d.hwClosed {
+		old.Close()
+		d.hwClosed = true
+	}
+	hw, err := d.reopen(context.Background())
+	if err != nil {
+		return err
+	}
+	if err := hw.SetTxLimit(old.TxLimit()); err != nil {
+		hw.Close()
+		return err
+	}
+	hw.SetHoldCS(old.HoldCS())
+	hw.SetTap(old.Tap())
+	d.hwMu.Lock()
+	d.hw = hw
+	d.hwMu.Unlock()
+	d.hwClosed = false
+	return nil
+}
@@ -0,0 +1,115 @@
+package epd7in5bhd
+
+import (
+	"context"
+	"errors"
+	"image"
+	"testing"
+
+	"github.com/toothrot/gink/devices/internal/driver"
+)
+
+func TestAutoReconnect(t *testing.T) {
+	broken, brokenBus := newFakeHardware()
+	brokenBus.Err = errors.New("spi: no such device")
+	good, goodBus := newFakeHardware()
+	opens := 0
+	d := &Display{
+		hw:        broken,
+		reconnect: reconnectPolicy{attempts: 2},
+		reopen: func(context.Context) (*driver.Hardware, error) {
+			opens++
+			return good, nil
+		},
+	}
+	d.sendCommand(deepSleepMode, 0x01)
+
+	if opens != 1 {
+		t.Errorf("sendCommand() reopened the hardware %d times, wanted 1", opens)
+	}
+	cmds := goodBus.commands()
+	if len(cmds) != 1 || cmds[0].cmd != deepSleepMode {
+		t.Errorf("sendCommand(%s) after reconnecting sent %v, wanted %s", deepSleepMode, cmds, deepSleepMode)
+	}
+}
+
+func TestAutoReconnectGivesUp(t *testing.T) {
+	hw, bus := newFakeHardware()
+	bus.Err = errors.New("spi: no such device")
+	opens := 0
+	d := &Display{
+		hw:        hw,
+		reconnect: reconnectPolicy{attempts: 3},
+		reopen: func(context.Context) (*driver.Hardware, error) {
+			opens++
+			return nil, errors.New("still unplugged")
+		},
+	}
+	if err := d.sendCommand(deepSleepMode, 0x01); !errors.Is(err, bus.Err) {
+		t.Errorf("sendCommand() = %v, wanted %v", err, bus.Err)
+	}
+	if opens != 3 {
+		t.Errorf("sendCommand() reopened the hardware %d times, wanted 3", opens)
+	}
+	if !d.hwClosed {
+		t.Errorf("hwClosed = false after failed reconnects, wanted true")
+	}
+}
+
+func TestUploadErrorAfterReconnects(t *testing.T) {
+	hw, bus := newFakeHardware()
+	bus.Err = errors.New("spi: no such device")
+	d := &Display{
+		hw:          hw,
+		buffer:      NewImage(DisplayBounds),
+		SettleDelay: -1,
+		reconnect:   reconnectPolicy{attempts: 1},
+		reopen: func(context.Context) (*driver.Hardware, error) {
+			return nil, errors.New("still unplugged")
+		},
+	}
+	for name, fn := range map[string]func() error{
+		"Refresh":              d.Refresh,
+		"RefreshHighlightOnly": d.RefreshHighlightOnly,
+		"RefreshRegion":        func() error { return d.RefreshRegion(image.Rect(0, 0, 8, 8)) },
+		"UploadBlack":          func() error { return d.UploadBlack(nil) },
+		"UploadHighlight":      func() error { return d.UploadHighlight(nil) },
+		"Trigger":              d.Trigger,
+	} {
+		if err := fn(); !errors.Is(err, bus.Err) {
+			t.Errorf("%s() = %v, wanted %v", name, err, bus.Err)
+		}
+	}
+}
+
+func TestReconnect(t *testing.T) {
+	old, _ := newFakeHardware()
+	if err := old.SetTxLimit(512); err != nil {
+		t.Fatalf("SetTxLimit(512) = %v", err)
+	}
+	old.SetHoldCS(true)
+	hw, _ := newFakeHardware()
+	d := &Display{
+		hw: old,
+		reopen: func(context.Context) (*driver.Hardware, error) {
+			return hw, nil
+		},
+	}
+	if err := d.Reconnect(); err != nil {
+		t.Fatalf("Reconnect() = %v, wanted no error", err)
+	}
+	if d.hw != hw {
+		t.Fatalf("Reconnect() did not replace the hardware")
+	}
+	if got := hw.TxLimit(); got != 512 {
+		t.Errorf("TxLimit() = %d after Reconnect(), wanted 512", got)
+	}
+	if !hw.HoldCS() {
+		t.Errorf("HoldCS() = false after Reconnect(), wanted true")
+	}
+
+	d = &Display{hw: old}
+	if err := d.Reconnect(); err == nil {
+		t.Errorf("Reconnect() = nil for a Display without pins, wanted error")
+	}
+}
@@ -0,0 +1,53 @@
+package epd7in5bhd
+
+import (
+	"fmt"
+)
+
+// RefreshMode selects the waveform the controller drives the panel with on each refresh.
+type RefreshMode int
+
+const (
+	// RefreshQuality is the panel's full waveform, display mode 1. It is the default.
+	RefreshQuality RefreshMode = iota
+	// RefreshFast uses the controller's display mode 2, which on many panels is a shorter
+	// waveform.
+	RefreshFast
+)
+
+func (m RefreshMode) String() string {
+	switch m {
+	case RefreshQuality:
+		return "RefreshQuality"
+	case RefreshFast:
+		return "RefreshFast"
+	}
+	return fmt.Sprintf("RefreshMode(%d)", int(m))
+}
+
+// updateControl returns the displayUpdateControl2 argument that refreshes the panel in m. Both
+// enable the clock and analog circuits, load the temperature and waveform, display, and then
+// disable them again; bit 3 selects display mode 2.
+func (m RefreshMode) updateControl() byte {
+	if m == RefreshFast {
+		return 0xCF
+	}
+	return 0xC7
+}
+
+// SetRefreshMode selects the waveform used by each refresh from then on.
+//
+// RefreshFast is an explicit opt-in. Its waveform is whatever the vendor programmed as display
+// mode 2, which differs between panel revisions: it is usually faster, but leaves more ghosting
+// of previous frames, and may render the highlight color weakly or not at all. Use
+// MeasureRefresh and a test frame to check it on a given panel, and refresh in RefreshQuality
+// periodically to clear any ghosting.
+func (d *Display) SetRefreshMode(m RefreshMode) error {
+	if m < RefreshQuality || m > RefreshFast {
+		return fmt.Errorf("invalid refresh mode %v", m)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.refreshMode = m
+	return nil
+}
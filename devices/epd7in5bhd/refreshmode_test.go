@@ -0,0 +1,36 @@
+package epd7in5bhd
+
+import (
+	"testing"
+)
+
+func TestSetRefreshMode(t *testing.T) {
+	cases := []struct {
+		mode RefreshMode
+		want byte
+	}{
+		{mode: RefreshQuality, want: 0xC7},
+		{mode: RefreshFast, want: 0xCF},
+	}
+	for _, c := range cases {
+		hw, bus := newFakeHardware()
+		d := &Display{hw: hw, buffer: NewImage(DisplayBounds)}
+		if err := d.SetRefreshMode(c.mode); err != nil {
+			t.Fatalf("SetRefreshMode(%v) = %v, wanted no error", c.mode, err)
+		}
+		d.Refresh()
+		if n := countCommands(bus.commands(), displayUpdateControl2, c.want); n != 1 {
+			t.Errorf("Refresh() in %v sent displayUpdateControl2 %#02x %d times, wanted 1", c.mode, c.want, n)
+		}
+	}
+
+	d := &Display{}
+	for _, m := range []RefreshMode{-1, RefreshFast + 1} {
+		if err := d.SetRefreshMode(m); err == nil {
+			t.Errorf("SetRefreshMode(%v) = nil, wanted error", m)
+		}
+	}
+	if d.refreshMode != RefreshQuality {
+		t.Errorf("refreshMode = %v after invalid modes, wanted %v", d.refreshMode, RefreshQuality)
+	}
+}
@@ -0,0 +1,93 @@
+package epd7in5bhd
+
+import (
+	"fmt"
+)
+
+// ScanDirection is the order in which the controller fills its RAM, set by its data entry mode.
+// Changing it mirrors or flips what is shown without transforming the image in software.
+type ScanDirection int
+
+const (
+	// ScanNormal fills rows top to bottom, each left to right.
+	ScanNormal ScanDirection = iota
+	// ScanMirror fills rows right to left, mirroring the display horizontally.
+	ScanMirror
+	// ScanFlip fills rows bottom to top, flipping the display vertically.
+	ScanFlip
+	// ScanRotate180 both mirrors and flips the display, rotating it by 180 degrees.
+	ScanRotate180
+)
+
+func (s ScanDirection) String() string {
+	switch s {
+	case ScanNormal:
+		return "ScanNormal"
+	case ScanMirror:
+		return "ScanMirror"
+	case ScanFlip:
+		return "ScanFlip"
+	case ScanRotate180:
+		return "ScanRotate180"
+	}
+	return fmt.Sprintf("ScanDirection(%d)", int(s))
+}
+
+// mirrored reports whether the X address counts down.
+func (s ScanDirection) mirrored() bool { return s == ScanMirror || s == ScanRotate180 }
+
+// flipped reports whether the Y address counts up, from the bottom row.
+func (s ScanDirection) flipped() bool { return s == ScanFlip || s == ScanRotate180 }
+
+// entryMode returns the dataEntryMode argument for s. Bit 0 increments X, and bit 1 increments
+// Y; X is always updated first.
+func (s ScanDirection) entryMode() byte {
+	var m byte
+	if !s.mirrored() {
+		m |= 0x01
+	}
+	if s.flipped() {
+		m |= 0x02
+	}
+	return m
+}
+
+// ramX returns the RAM X address of buffer column x.
+func (s ScanDirection) ramX(x int) int {
+	if s.mirrored() {
+		return DisplayWidth - 1 - x
+	}
+	return x
+}
+
+// ramY returns the RAM Y address of buffer row y. The rows of the display are at RAM addresses
+// ramYTop down to ramYTop-DisplayHeight+1.
+func (s ScanDirection) ramY(y int) int {
+	if s.flipped() {
+		return ramYTop - (DisplayHeight - 1) + y
+	}
+	return ramYTop - y
+}
+
+// SetScanDirection changes the order in which the controller fills its RAM, and so how the
+// buffer is oriented on the panel. The change applies from the next upload, and is kept by Init.
+//
+// It is cheaper than Mirror and FlipVertical, which transform each image as it is drawn, and
+// combines with them: an image drawn with Mirror on a display scanning with ScanMirror is shown
+// unmirrored. RefreshRegion rectangles remain in buffer coordinates.
+func (d *Display) SetScanDirection(s ScanDirection) error {
+	if s < ScanNormal || s > ScanRotate180 {
+		return fmt.Errorf("invalid scan direction %v", s)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.scan = s
+	d.sendScanDirection()
+	return nil
+}
+
+// sendScanDirection sets the data entry mode and full RAM window for d.scan.
+func (d *Display) sendScanDirection() {
+	d.sendCommand(dataEntryMode, d.scan.entryMode())
+	d.resetWindow()
+}
@@ -0,0 +1,87 @@
+package epd7in5bhd
+
+import (
+	"bytes"
+	"image"
+	"testing"
+)
+
+func TestSetScanDirection(t *testing.T) {
+	cases := []struct {
+		s    ScanDirection
+		want []fakeCommand
+	}{
+		{
+			s: ScanNormal,
+			want: []fakeCommand{
+				{cmd: dataEntryMode, data: []byte{0x01}},
+				{cmd: setRamXStart, data: []byte{0x00, 0x00, 0x6F, 0x03}},
+				{cmd: setRamYStart, data: []byte{0xAF, 0x02, 0x00, 0x00}},
+				{cmd: setRamXAddressCtr, data: []byte{0x00, 0x00}},
+				{cmd: setRamYAddressCtr, data: []byte{0xAF, 0x02}},
+			},
+		},
+		{
+			s: ScanMirror,
+			want: []fakeCommand{
+				{cmd: dataEntryMode, data: []byte{0x00}},
+				{cmd: setRamXStart, data: []byte{0x6F, 0x03, 0x00, 0x00}},
+				{cmd: setRamYStart, data: []byte{0xAF, 0x02, 0x00, 0x00}},
+				{cmd: setRamXAddressCtr, data: []byte{0x6F, 0x03}},
+				{cmd: setRamYAddressCtr, data: []byte{0xAF, 0x02}},
+			},
+		},
+		{
+			s: ScanRotate180,
+			want: []fakeCommand{
+				{cmd: dataEntryMode, data: []byte{0x02}},
+				{cmd: setRamXStart, data: []byte{0x6F, 0x03, 0x00, 0x00}},
+				{cmd: setRamYStart, data: []byte{0xA0, 0x00, 0xAF, 0x02}},
+				{cmd: setRamXAddressCtr, data: []byte{0x6F, 0x03}},
+				{cmd: setRamYAddressCtr, data: []byte{0xA0, 0x00}},
+			},
+		},
+	}
+	for _, c := range cases {
+		hw, bus := newFakeHardware()
+		d := &Display{hw: hw, buffer: NewImage(DisplayBounds)}
+		if err := d.SetScanDirection(c.s); err != nil {
+			t.Fatalf("SetScanDirection(%v) = %v, wanted no error", c.s, err)
+		}
+		checkCommands(t, c.s.String(), bus.commands(), c.want)
+	}
+
+	d := &Display{}
+	if err := d.SetScanDirection(ScanRotate180 + 1); err == nil {
+		t.Errorf("SetScanDirection(%v) = nil, wanted error", ScanRotate180+1)
+	}
+}
+
+func TestScanDirectionRefreshRegion(t *testing.T) {
+	hw, bus := newFakeHardware()
+	d := &Display{hw: hw, buffer: NewImage(DisplayBounds), scan: ScanFlip}
+	if err := d.RefreshRegion(image.Rect(16, 0, 32, 10)); err != nil {
+		t.Fatalf("RefreshRegion() = %v, wanted no error", err)
+	}
+	want := []fakeCommand{
+		{cmd: setRamXStart, data: []byte{0x10, 0x00, 0x1F, 0x00}},
+		{cmd: setRamYStart, data: []byte{0xA0, 0x00, 0xA9, 0x00}},
+		{cmd: setRamXAddressCtr, data: []byte{0x10, 0x00}},
+		{cmd: setRamYAddressCtr, data: []byte{0xA0, 0x00}},
+	}
+	checkCommands(t, "RefreshRegion", bus.commands()[:len(want)], want)
+}
+
+// checkCommands reports any difference between the commands got and want.
+func checkCommands(t *testing.T, name string, got, want []fakeCommand) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Errorf("%s sent %d commands, wanted %d: %v", name, len(got), len(want), got)
+		return
+	}
+	for i, w := range want {
+		if got[i].cmd != w.cmd || !bytes.Equal(got[i].data, w.data) {
+			t.Errorf("%s command %d = %v % x, wanted %v % x", name, i, got[i].cmd, got[i].data, w.cmd, w.data)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package epd7in5bhd
+
+import "github.com/toothrot/gink/devices/internal/driver"
+
+// ErrNoHardware is wrapped by the error returned by New on a host with no display hardware,
+// such as a laptop or CI machine, where periph's host drivers fail to load or no SPI ports
+// are available. On a Raspberry Pi with SPI disabled, New returns an error that does not wrap
+// it.
+var ErrNoHardware = driver.ErrNoHardware
+
+// WithSimulator makes New return a Display backed by a simulator, instead of an error
+// wrapping ErrNoHardware, when the host has no display hardware. This lets the same program
+// run in CI or on a development machine without special cases.
+//
+// A simulated Display accepts every method, and keeps its buffer as a real one does, but
+// sends nothing: refreshes finish at once, and reads from the panel, such as ReadOTP and
+// Verify, see zeros. Other errors from New, such as an invalid pin on a host that has
+// hardware, or SPI being disabled on a Raspberry Pi, are still returned.
+func WithSimulator() Option {
+	return func(o *options) {
+		o.simulator = true
+	}
+}
+
+// Simulated reports whether d is backed by a simulator, as allowed by WithSimulator, rather
+// than a panel.
+func (d *Display) Simulated() bool {
+	return d.simulated
+}
@@ -0,0 +1,31 @@
+package epd7in5bhd
+
+import (
+	"errors"
+	"image"
+	"testing"
+)
+
+func TestNewSimulator(t *testing.T) {
+	d, err := New(DefaultPins, WithSimulator())
+	if err != nil {
+		t.Fatalf("New(_, WithSimulator()) = _, %v, wanted a simulated Display", err)
+	}
+	if !d.Simulated() {
+		t.Skip("display hardware is attached")
+	}
+	if _, err := New(DefaultPins); !errors.Is(err, ErrNoHardware) {
+		t.Errorf("New() = _, %v, wanted %v", err, ErrNoHardware)
+	}
+
+	d.Init()
+	img := image.NewPaletted(DisplayBounds, defaultPalette)
+	img.SetColorIndex(10, 10, 1)
+	if err := d.DrawAndRefresh(img); err != nil {
+		t.Errorf("DrawAndRefresh() = %v, wanted no error", err)
+	}
+	if got := d.buffer.At(10, 10); got != Black {
+		t.Errorf("buffer.At(10, 10) = %v, wanted %v", got, Black)
+	}
+	d.Sleep()
+}
@@ -0,0 +1,43 @@
+package epd7in5bhd
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/toothrot/gink/devices/internal/driver"
+)
+
+// tapTimeFormat is the time format of each line written by WithProtocolTap.
+const tapTimeFormat = "2006-01-02T15:04:05.000000Z07:00"
+
+// WithProtocolTap writes a line to w for each command byte and each write of data sent to the
+// panel, for comparing the traffic with a logic analyzer capture or another driver. Each line
+// has three or four tab-separated fields: the time it was sent, with microseconds, then "cmd",
+// the command byte in hex, and its name; or "data" and the number of bytes.
+//
+//	2021-06-01T15:04:05.123456-04:00	cmd	0x24	writeRAMBW
+//	2021-06-01T15:04:05.123502-04:00	data	48400
+//
+// Commands sent before reading a response, such as by ReadOTP, are included. w is written to
+// while sending, so a slow w slows the panel; errors from w are ignored. The tap is off by
+// default.
+func WithProtocolTap(w io.Writer) Option {
+	return func(o *options) {
+		o.tap = w
+	}
+}
+
+// protocolTap returns a driver.Tap that writes to w as described by WithProtocolTap.
+func protocolTap(w io.Writer) driver.Tap {
+	return func(isCommand bool, p []byte) {
+		now := time.Now().Format(tapTimeFormat)
+		if !isCommand {
+			fmt.Fprintf(w, "%s\tdata\t%d\n", now, len(p))
+			return
+		}
+		for _, b := range p {
+			fmt.Fprintf(w, "%s\tcmd\t0x%02x\t%s\n", now, b, command(b))
+		}
+	}
+}
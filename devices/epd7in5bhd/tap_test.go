@@ -0,0 +1,55 @@
+package epd7in5bhd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProtocolTap(t *testing.T) {
+	hw, _ := newFakeHardware()
+	var buf bytes.Buffer
+	hw.SetTap(protocolTap(&buf))
+	d := &Display{hw: hw, buffer: NewImage(DisplayBounds)}
+	if err := d.sendCommand(writeRAMBW, 1, 2, 3); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.sendCommand(masterActivation); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	want := [][]string{
+		{"cmd", "0x24", "writeRAMBW"},
+		{"data", "3"},
+		{"cmd", "0x20", "masterActivation"},
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("tap wrote %q, wanted %d lines", buf.String(), len(want))
+	}
+	for i, line := range lines {
+		fields := strings.Split(line, "\t")
+		if _, err := time.Parse(tapTimeFormat, fields[0]); err != nil {
+			t.Errorf("line %d: time.Parse(%q) = %v", i, fields[0], err)
+		}
+		if got := strings.Join(fields[1:], " "); got != strings.Join(want[i], " ") {
+			t.Errorf("line %d = %q, wanted fields %q after the time", i, line, want[i])
+		}
+	}
+}
+
+func TestWithProtocolTap(t *testing.T) {
+	var buf bytes.Buffer
+	d, err := New(DefaultPins, WithSimulator(), WithProtocolTap(&buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !d.Simulated() {
+		t.Skip("display hardware is attached")
+	}
+	d.Init()
+	if !strings.Contains(buf.String(), "\tcmd\t0x20\tmasterActivation\n") {
+		t.Errorf("tap wrote %q after Init(), wanted a masterActivation command", buf.String())
+	}
+}
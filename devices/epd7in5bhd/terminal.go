@@ -0,0 +1,58 @@
+package epd7in5bhd
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"io"
+)
+
+// PrintTerminal writes an approximation of the display buffer to w for a terminal with 24-bit
+// color, such as over SSH to check a frame without seeing the panel.
+//
+// Each scale by scale block of pixels is shown as its most common color, and each character
+// shows two blocks, one above the other, using a half block. At a scale of 8, the full display
+// takes 110 columns and 33 lines.
+func (d *Display) PrintTerminal(w io.Writer, scale int) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return writeTerminal(w, d.buffer, scale)
+}
+
+// writeTerminal writes img to w as PrintTerminal does.
+func writeTerminal(w io.Writer, img *Image, scale int) error {
+	if scale < 1 {
+		return fmt.Errorf("invalid scale %d, must be at least 1", scale)
+	}
+	r := img.Bounds()
+	bw := bufio.NewWriter(w)
+	for y := r.Min.Y; y < r.Max.Y; y += 2 * scale {
+		for x := r.Min.X; x < r.Max.X; x += scale {
+			top := blockColor(img, image.Rect(x, y, x+scale, y+scale))
+			bottom := blockColor(img, image.Rect(x, y+scale, x+scale, y+2*scale))
+			tr, tg, tb, _ := top.RGBA()
+			br, bg, bb, _ := bottom.RGBA()
+			fmt.Fprintf(bw, "\x1b[38;2;%d;%d;%dm\x1b[48;2;%d;%d;%dm▀", tr>>8, tg>>8, tb>>8, br>>8, bg>>8, bb>>8)
+		}
+		bw.WriteString("\x1b[0m\n")
+	}
+	return bw.Flush()
+}
+
+// blockColor returns the most common color of the pixels of img within b. Ties go to the
+// highlight color, then black, so that thin lines stay visible. Pixels outside of img are white.
+func blockColor(img *Image, b image.Rectangle) Color {
+	var n [3]int
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			n[img.ColorIndexAt(x, y)]++
+		}
+	}
+	c := Highlight
+	for _, i := range []uint8{1, 0} {
+		if n[i] > n[c.C] {
+			c = Color{i}
+		}
+	}
+	return c
+}
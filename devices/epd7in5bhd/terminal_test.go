@@ -0,0 +1,43 @@
+package epd7in5bhd
+
+import (
+	"bytes"
+	"image"
+	"strings"
+	"testing"
+)
+
+func TestPrintTerminal(t *testing.T) {
+	d := &Display{buffer: NewImage(image.Rect(0, 0, 4, 4))}
+	// The top left 2x2 block is black, and the bottom right has a single highlight pixel.
+	for _, pt := range []image.Point{{0, 0}, {1, 0}, {0, 1}, {1, 1}} {
+		d.buffer.SetColorIndex(pt.X, pt.Y, 1)
+	}
+	d.buffer.SetColorIndex(3, 3, 2)
+
+	var buf bytes.Buffer
+	if err := d.PrintTerminal(&buf, 2); err != nil {
+		t.Fatalf("PrintTerminal() = %v, wanted no error", err)
+	}
+	want := "\x1b[38;2;0;0;0m\x1b[48;2;255;255;255m▀" +
+		"\x1b[38;2;255;255;255m\x1b[48;2;255;255;255m▀" +
+		"\x1b[0m\n"
+	if got := buf.String(); got != want {
+		t.Errorf("PrintTerminal() = %q, wanted %q", got, want)
+	}
+
+	buf.Reset()
+	if err := d.PrintTerminal(&buf, 1); err != nil {
+		t.Fatalf("PrintTerminal() = %v, wanted no error", err)
+	}
+	if lines := strings.Count(buf.String(), "\n"); lines != 2 {
+		t.Errorf("PrintTerminal() at scale 1 wrote %d lines, wanted 2", lines)
+	}
+	if !strings.Contains(buf.String(), "\x1b[48;2;255;0;0m") {
+		t.Errorf("PrintTerminal() at scale 1 = %q, wanted a highlight pixel", buf.String())
+	}
+
+	if err := d.PrintTerminal(&buf, 0); err == nil {
+		t.Errorf("PrintTerminal() with scale 0 = nil, wanted error")
+	}
+}
@@ -0,0 +1,96 @@
+package epd7in5bhd
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNoReads is returned by Verify when the panel's data line can't be read, so the panel can't
+// be checked.
+var ErrNoReads = errors.New("panel does not respond to reads")
+
+// ramProbe is the byte Verify writes to the panel's RAM and expects to read back.
+const ramProbe = 0xA5
+
+// Verify checks, as far as the controller allows, that the attached panel is one this package
+// can drive, such as to catch the use of the wrong device package. It is optional, and New
+// does not call it.
+//
+// The SSD1677 does not report its resolution, so Verify checks that the initialization tables
+// cover DisplayBounds, reads the OTP to check that reads work at all, and then writes a byte to
+// the bottom right corner of the panel's RAM and reads it back. A panel with another controller,
+// such as the 640x384 panel driven by epd7in5bc, does not read it back.
+//
+// Reads must be wired up for Verify to work; if they are not, it returns an error wrapping
+// ErrNoReads. A panel in deep sleep ignores everything until it is reset, so Verify resets the
+// panel first, and puts it into deep sleep again when it is done, even if it fails. Call Init
+// before drawing to the panel afterwards.
+func (d *Display) Verify() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := checkSteps(configureSteps); err != nil {
+		return err
+	}
+	d.reset()
+	defer d.sendCommand(deepSleepMode, 0x01)
+	if err := d.runSteps(resetSteps); err != nil {
+		return err
+	}
+	otp, err := d.hw.Read(byte(otpRegisterRead), otpSize)
+	if err != nil {
+		return err
+	}
+	if allBytes(otp, 0x00) || allBytes(otp, 0xFF) {
+		return fmt.Errorf("OTP read returned all %#02x: %w", otp[0], ErrNoReads)
+	}
+
+	x, y := DisplayWidth-8, DisplayHeight-1
+	d.setWindow(x, DisplayWidth-1, y, y)
+	d.sendCommand(writeRAMBW, ramProbe)
+	d.setWindow(x, DisplayWidth-1, y, y)
+	d.sendCommand(readRamOption, 0x00)
+	// The first byte read is a dummy.
+	b, err := d.hw.Read(byte(readRAM), 2)
+	d.resetWindow()
+	if err != nil {
+		return err
+	}
+	if b[1] != ramProbe {
+		return fmt.Errorf("configured for %dx%d (SSD1677), but the panel did not read back RAM at (%d, %d): wrote %#02x, read %#02x; it may be a different panel, such as the 640x384 epd7in5bc",
+			DisplayWidth, DisplayHeight, x, y, ramProbe, b[1])
+	}
+	return nil
+}
+
+// checkSteps returns an error if the RAM window set by steps does not cover DisplayBounds.
+func checkSteps(steps []initStep) error {
+	var xEnd, yStart, yEnd int
+	var found int
+	for _, s := range steps {
+		switch {
+		case s.cmd == setRamXStart && len(s.data) == 4:
+			xEnd = int(s.data[2]) | int(s.data[3])<<8
+			found++
+		case s.cmd == setRamYStart && len(s.data) == 4:
+			yStart, yEnd = int(s.data[0])|int(s.data[1])<<8, int(s.data[2])|int(s.data[3])<<8
+			found++
+		}
+	}
+	if found != 2 {
+		return errors.New("initialization table does not set the RAM window")
+	}
+	if xEnd+1 != DisplayWidth || yStart-yEnd+1 < DisplayHeight {
+		return fmt.Errorf("initialization table sets a RAM window of %dx%d, which does not fit the configured %dx%d", xEnd+1, yStart-yEnd+1, DisplayWidth, DisplayHeight)
+	}
+	return nil
+}
+
+// allBytes reports whether every byte of b is c.
+func allBytes(b []byte, c byte) bool {
+	for _, v := range b {
+		if v != c {
+			return false
+		}
+	}
+	return true
+}
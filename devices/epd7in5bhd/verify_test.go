@@ -0,0 +1,68 @@
+package epd7in5bhd
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestVerify(t *testing.T) {
+	otp := []byte{0x80, 0x3C, 1, 2, 3, 4, 5, 0xDE, 0xAD, 0xBE, 0xEF}
+	cases := []struct {
+		desc    string
+		rx      []byte
+		wantErr string
+		wantIs  error
+	}{
+		{desc: "matching panel", rx: append(append([]byte(nil), otp...), 0x00, ramProbe)},
+		{desc: "no reads", rx: make([]byte, otpSize+2), wantIs: ErrNoReads},
+		{desc: "other panel", rx: append(append([]byte(nil), otp...), 0x00, 0x12), wantErr: "did not read back RAM at (872, 527)"},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			hw, bus := newFakeHardware()
+			bus.Rx = c.rx
+			d := &Display{hw: hw, buffer: NewImage(DisplayBounds), SettleDelay: -1}
+
+			err := d.Verify()
+			switch {
+			case c.wantIs != nil:
+				if !errors.Is(err, c.wantIs) {
+					t.Errorf("Verify() = %v, wanted %v", err, c.wantIs)
+				}
+			case c.wantErr != "":
+				if err == nil || !strings.Contains(err.Error(), c.wantErr) {
+					t.Errorf("Verify() = %v, wanted error containing %q", err, c.wantErr)
+				}
+			case err != nil:
+				t.Errorf("Verify() = %v, wanted nil", err)
+			}
+			cmds := bus.commands()
+			if len(cmds) < 2 || cmds[0].cmd != displayRefresh || cmds[len(cmds)-1].cmd != deepSleepMode {
+				t.Errorf("Verify() sent %v, wanted a reset first and deep sleep last", cmds)
+			}
+			if c.wantIs != nil {
+				return
+			}
+			if countCommands(cmds, writeRAMBW, ramProbe) != 1 || countCommands(cmds, readRamOption, 0x00) != 1 || countCommands(cmds, readRAM) != 1 {
+				t.Errorf("Verify() sent %v, wanted a write and read back of the probe", cmds)
+			}
+		})
+	}
+}
+
+func TestCheckSteps(t *testing.T) {
+	if err := checkSteps(configureSteps); err != nil {
+		t.Errorf("checkSteps(configureSteps) = %v, wanted nil", err)
+	}
+	small := []initStep{
+		{cmd: setRamXStart, data: []byte{0x00, 0x00, 0x7F, 0x02}},
+		{cmd: setRamYStart, data: []byte{0x7F, 0x01, 0x00, 0x00}},
+	}
+	if err := checkSteps(small); err == nil || !strings.Contains(err.Error(), "640x384") {
+		t.Errorf("checkSteps(640x384) = %v, wanted an error naming the window", err)
+	}
+	if err := checkSteps(resetSteps); err == nil {
+		t.Errorf("checkSteps(resetSteps) = nil, wanted error for a table without a window")
+	}
+}
@@ -0,0 +1,93 @@
+package epd7in5bhd
+
+import (
+	"fmt"
+	"math"
+)
+
+// drivingVoltages are the register values for setGateDrivingVoltage and
+// setSourceDrivingVoltage.
+type drivingVoltages struct {
+	gate, vsh1, vsh2, vsl byte
+}
+
+// SetDrivingVoltages overrides the gate and source driving voltages that the controller
+// otherwise loads from OTP. The values are sent immediately, and again by every Init or
+// InitFast, since a reset restores the defaults.
+//
+// The register values are those of the SSD1677 datasheet:
+//
+//	gate: VGH. 0x00 is 20V, the default. 0x07 to 0x17 select 12V to 20V in 0.5V steps.
+//	vshH: VSH1. The default is 0x41, 15V.
+//	vshL: VSH2. The default is 0xA8, 5V.
+//	vsl:  VSL. The default is 0x32, -15V.
+//
+// VSH1 and VSH2 range from 2.4V to 17V, and VSL from -5V to -17V; see the datasheet's
+// tables for the encoding. Raising VSH2 can strengthen faint reds, and raising VSH1 or
+// lowering VSL can deepen weak blacks, at the cost of panel wear. Values outside of the
+// datasheet's tables are not checked.
+func (d *Display) SetDrivingVoltages(gate, vshH, vshL, vsl byte) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.voltages = &drivingVoltages{gate: gate, vsh1: vshH, vsh2: vshL, vsl: vsl}
+	d.sendVoltages()
+}
+
+// sendVoltages sends the voltages set by SetDrivingVoltages and SetVCOM, if any.
+func (d *Display) sendVoltages() {
+	if d.voltages != nil {
+		d.sendCommand(setGateDrivingVoltage, d.voltages.gate)
+		d.sendCommand(setSourceDrivingVoltage, d.voltages.vsh1, d.voltages.vsh2, d.voltages.vsl)
+	}
+	if d.vcom != nil {
+		d.sendCommand(vcomWriteRegister, *d.vcom)
+	}
+}
+
+// The range of VCOM voltages accepted by vcomWriteRegister.
+const (
+	MinVCOM = -3.0
+	MaxVCOM = -0.2
+)
+
+// SetVCOM sets the VCOM voltage in volts, overriding the value programmed into OTP. v must be
+// between MinVCOM and MaxVCOM. Like SetDrivingVoltages, the value is sent immediately and
+// again by every Init or InitFast.
+//
+// A VCOM that does not match the panel causes ghosting and uneven blacks. Panels often have
+// their VCOM printed on the flex cable; otherwise ReadVCOM returns the programmed default.
+func (d *Display) SetVCOM(v float64) error {
+	if math.IsNaN(v) || v < MinVCOM || v > MaxVCOM {
+		return fmt.Errorf("invalid VCOM %vV, must be between %vV and %vV", v, MinVCOM, MaxVCOM)
+	}
+	b := encodeVCOM(v)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.vcom = &b
+	d.sendCommand(vcomWriteRegister, b)
+	return nil
+}
+
+// ReadVCOM returns the VCOM voltage programmed into OTP. It has the same wiring requirements
+// as ReadOTP.
+func (d *Display) ReadVCOM() (float64, error) {
+	b, err := d.ReadOTP()
+	if err != nil {
+		return 0, err
+	}
+	o, err := DecodeOTP(b)
+	if err != nil {
+		return 0, err
+	}
+	return decodeVCOM(o.VCOM), nil
+}
+
+// encodeVCOM returns the vcomWriteRegister value for v volts. The register counts down from
+// 0V in steps of 25mV, so 0x08 is -0.2V and 0x78 is -3.0V.
+func encodeVCOM(v float64) byte {
+	return byte(math.Round(-v * 40))
+}
+
+func decodeVCOM(b byte) float64 {
+	return -float64(b) / 40
+}
@@ -0,0 +1,79 @@
+package epd7in5bhd
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSetDrivingVoltages(t *testing.T) {
+	hw, bus := newFakeHardware()
+	d := &Display{hw: hw, buffer: NewImage(DisplayBounds)}
+	d.SetDrivingVoltages(0x17, 0x41, 0xA8, 0x32)
+	d.InitFast()
+
+	want := map[command][]byte{
+		setGateDrivingVoltage:   {0x17},
+		setSourceDrivingVoltage: {0x41, 0xA8, 0x32},
+	}
+	sent := map[command]int{}
+	for _, c := range bus.commands() {
+		w, ok := want[c.cmd]
+		if !ok {
+			continue
+		}
+		sent[c.cmd]++
+		if !bytes.Equal(c.data, w) {
+			t.Errorf("%v sent % X, wanted % X", c.cmd, c.data, w)
+		}
+	}
+	// Once when set, and again after the reset in InitFast.
+	for cmd := range want {
+		if sent[cmd] != 2 {
+			t.Errorf("%v was sent %d times, wanted 2", cmd, sent[cmd])
+		}
+	}
+}
+
+func TestSetVCOM(t *testing.T) {
+	for _, v := range []float64{-0.1, -3.1, 0, 1} {
+		hw, bus := newFakeHardware()
+		d := &Display{hw: hw, buffer: NewImage(DisplayBounds)}
+		if err := d.SetVCOM(v); err == nil {
+			t.Errorf("SetVCOM(%v) = nil, wanted error", v)
+		}
+		if len(bus.commands()) != 0 {
+			t.Errorf("SetVCOM(%v) sent %v, wanted nothing", v, bus.commands())
+		}
+	}
+
+	cases := []struct {
+		v    float64
+		want byte
+	}{
+		{v: MaxVCOM, want: 0x08},
+		{v: -1.5, want: 0x3C},
+		{v: MinVCOM, want: 0x78},
+	}
+	for _, c := range cases {
+		hw, bus := newFakeHardware()
+		d := &Display{hw: hw, buffer: NewImage(DisplayBounds)}
+		if err := d.SetVCOM(c.v); err != nil {
+			t.Errorf("SetVCOM(%v) = %v, wanted no error", c.v, err)
+			continue
+		}
+		got := bus.commands()
+		if len(got) != 1 || got[0].cmd != vcomWriteRegister || !bytes.Equal(got[0].data, []byte{c.want}) {
+			t.Errorf("SetVCOM(%v) sent %v, wanted %v % X", c.v, got, vcomWriteRegister, c.want)
+		}
+	}
+}
+
+func TestReadVCOM(t *testing.T) {
+	hw, bus := newFakeHardware()
+	bus.Rx = []byte{0x80, 0x3C, 1, 2, 3, 4, 5, 0xDE, 0xAD, 0xBE, 0xEF}
+	d := &Display{hw: hw}
+	v, err := d.ReadVCOM()
+	if err != nil || v != -1.5 {
+		t.Errorf("ReadVCOM() = %v, %v, wanted %v, nil", v, err, -1.5)
+	}
+}
@@ -0,0 +1,53 @@
+package epd7in5bhd
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrRefreshTimeout is wrapped by the error returned when the panel is still busy after the
+// limit set by WithWatchdog.
+var ErrRefreshTimeout = errors.New("panel did not become idle in time")
+
+// WithWatchdog limits each wait for the panel to become idle to maxRefresh, rather than waiting
+// forever. A panel that stops responding otherwise hangs the process in its next refresh.
+//
+// If a full refresh, such as from Refresh, Upload, or DrawAndRefresh, takes longer than
+// maxRefresh, the refresh is abandoned, the panel is reset and reinitialized as by Init, and
+// the frame is uploaded and refreshed once more. If that also fails, an error wrapping
+// ErrRefreshTimeout is returned. LastRefreshStats reports whether a refresh was recovered.
+//
+// Other refreshes, such as RefreshRegion and RefreshHighlightOnly, depend on RAM that Init
+// clears, so they return the error without retrying. maxRefresh must comfortably exceed the
+// panel's refresh time, which MeasureRefresh reports; about 20s is typical in the cold.
+// The watchdog is off by default.
+func WithWatchdog(maxRefresh time.Duration) Option {
+	return func(o *options) {
+		o.watchdog = maxRefresh
+	}
+}
+
+// recoverRefresh reinitializes the panel after err timed out a refresh, and then refreshes
+// black and red once more.
+func (d *Display) recoverRefresh(black, red []byte, err error) error {
+	d.logf("Watchdog: %v; reinitializing the panel", err)
+	if err := d.init(); err != nil {
+		d.logf("Watchdog: Init: %v", err)
+		return err
+	}
+	if _, err := d.uploadBlack(black); err != nil {
+		return err
+	}
+	if _, err := d.uploadHighlight(red); err != nil {
+		return err
+	}
+	start := time.Now()
+	err = d.turnOnDisplay()
+	d.stats.Wait = time.Since(start)
+	if err != nil {
+		d.logf("Watchdog: retry: %v", err)
+		return err
+	}
+	d.stats.Recovered = true
+	return nil
+}
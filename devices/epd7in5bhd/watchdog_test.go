@@ -0,0 +1,94 @@
+package epd7in5bhd
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"periph.io/x/periph/conn/gpio"
+)
+
+// countCommands returns how many times cmd was sent, only counting those sent with data if any
+// is given.
+func countCommands(cmds []fakeCommand, cmd command, data ...byte) int {
+	n := 0
+	for _, c := range cmds {
+		if c.cmd == cmd && (len(data) == 0 || string(c.data) == string(data)) {
+			n++
+		}
+	}
+	return n
+}
+
+func TestWatchdogStuck(t *testing.T) {
+	hw, bus := newFakeHardware()
+	d := &Display{hw: hw, buffer: NewImage(DisplayBounds), SettleDelay: -1, watchdog: 20 * time.Millisecond}
+	hw.Busy().Out(gpio.Low)
+
+	if err := d.Refresh(); !errors.Is(err, ErrRefreshTimeout) {
+		t.Fatalf("Refresh() = %v, wanted %v", err, ErrRefreshTimeout)
+	}
+	// The panel is reset and initialized, which times out in its first wait.
+	cmds := bus.commands()
+	if n := countCommands(cmds, displayUpdateControl2, 0xC7); n != 1 {
+		t.Errorf("Refresh() triggered %d refreshes, wanted 1 before the reinitialization failed", n)
+	}
+	if n := countCommands(cmds, displayRefresh); n != 1 {
+		t.Errorf("Refresh() sent %d resetSteps, wanted 1", n)
+	}
+	if d.LastRefreshStats().Recovered {
+		t.Errorf("LastRefreshStats().Recovered = true, wanted false")
+	}
+}
+
+func TestWatchdogRecovers(t *testing.T) {
+	hw, bus := newFakeHardware()
+	l := &fakeLogger{}
+	d := &Display{hw: hw, buffer: NewImage(DisplayBounds), SettleDelay: -1, watchdog: 100 * time.Millisecond, logger: l}
+	hw.Busy().Out(gpio.Low)
+	// The panel recovers partway through the reinitialization's first wait.
+	release := time.AfterFunc(150*time.Millisecond, func() { hw.Busy().Out(gpio.High) })
+	defer release.Stop()
+
+	if err := d.Refresh(); err != nil {
+		t.Fatalf("Refresh() = %v, wanted no error", err)
+	}
+	cmds := bus.commands()
+	if n := countCommands(cmds, displayUpdateControl2, 0xC7); n != 2 {
+		t.Errorf("Refresh() triggered %d refreshes, wanted 2", n)
+	}
+	if n := countCommands(cmds, writeRAMBW); n != 2 {
+		t.Errorf("Refresh() uploaded the black plane %d times, wanted 2", n)
+	}
+	if !d.LastRefreshStats().Recovered {
+		t.Errorf("LastRefreshStats().Recovered = false, wanted true")
+	}
+	if len(l.lines) == 0 {
+		t.Errorf("Refresh() logged nothing, wanted the watchdog to log")
+	}
+}
+
+func TestWatchdogOff(t *testing.T) {
+	hw, _ := newFakeHardware()
+	d := &Display{hw: hw, buffer: NewImage(DisplayBounds), SettleDelay: -1}
+	hw.Busy().Out(gpio.Low)
+	release := time.AfterFunc(50*time.Millisecond, func() { hw.Busy().Out(gpio.High) })
+	defer release.Stop()
+	if err := d.Refresh(); err != nil {
+		t.Errorf("Refresh() = %v without a watchdog, wanted no error", err)
+	}
+}
+
+func TestWatchdogInit(t *testing.T) {
+	for name, init := range map[string]func(*Display) error{"Init": (*Display).Init, "InitFast": (*Display).InitFast} {
+		hw, _ := newFakeHardware()
+		d := &Display{hw: hw, buffer: NewImage(DisplayBounds), SettleDelay: -1, watchdog: 20 * time.Millisecond}
+		hw.Busy().Out(gpio.Low)
+		if err := init(d); !errors.Is(err, ErrRefreshTimeout) {
+			t.Errorf("%s() = %v, wanted %v", name, err, ErrRefreshTimeout)
+		}
+		if n := d.Stats().WatchdogTimeouts; n != 1 {
+			t.Errorf("%s(): Stats().WatchdogTimeouts = %d, wanted 1", name, n)
+		}
+	}
+}
@@ -0,0 +1,584 @@
+// Package driver implements the GPIO and SPI layer shared by the e-Paper display packages.
+//
+// It handles pin and bus acquisition, the data/command pin, chip select, and splitting
+// transfers. The command set and timing of each panel belong to its display package.
+package driver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/gpio/gpioreg"
+	"periph.io/x/periph/conn/physic"
+	"periph.io/x/periph/conn/spi"
+	"periph.io/x/periph/conn/spi/spireg"
+	"periph.io/x/periph/host"
+	"periph.io/x/periph/host/rpi"
+)
+
+// DefaultTxLimit is the default maximum number of bytes sent in a single SPI transfer.
+const DefaultTxLimit = 2048
+
+// DefaultSpeed is the default SPI clock. 20Mhz is the max for write operations, and 2.5Mhz is
+// the max for read operations. Wire length and health impact the maximum workable speed.
+const DefaultSpeed = 20 * physic.MegaHertz
+
+// ReadSpeed is the SPI clock that Read lowers the port to, the max for read operations.
+const ReadSpeed = 2500 * physic.KiloHertz
+
+// ErrNoHardware is wrapped by errors from Open when the host has no display hardware at all,
+// such as a laptop or CI machine: periph's host drivers fail to load, or no SPI ports are
+// registered on a host that is not a Raspberry Pi. A Raspberry Pi with SPI disabled is
+// misconfigured rather than without hardware, so its error does not match.
+var ErrNoHardware = errors.New("no display hardware found")
+
+// noHardwareError wraps err, keeping its message, so that it also matches ErrNoHardware.
+type noHardwareError struct {
+	err error
+}
+
+func (e noHardwareError) Error() string {
+	return e.err.Error()
+}
+
+func (e noHardwareError) Unwrap() error {
+	return e.err
+}
+
+func (e noHardwareError) Is(target error) bool {
+	return target == ErrNoHardware
+}
+
+// Pins are the gpioreg names of the pins used to drive a display.
+type Pins struct {
+	Busy string
+	CS   string
+	DC   string
+	RST  string
+}
+
+// Validate returns an error if any pin in p is unset, or if two pins have the same name.
+// Pins are compared by name, so aliases for the same pin, such as "P1_18" and "GPIO24", are
+// not caught until they are acquired.
+func (p Pins) Validate() error {
+	if p == (Pins{}) {
+		return errors.New("Pins is empty; use DefaultPins or set all four pins")
+	}
+	pins := []struct {
+		name, value string
+	}{{"busy", p.Busy}, {"cs", p.CS}, {"dc", p.DC}, {"rst", p.RST}}
+	for i, a := range pins {
+		if a.value == "" {
+			return fmt.Errorf("%s pin is not set", a.name)
+		}
+		for _, b := range pins[:i] {
+			if a.value == b.value {
+				return fmt.Errorf("%s and %s pins are both %q", b.name, a.name, a.value)
+			}
+		}
+	}
+	return nil
+}
+
+// Open acquires the pins in p, and the SPI port at speed, or DefaultSpeed if speed is 0. port
+// is the spireg name, alias, or number of the port, such as "SPI1.0", or "" for the default
+// port. If ctx is done first, it returns an error wrapping ctx.Err(), and anything acquired
+// afterwards is released in the background.
+//
+// Pins, port, and speed are validated before anything is acquired. An unknown port's error
+// lists the ports that are available.
+func Open(ctx context.Context, p Pins, port string, speed physic.Frequency) (*Hardware, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	if speed < 0 {
+		return nil, fmt.Errorf("invalid SPI speed %v, must be greater than 0", speed)
+	}
+	if speed == 0 {
+		speed = DefaultSpeed
+	}
+	type result struct {
+		h   *Hardware
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		h, err := openHardware(ctx, p, port, speed)
+		done <- result{h, err}
+	}()
+	select {
+	case r := <-done:
+		return r.h, r.err
+	case <-ctx.Done():
+		go func() {
+			if r := <-done; r.err == nil {
+				r.h.Close()
+			}
+		}()
+		return nil, fmt.Errorf("acquiring display hardware: %w", ctx.Err())
+	}
+}
+
+func openHardware(ctx context.Context, p Pins, portName string, speed physic.Frequency) (*Hardware, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("acquiring display hardware: %w", err)
+	}
+	if _, err := host.Init(); err != nil {
+		return nil, noHardwareError{fmt.Errorf("host.Init() = %w", err)}
+	}
+	// Ports are only registered by host.Init.
+	refs := spireg.All()
+	if len(refs) == 0 {
+		if rpi.Present() {
+			return nil, errors.New("no SPI ports are available; is SPI enabled?")
+		}
+		return nil, noHardwareError{errors.New("no SPI ports are available")}
+	}
+	if err := checkPort(portName, refs); err != nil {
+		return nil, err
+	}
+
+	dc := gpioreg.ByName(p.DC)
+	if dc == nil {
+		return nil, fmt.Errorf("invalid dc pin %q", p.DC)
+	}
+	if err := dc.Out(gpio.Low); err != nil {
+		return nil, fmt.Errorf("dc.Out(%v) = %w", gpio.Low, err)
+	}
+
+	cs := gpioreg.ByName(p.CS)
+	if cs == nil {
+		return nil, fmt.Errorf("invalid cs pin %q", p.CS)
+	}
+	if err := cs.Out(gpio.Low); err != nil {
+		return nil, fmt.Errorf("cs.Out(%v) = %w", gpio.Low, err)
+	}
+
+	rst := gpioreg.ByName(p.RST)
+	if rst == nil {
+		return nil, fmt.Errorf("invalid rst pin %q", p.RST)
+	}
+	if err := rst.Out(gpio.Low); err != nil {
+		return nil, fmt.Errorf("rst.Out(%v) = %w", gpio.Low, err)
+	}
+
+	busy := gpioreg.ByName(p.Busy)
+	if busy == nil {
+		return nil, fmt.Errorf("invalid busy pin %q", p.Busy)
+	}
+	if err := busy.In(gpio.PullDown, gpio.RisingEdge); err != nil {
+		return nil, fmt.Errorf("busy.In(%v, %v) = %w", gpio.PullDown, gpio.RisingEdge, err)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("acquiring display hardware: %w", err)
+	}
+	port, err := spireg.Open(portName)
+	if err != nil {
+		return nil, fmt.Errorf("spireg.Open(%q) = _, %w", portName, err)
+	}
+	if err := ctx.Err(); err != nil {
+		port.Close()
+		return nil, fmt.Errorf("acquiring display hardware: %w", err)
+	}
+	c, err := port.Connect(speed, spi.Mode0, 8)
+	if err != nil {
+		connerr := fmt.Errorf("port.Connect(%v, %v, %v) = %w", speed, spi.Mode0, 8, err)
+		if err := port.Close(); err != nil {
+			return nil, fmt.Errorf("port.Close() = %w while handling %q", err, connerr)
+		}
+		return nil, connerr
+	}
+
+	return &Hardware{
+		txLimit: DefaultTxLimit,
+		port:    port,
+		speed:   speed,
+		c:       c,
+		dc:      dc,
+		cs:      cs,
+		rst:     rst,
+		busy:    busy,
+	}, nil
+}
+
+// checkPort returns an error listing the ports in refs if name is not the name, an alias, or the
+// number of one of them. The empty name, for the default port, is always accepted.
+func checkPort(name string, refs []*spireg.Ref) error {
+	if name == "" {
+		return nil
+	}
+	var names []string
+	for _, r := range refs {
+		if r.Name == name || (r.Number >= 0 && strconv.Itoa(r.Number) == name) {
+			return nil
+		}
+		for _, a := range r.Aliases {
+			if a == name {
+				return nil
+			}
+		}
+		names = append(names, r.Name)
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("unknown SPI port %q; no SPI ports are available, is SPI enabled?", name)
+	}
+	return fmt.Errorf("unknown SPI port %q; available ports are %s", name, strings.Join(names, ", "))
+}
+
+// New returns Hardware that sends over c, without acquiring anything. It is meant for fakes and
+// tests; use Open for a real display.
+func New(c conn.Conn, dc, cs, rst gpio.PinOut, busy gpio.PinIO) *Hardware {
+	return &Hardware{
+		txLimit: DefaultTxLimit,
+		c:       c,
+		dc:      dc,
+		cs:      cs,
+		rst:     rst,
+		busy:    busy,
+	}
+}
+
+// NewSimulator returns Hardware that acquires nothing, discards everything sent, reads zeros,
+// and reports that the panel is never busy. It stands in for a display on hosts without one.
+func NewSimulator() *Hardware {
+	return New(discard{}, simPin{"DC", gpio.Low}, simPin{"CS", gpio.Low}, simPin{"RST", gpio.Low}, simPin{"BUSY", gpio.High})
+}
+
+// simPin is a gpio.PinIO that ignores output and always reads level.
+type simPin struct {
+	name  string
+	level gpio.Level
+}
+
+func (p simPin) String() string                        { return p.name }
+func (p simPin) Halt() error                           { return nil }
+func (p simPin) Name() string                          { return p.name }
+func (p simPin) Number() int                           { return -1 }
+func (p simPin) Function() string                      { return "" }
+func (p simPin) In(gpio.Pull, gpio.Edge) error         { return nil }
+func (p simPin) Read() gpio.Level                      { return p.level }
+func (p simPin) WaitForEdge(time.Duration) bool        { return false }
+func (p simPin) Pull() gpio.Pull                       { return gpio.PullNoChange }
+func (p simPin) DefaultPull() gpio.Pull                { return gpio.PullNoChange }
+func (p simPin) Out(gpio.Level) error                  { return nil }
+func (p simPin) PWM(gpio.Duty, physic.Frequency) error { return nil }
+
+// discard is a conn.Conn that drops writes and reads zeros.
+type discard struct{}
+
+func (discard) String() string {
+	return "driver.discard"
+}
+
+// Tx implements conn.Conn.
+func (discard) Tx(w, r []byte) error {
+	for i := range r {
+		r[i] = 0
+	}
+	return nil
+}
+
+// Duplex implements conn.Conn.
+func (discard) Duplex() conn.Duplex {
+	return conn.Half
+}
+
+// Hardware sends commands and data to a display over SPI. Its methods are safe for concurrent
+// use.
+type Hardware struct {
+	txLimit int
+	// holdCS keeps cs low from a command through the end of its data.
+	holdCS bool
+	// tap, if set, is called with everything sent. See SetTap.
+	tap Tap
+
+	mut sync.Mutex
+	// port is the SPI port that c is connected through. It is nil for Hardware from New.
+	port spi.PortCloser
+	// speed is the clock that c was connected at.
+	speed physic.Frequency
+	// c is a perhiph conn.Conn.
+	c conn.Conn
+
+	// busy pin, when waiting for device to be ready.
+	busy gpio.PinIO
+	// cs is the Chip Enable pin.
+	cs gpio.PinOut
+	// dc is the data/command pin.
+	dc gpio.PinOut
+	// rst is the CE1 pin.
+	rst gpio.PinOut
+}
+
+// Busy returns the busy pin.
+func (h *Hardware) Busy() gpio.PinIO {
+	return h.busy
+}
+
+// RST returns the reset pin.
+func (h *Hardware) RST() gpio.PinOut {
+	return h.rst
+}
+
+// SetHoldCS sets whether cs is held low from each command through the end of its data,
+// instead of being released between the two.
+func (h *Hardware) SetHoldCS(hold bool) {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+	h.holdCS = hold
+}
+
+// HoldCS reports whether cs is held low from each command through the end of its data.
+func (h *Hardware) HoldCS() bool {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+	return h.holdCS
+}
+
+// Close releases the SPI port.
+func (h *Hardware) Close() error {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+	if h.port == nil {
+		return nil
+	}
+	err := h.port.Close()
+	h.port = nil
+	return err
+}
+
+// Tap is called by Hardware with each command byte, with command set, and each write of data,
+// in the order they are sent. It is called with the Hardware's lock held, so it must not call
+// the Hardware's methods, and p must not be retained.
+type Tap func(command bool, p []byte)
+
+// SetTap sets a function to be called with everything sent, for logging the protocol. Nil, the
+// default, turns it off.
+func (h *Hardware) SetTap(t Tap) {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+	h.tap = t
+}
+
+// Tap returns the function set by SetTap.
+func (h *Hardware) Tap() Tap {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+	return h.tap
+}
+
+// SetTxLimit sets the maximum number of bytes sent in a single SPI transfer.
+func (h *Hardware) SetTxLimit(n int) error {
+	if n <= 0 {
+		return fmt.Errorf("invalid tx limit %d, must be greater than 0", n)
+	}
+	h.mut.Lock()
+	defer h.mut.Unlock()
+	h.txLimit = n
+	return nil
+}
+
+// TxLimit returns the maximum number of bytes sent in a single SPI transfer.
+func (h *Hardware) TxLimit() int {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+	return h.txLimit
+}
+
+// DataWriter returns a Writer that sends each write as data.
+func (h *Hardware) DataWriter() io.Writer {
+	return &dataWriter{h}
+}
+
+// CommandWriter returns a Writer that sends the first byte of each write as a command, and the
+// rest as its data.
+func (h *Hardware) CommandWriter() io.Writer {
+	return &commandWriter{h}
+}
+
+type dataWriter struct {
+	*Hardware
+}
+
+// Write sends p as data, split into transfers of at most txLimit bytes.
+func (w *dataWriter) Write(p []byte) (n int, err error) {
+	w.mut.Lock()
+	defer w.mut.Unlock()
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if w.txLimit <= 0 {
+		return 0, fmt.Errorf("invalid tx limit %d, must be greater than 0", w.txLimit)
+	}
+	if err := w.cs.Out(gpio.Low); err != nil {
+		return 0, fmt.Errorf("%v.Out(%v) = %w", w.cs.String(), gpio.Low.String(), err)
+	}
+	if err := w.dc.Out(gpio.High); err != nil {
+		return 0, fmt.Errorf("%v.Out(%v) = %w", w.dc.String(), gpio.High.String(), err)
+	}
+	defer func() {
+		if e := w.cs.Out(gpio.High); e != nil {
+			err = fmt.Errorf("already had err %q, and got e: %w", err, e)
+		}
+	}()
+	if w.tap != nil {
+		w.tap(false, p)
+	}
+	return w.txChunks(p)
+}
+
+// txChunks sends p in transfers of at most txLimit bytes. The caller must hold mut and set
+// the cs and dc pins.
+func (h *Hardware) txChunks(p []byte) (n int, err error) {
+	for n < len(p) {
+		j := n + h.txLimit
+		if j > len(p) {
+			j = len(p)
+		}
+		if err := h.c.Tx(p[n:j], nil); err != nil {
+			return n, err
+		}
+		n = j
+	}
+	return n, nil
+}
+
+// Read sends cmd and then reads n bytes of response data.
+//
+// Reads need the controller's data line to be readable by the SPI controller, and the
+// controller only supports reads at up to 2.5Mhz, so the port is limited to ReadSpeed for the
+// read, and restored to the connection's speed after. Hardware from New is read at whatever
+// speed its conn.Conn runs at.
+func (h *Hardware) Read(cmd byte, n int) (b []byte, err error) {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+	if h.port != nil && h.speed > ReadSpeed {
+		if err := h.port.LimitSpeed(ReadSpeed); err != nil {
+			return nil, fmt.Errorf("port.LimitSpeed(%v) = %w", ReadSpeed, err)
+		}
+		defer func() {
+			if err2 := h.port.LimitSpeed(h.speed); err2 != nil && err == nil {
+				err = fmt.Errorf("port.LimitSpeed(%v) = %w", h.speed, err2)
+			}
+		}()
+	}
+	if err := h.dc.Out(gpio.Low); err != nil {
+		return nil, fmt.Errorf("%v.Out(%v) = %w", h.dc.String(), gpio.Low.String(), err)
+	}
+	if err := h.cs.Out(gpio.Low); err != nil {
+		return nil, fmt.Errorf("%v.Out(%v) = %w", h.cs.String(), gpio.Low.String(), err)
+	}
+	defer func() {
+		if err2 := h.cs.Out(gpio.High); err2 != nil {
+			err = fmt.Errorf("%v.Out(%v) = %w, already had error %v", h.cs.String(), gpio.High, err2, err)
+		}
+	}()
+	if h.tap != nil {
+		h.tap(true, []byte{cmd})
+	}
+	if err := h.c.Tx([]byte{cmd}, nil); err != nil {
+		return nil, fmt.Errorf("sending command %#02x: %w", cmd, err)
+	}
+	if err := h.dc.Out(gpio.High); err != nil {
+		return nil, fmt.Errorf("%v.Out(%v) = %w", h.dc.String(), gpio.High.String(), err)
+	}
+	b = make([]byte, n)
+	if err := h.c.Tx(nil, b); err != nil {
+		return nil, fmt.Errorf("reading %d bytes for command %#02x: %w", n, cmd, err)
+	}
+	return b, nil
+}
+
+type commandWriter struct {
+	*Hardware
+}
+
+func (w *commandWriter) writeCommand(p byte) (err error) {
+	w.mut.Lock()
+	defer w.mut.Unlock()
+	if err := w.dc.Out(gpio.Low); err != nil {
+		return fmt.Errorf("%v.Out(%v) = %w", w.dc.String(), gpio.Low.String(), err)
+	}
+	if err := w.cs.Out(gpio.Low); err != nil {
+		return fmt.Errorf("%v.Out(%v) = %w", w.cs.String(), gpio.Low.String(), err)
+	}
+	defer func() {
+		if err2 := w.cs.Out(gpio.High); err2 != nil {
+			err = fmt.Errorf("%v.Out(%v) = %w, already had error %v", w.cs.String(), gpio.High, err2, err)
+		}
+	}()
+	if w.tap != nil {
+		w.tap(true, []byte{p})
+	}
+	if err := w.c.Tx([]byte{p}, nil); err != nil {
+		return fmt.Errorf("sending command %#02x: %w", p, err)
+	}
+	return nil
+}
+
+// writeFrame sends cmd followed by data with cs held low for the whole frame.
+func (w *commandWriter) writeFrame(cmd byte, data []byte) (n int, err error) {
+	w.mut.Lock()
+	defer w.mut.Unlock()
+	if w.txLimit <= 0 {
+		return 0, fmt.Errorf("invalid tx limit %d, must be greater than 0", w.txLimit)
+	}
+	if err := w.dc.Out(gpio.Low); err != nil {
+		return 0, fmt.Errorf("%v.Out(%v) = %w", w.dc.String(), gpio.Low.String(), err)
+	}
+	if err := w.cs.Out(gpio.Low); err != nil {
+		return 0, fmt.Errorf("%v.Out(%v) = %w", w.cs.String(), gpio.Low.String(), err)
+	}
+	defer func() {
+		if err2 := w.cs.Out(gpio.High); err2 != nil {
+			err = fmt.Errorf("%v.Out(%v) = %w, already had error %v", w.cs.String(), gpio.High, err2, err)
+		}
+	}()
+	if w.tap != nil {
+		w.tap(true, []byte{cmd})
+	}
+	if err := w.c.Tx([]byte{cmd}, nil); err != nil {
+		return 0, fmt.Errorf("sending command %#02x: %w", cmd, err)
+	}
+	if len(data) == 0 {
+		return 1, nil
+	}
+	if err := w.dc.Out(gpio.High); err != nil {
+		return 1, fmt.Errorf("%v.Out(%v) = %w", w.dc.String(), gpio.High.String(), err)
+	}
+	if w.tap != nil {
+		w.tap(false, data)
+	}
+	n, err = w.txChunks(data)
+	return 1 + n, err
+}
+
+// Write sends p[0] as a command and the rest of p as its data. Unless holdCS is set, cs is
+// released between the command and its data.
+func (w *commandWriter) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	cmd, data := p[0], p[1:]
+	// holdCS is read under the lock, as SetHoldCS may change it concurrently.
+	if w.HoldCS() {
+		return w.writeFrame(cmd, data)
+	}
+	if err := w.writeCommand(cmd); err != nil {
+		return 1, err
+	}
+	if len(data) == 0 {
+		return 1, nil
+	}
+	n, err := w.DataWriter().Write(data)
+	return 1 + n, err
+}
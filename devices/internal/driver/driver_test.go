@@ -0,0 +1,235 @@
+package driver_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/toothrot/gink/devices/internal/driver"
+	"github.com/toothrot/gink/devices/internal/driver/drivertest"
+	"periph.io/x/periph/conn/conntest"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/gpio/gpiotest"
+	"periph.io/x/periph/conn/spi"
+	"periph.io/x/periph/conn/spi/spireg"
+)
+
+func TestSetTxLimit(t *testing.T) {
+	for _, n := range []int{-1, 0} {
+		hw, _ := drivertest.NewHardware()
+		if err := hw.SetTxLimit(n); err == nil {
+			t.Errorf("SetTxLimit(%d) = nil, wanted error", n)
+		}
+		if hw.TxLimit() != driver.DefaultTxLimit {
+			t.Errorf("TxLimit() = %d, wanted %d", hw.TxLimit(), driver.DefaultTxLimit)
+		}
+	}
+	hw, bus := drivertest.NewHardware()
+	if err := hw.SetTxLimit(100); err != nil {
+		t.Fatalf("SetTxLimit(%d) = %v, wanted no error", 100, err)
+	}
+	if _, err := hw.DataWriter().Write(make([]byte, 250)); err != nil {
+		t.Fatalf("DataWriter().Write() = _, %v, wanted no error", err)
+	}
+	var got []int
+	for _, tx := range bus.Txs {
+		got = append(got, len(tx.W))
+	}
+	if want := []int{100, 100, 50}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("transfer sizes = %v, wanted %v", got, want)
+	}
+}
+
+// BenchmarkDataWriter sweeps SPI transfer sizes for a full plane upload of the 7.5" HD panel.
+func BenchmarkDataWriter(b *testing.B) {
+	buf := make([]byte, 880/8*528)
+	for _, n := range []int{256, 1024, 2048, 4096, 16384, 65536} {
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			dc := &gpiotest.Pin{N: "DC"}
+			hw := driver.New(&conntest.Discard{}, dc, &gpiotest.Pin{N: "CS"}, &gpiotest.Pin{N: "RST"}, &gpiotest.Pin{N: "BUSY"})
+			if err := hw.SetTxLimit(n); err != nil {
+				b.Fatal(err)
+			}
+			b.SetBytes(int64(len(buf)))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				hw.DataWriter().Write(buf)
+			}
+		})
+	}
+}
+
+func TestDataWriterLargeWrite(t *testing.T) {
+	hw, bus := drivertest.NewHardware()
+	p := make([]byte, 10*hw.TxLimit()+1)
+	for i := range p {
+		p[i] = byte(i)
+	}
+	n, err := hw.DataWriter().Write(p)
+	if n != len(p) || err != nil {
+		t.Fatalf("DataWriter().Write() = %d, %v, wanted %d, %v", n, err, len(p), nil)
+	}
+	var got []byte
+	for _, tx := range bus.Txs {
+		if len(tx.W) > hw.TxLimit() {
+			t.Errorf("len(tx.W) = %d, wanted at most %d", len(tx.W), hw.TxLimit())
+		}
+		if tx.DC != gpio.High {
+			t.Errorf("tx.DC = %v, wanted %v", tx.DC, gpio.High)
+		}
+		got = append(got, tx.W...)
+	}
+	if !bytes.Equal(got, p) {
+		t.Errorf("DataWriter().Write() sent %d bytes that differ from the %d written", len(got), len(p))
+	}
+}
+
+// countingPin counts how many times it is driven low.
+type countingPin struct {
+	*gpiotest.Pin
+	lows int
+}
+
+func (p *countingPin) Out(l gpio.Level) error {
+	if l == gpio.Low {
+		p.lows++
+	}
+	return p.Pin.Out(l)
+}
+
+func TestCommandWriterHoldCS(t *testing.T) {
+	for _, holdCS := range []bool{false, true} {
+		cs := &countingPin{Pin: &gpiotest.Pin{N: "CS"}}
+		hw, bus := drivertest.NewHardwareWithCS(cs)
+		hw.SetHoldCS(holdCS)
+		if err := hw.SetTxLimit(2); err != nil {
+			t.Fatal(err)
+		}
+
+		n, err := hw.CommandWriter().Write([]byte{0x45, 0xAF, 0x02, 0x00, 0x00})
+		if n != 5 || err != nil {
+			t.Fatalf("holdCS %v: Write() = %d, %v, wanted %d, nil", holdCS, n, err, 5)
+		}
+		wantLows := 2
+		if holdCS {
+			wantLows = 1
+		}
+		if cs.lows != wantLows {
+			t.Errorf("holdCS %v: cs was driven low %d times, wanted %d", holdCS, cs.lows, wantLows)
+		}
+		if cs.Read() != gpio.High {
+			t.Errorf("holdCS %v: cs is %v after Write(), wanted %v", holdCS, cs.Read(), gpio.High)
+		}
+		got := bus.Commands()
+		want := []drivertest.Command{{Cmd: 0x45, Data: []byte{0xAF, 0x02, 0x00, 0x00}}}
+		if len(got) != 1 || got[0].Cmd != want[0].Cmd || !bytes.Equal(got[0].Data, want[0].Data) {
+			t.Errorf("holdCS %v: sent %v, wanted %v", holdCS, got, want)
+		}
+	}
+}
+
+func TestReadSendsCommand(t *testing.T) {
+	hw, bus := drivertest.NewHardware()
+	bus.Rx = []byte{1, 2, 3}
+	b, err := hw.Read(0x2D, 3)
+	if err != nil || !bytes.Equal(b, []byte{1, 2, 3}) {
+		t.Fatalf("Read() = %v, %v, wanted %v, nil", b, err, []byte{1, 2, 3})
+	}
+	if len(bus.Txs) != 2 || bus.Txs[0].DC != gpio.Low || !bytes.Equal(bus.Txs[0].W, []byte{0x2D}) {
+		t.Errorf("Read() sent %+v, wanted command %#02x followed by a read", bus.Txs, 0x2D)
+	}
+}
+
+func TestPinsValidate(t *testing.T) {
+	valid := driver.Pins{Busy: "P1_18", CS: "P1_24", DC: "P1_22", RST: "P1_11"}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("%+v.Validate() = %v, wanted no error", valid, err)
+	}
+	cases := []struct {
+		p    driver.Pins
+		want string
+	}{
+		{p: driver.Pins{}, want: "Pins is empty; use DefaultPins or set all four pins"},
+		{p: driver.Pins{Busy: "P1_18", CS: "P1_24", RST: "P1_11"}, want: "dc pin is not set"},
+		{p: driver.Pins{Busy: "P1_18", CS: "P1_24", DC: "P1_24", RST: "P1_11"}, want: `cs and dc pins are both "P1_24"`},
+	}
+	for _, c := range cases {
+		err := c.p.Validate()
+		if err == nil || err.Error() != c.want {
+			t.Errorf("%+v.Validate() = %v, wanted %q", c.p, err, c.want)
+		}
+		if _, err := driver.Open(context.Background(), c.p, "", 0); err == nil || err.Error() != c.want {
+			t.Errorf("Open(%+v) = _, %v, wanted %q", c.p, err, c.want)
+		}
+	}
+}
+
+func TestOpenUnknownPort(t *testing.T) {
+	opener := func() (spi.PortCloser, error) { return nil, errors.New("not a real port") }
+	if err := spireg.Register("FAKESPI0.0", []string{"FAKESPI0"}, -1, opener); err != nil {
+		t.Fatalf("spireg.Register() = %v", err)
+	}
+	defer spireg.Unregister("FAKESPI0.0")
+
+	p := driver.Pins{Busy: "P1_18", CS: "P1_24", DC: "P1_22", RST: "P1_11"}
+	_, err := driver.Open(context.Background(), p, "SPI9.9", 0)
+	if err == nil || !strings.Contains(err.Error(), `unknown SPI port "SPI9.9"`) || !strings.Contains(err.Error(), "FAKESPI0.0") {
+		t.Errorf("Open(%q) = _, %v, wanted an error listing FAKESPI0.0", "SPI9.9", err)
+	}
+	// A known port, by alias, gets as far as acquiring the pins or the port.
+	if _, err := driver.Open(context.Background(), p, "FAKESPI0", 0); err == nil || strings.Contains(err.Error(), "unknown SPI port") {
+		t.Errorf("Open(%q) = _, %v, wanted an error from acquiring the hardware", "FAKESPI0", err)
+	}
+}
+
+func TestNewSimulator(t *testing.T) {
+	h := driver.NewSimulator()
+	if _, err := h.CommandWriter().Write([]byte{0x24, 1, 2, 3}); err != nil {
+		t.Errorf("CommandWriter().Write() = _, %v, wanted no error", err)
+	}
+	b, err := h.Read(0x2D, 3)
+	if err != nil || !bytes.Equal(b, []byte{0, 0, 0}) {
+		t.Errorf("Read() = %v, %v, wanted zeros", b, err)
+	}
+	if got := h.Busy().Read(); got != gpio.High {
+		t.Errorf("Busy().Read() = %v, wanted %v", got, gpio.High)
+	}
+	if err := h.Close(); err != nil {
+		t.Errorf("Close() = %v, wanted no error", err)
+	}
+}
+
+func TestSetTap(t *testing.T) {
+	for _, holdCS := range []bool{false, true} {
+		hw, _ := drivertest.NewHardware()
+		hw.SetHoldCS(holdCS)
+		var got []string
+		hw.SetTap(func(command bool, p []byte) {
+			got = append(got, fmt.Sprintf("%v %x", command, p))
+		})
+		if _, err := hw.CommandWriter().Write([]byte{0x24, 1, 2, 3}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := hw.CommandWriter().Write([]byte{0x20}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := hw.Read(0x2D, 2); err != nil {
+			t.Fatal(err)
+		}
+		want := []string{"true 24", "false 010203", "true 20", "true 2d"}
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("holdCS %v: tapped %q, wanted %q", holdCS, got, want)
+		}
+
+		hw.SetTap(nil)
+		if _, err := hw.DataWriter().Write([]byte{1}); err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != len(want) {
+			t.Errorf("holdCS %v: tapped %q after SetTap(nil), wanted nothing more", holdCS, got[len(want):])
+		}
+	}
+}
@@ -0,0 +1,88 @@
+// Package drivertest implements fakes for package driver.
+package drivertest
+
+import (
+	"sync"
+
+	"github.com/toothrot/gink/devices/internal/driver"
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/gpio/gpiotest"
+)
+
+// Bus is a conn.Conn that records each transfer along with the level of the dc pin.
+// Reads are filled from Rx. If Err is set, transfers fail with it and are not recorded.
+type Bus struct {
+	mu  sync.Mutex
+	dc  gpio.PinIO
+	Txs []Tx
+	Rx  []byte
+	Err error
+}
+
+// Tx is a single transfer on a Bus.
+type Tx struct {
+	DC gpio.Level
+	W  []byte
+}
+
+// Command is a command sent on a Bus, along with the data that followed it.
+type Command struct {
+	Cmd  byte
+	Data []byte
+}
+
+func (b *Bus) String() string {
+	return "drivertest.Bus"
+}
+
+// Tx implements conn.Conn.
+func (b *Bus) Tx(w, r []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.Err != nil {
+		return b.Err
+	}
+	b.Txs = append(b.Txs, Tx{DC: b.dc.Read(), W: append([]byte(nil), w...)})
+	n := copy(r, b.Rx)
+	b.Rx = b.Rx[n:]
+	return nil
+}
+
+// Duplex implements conn.Conn.
+func (b *Bus) Duplex() conn.Duplex {
+	return conn.Half
+}
+
+// Commands returns each command sent on the bus, in order, along with the data that followed
+// it.
+func (b *Bus) Commands() []Command {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var cmds []Command
+	for _, tx := range b.Txs {
+		if tx.DC == gpio.Low {
+			for _, c := range tx.W {
+				cmds = append(cmds, Command{Cmd: c})
+			}
+			continue
+		}
+		if len(cmds) > 0 {
+			last := &cmds[len(cmds)-1]
+			last.Data = append(last.Data, tx.W...)
+		}
+	}
+	return cmds
+}
+
+// NewHardware returns Hardware backed by fake pins and a recording Bus. The busy pin is high.
+func NewHardware() (*driver.Hardware, *Bus) {
+	return NewHardwareWithCS(&gpiotest.Pin{N: "CS"})
+}
+
+// NewHardwareWithCS is like NewHardware, but uses cs as the chip select pin.
+func NewHardwareWithCS(cs gpio.PinOut) (*driver.Hardware, *Bus) {
+	dc := &gpiotest.Pin{N: "DC"}
+	bus := &Bus{dc: dc}
+	return driver.New(bus, dc, cs, &gpiotest.Pin{N: "RST"}, &gpiotest.Pin{N: "BUSY", L: gpio.High}), bus
+}
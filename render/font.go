@@ -0,0 +1,94 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package render
+
+import (
+	_ "embed"
+	"fmt"
+	"io/ioutil"
+	"sync"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/opentype"
+)
+
+// The embedded fonts are the Go fonts. See fonts/README.md for their license.
+var (
+	//go:embed fonts/Go-Mono-Bold.ttf
+	monoBoldTTF []byte
+	//go:embed fonts/Go-Regular.ttf
+	regularTTF []byte
+
+	monoBold = &lazyFont{ttf: monoBoldTTF}
+	regular  = &lazyFont{ttf: regularTTF}
+)
+
+// lazyFont parses an embedded font the first time it is used.
+type lazyFont struct {
+	ttf  []byte
+	once sync.Once
+	f    *opentype.Font
+	err  error
+}
+
+func (l *lazyFont) face(size float64) (font.Face, error) {
+	l.once.Do(func() {
+		l.f, l.err = opentype.Parse(l.ttf)
+	})
+	if l.err != nil {
+		return nil, l.err
+	}
+	return opentype.NewFace(l.f, &opentype.FaceOptions{
+		Size:    size,
+		DPI:     72,
+		Hinting: font.HintingNone,
+	})
+}
+
+// DefaultMonoFace returns the embedded monospace font, Go Mono Bold, at size points.
+func DefaultMonoFace(size float64) (font.Face, error) {
+	return monoBold.face(size)
+}
+
+// DefaultFace returns the embedded proportional font, Go Regular, at size points.
+func DefaultFace(size float64) (font.Face, error) {
+	return regular.face(size)
+}
+
+// FaceOrDefault returns the font in the file at path, at size points, or the embedded
+// monospace font if path is empty. If the file cannot be loaded, the embedded font is returned
+// along with the error, so that callers can report the fallback and carry on. The face is nil
+// only if the embedded font itself fails.
+func FaceOrDefault(path string, size float64) (font.Face, error) {
+	if path == "" {
+		return DefaultMonoFace(size)
+	}
+	ff, err := LoadFace(path, size)
+	if err == nil {
+		return ff, nil
+	}
+	df, derr := DefaultMonoFace(size)
+	if derr != nil {
+		return nil, derr
+	}
+	return df, err
+}
+
+// LoadFace returns the TrueType or OpenType font in the file at path, at size points.
+func LoadFace(path string, size float64) (font.Face, error) {
+	ttf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := opentype.Parse(ttf)
+	if err != nil {
+		return nil, fmt.Errorf("opentype.Parse(%q) = _, %w", path, err)
+	}
+	return opentype.NewFace(f, &opentype.FaceOptions{
+		Size:    size,
+		DPI:     72,
+		Hinting: font.HintingNone,
+	})
+}
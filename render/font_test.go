@@ -0,0 +1,66 @@
+package render
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadFace(t *testing.T) {
+	dir := t.TempDir()
+	good := filepath.Join(dir, "good.ttf")
+	if err := ioutil.WriteFile(good, regularTTF, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	bad := filepath.Join(dir, "bad.ttf")
+	if err := ioutil.WriteFile(bad, []byte("not a font"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	ff, err := LoadFace(good, 24)
+	if err != nil {
+		t.Fatalf("LoadFace(%q) = _, %v, wanted no error", good, err)
+	}
+	if h := ff.Metrics().Height.Ceil(); h == 0 {
+		t.Errorf("LoadFace(%q).Metrics().Height = %d, wanted non-zero", good, h)
+	}
+
+	if _, err := LoadFace(bad, 24); err == nil {
+		t.Errorf("LoadFace(%q) = _, nil, wanted error", bad)
+	}
+	missing := filepath.Join(dir, "missing.ttf")
+	if _, err := LoadFace(missing, 24); !os.IsNotExist(err) {
+		t.Errorf("LoadFace(%q) = _, %v, wanted not-exist error", missing, err)
+	}
+}
+
+func TestFaceOrDefault(t *testing.T) {
+	dir := t.TempDir()
+	good := filepath.Join(dir, "good.ttf")
+	if err := ioutil.WriteFile(good, regularTTF, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	bad := filepath.Join(dir, "bad.ttf")
+	if err := ioutil.WriteFile(bad, []byte("not a font"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		path    string
+		wantErr bool
+	}{
+		{path: ""},
+		{path: good},
+		{path: bad, wantErr: true},
+		{path: filepath.Join(dir, "missing.ttf"), wantErr: true},
+	}
+	for _, c := range cases {
+		ff, err := FaceOrDefault(c.path, 24)
+		if (err != nil) != c.wantErr {
+			t.Errorf("FaceOrDefault(%q) = _, %v, wanted error: %v", c.path, err, c.wantErr)
+		}
+		if ff == nil {
+			t.Errorf("FaceOrDefault(%q) = nil, _, wanted a face", c.path)
+		}
+	}
+}
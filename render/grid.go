@@ -0,0 +1,112 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package render
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+
+	"github.com/fogleman/gg"
+)
+
+// Grid renders a single line of text in fixed cells, one character per cell, so that each
+// character stays in place as the text changes. With a monospace font, such as the embedded
+// default, a changed character only changes the pixels of its own cell, which Changed reports.
+//
+// The line is vertically centered and aligned horizontally according to the options. Wrapping
+// and LineSpacing do not apply.
+type Grid struct {
+	frame  *image.RGBA
+	ctx    *gg.Context
+	opts   TextOptions
+	cols   int
+	cell   image.Point
+	origin image.Point
+	ascent int
+}
+
+// NewGrid returns a Grid of cols cells for frames of the given size. Each cell is as wide as
+// the font's advance for '0', and as tall as the font. An error is returned if the cells do not
+// fit within size less the margin.
+func NewGrid(size image.Point, cols int, opts TextOptions) (*Grid, error) {
+	face, err := opts.face()
+	if err != nil {
+		return nil, err
+	}
+	adv, ok := face.GlyphAdvance('0')
+	if !ok {
+		return nil, fmt.Errorf("font has no glyph for '0'")
+	}
+	m := face.Metrics()
+	cell := image.Pt(adv.Ceil(), m.Height.Ceil())
+	w, h := cols*cell.X, cell.Y
+	if room := size.Sub(image.Pt(2*opts.Margin, 2*opts.Margin)); w > room.X || h > room.Y {
+		return nil, fmt.Errorf("%d cells of %v do not fit in %v with a margin of %d", cols, cell, size, opts.Margin)
+	}
+	origin := image.Pt((size.X-w)/2, (size.Y-h)/2)
+	switch opts.Align {
+	case AlignLeft:
+		origin.X = opts.Margin
+	case AlignRight:
+		origin.X = size.X - opts.Margin - w
+	}
+	frame := image.NewRGBA(image.Rectangle{Max: size})
+	ctx := gg.NewContextForRGBA(frame)
+	ctx.SetFontFace(face)
+	return &Grid{
+		frame:  frame,
+		ctx:    ctx,
+		opts:   opts,
+		cols:   cols,
+		cell:   cell,
+		origin: origin,
+		ascent: m.Ascent.Ceil(),
+	}, nil
+}
+
+// Cell returns the bounds of cell i within the frame.
+func (g *Grid) Cell(i int) image.Rectangle {
+	min := g.origin.Add(image.Pt(i*g.cell.X, 0))
+	return image.Rectangle{Min: min, Max: min.Add(g.cell)}
+}
+
+// Render returns a frame with each character of s drawn in its cell. Characters beyond the
+// last cell are not drawn, and cells beyond the end of s are left blank.
+//
+// The returned image is reused by the next call to Render.
+func (g *Grid) Render(s string) image.Image {
+	fg, bg := g.opts.colors()
+	draw.Draw(g.frame, g.frame.Bounds(), image.NewUniform(bg), image.Point{}, draw.Src)
+	g.ctx.SetColor(fg)
+	for i, r := range g.cells(s) {
+		c := g.Cell(i)
+		g.ctx.DrawString(string(r), float64(c.Min.X), float64(c.Min.Y+g.ascent))
+	}
+	return g.frame
+}
+
+// Changed returns the smallest rectangle that holds every cell whose character differs
+// between old and new. It is empty if they render the same.
+func (g *Grid) Changed(old, new string) image.Rectangle {
+	a, b := g.cells(old), g.cells(new)
+	var r image.Rectangle
+	for i := 0; i < g.cols; i++ {
+		if a[i] != b[i] {
+			r = r.Union(g.Cell(i))
+		}
+	}
+	return r
+}
+
+// cells returns the character in each cell for s, with a space in blank cells.
+func (g *Grid) cells(s string) []rune {
+	cells := make([]rune, g.cols)
+	for i := range cells {
+		cells[i] = ' '
+	}
+	copy(cells, []rune(s))
+	return cells
+}
@@ -0,0 +1,55 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+func TestGrid(t *testing.T) {
+	size := image.Pt(400, 200)
+	g, err := NewGrid(size, 5, TextOptions{Size: 64})
+	if err != nil {
+		t.Fatalf("NewGrid() = _, %v, wanted no error", err)
+	}
+	before := image.NewRGBA(image.Rectangle{Max: size})
+	draw.Draw(before, before.Rect, g.Render("12:34"), image.Point{}, draw.Src)
+	after := g.Render("12:35")
+
+	changed := g.Changed("12:34", "12:35")
+	if changed != g.Cell(4) {
+		t.Errorf("Changed() = %v, wanted the last cell %v", changed, g.Cell(4))
+	}
+	var diffs int
+	for y := 0; y < size.Y; y++ {
+		for x := 0; x < size.X; x++ {
+			if color.GrayModel.Convert(before.At(x, y)) == color.GrayModel.Convert(after.At(x, y)) {
+				continue
+			}
+			diffs++
+			if !image.Pt(x, y).In(changed) {
+				t.Fatalf("Render() changed (%d, %d), outside of Changed() %v", x, y, changed)
+			}
+		}
+	}
+	if diffs == 0 {
+		t.Errorf("Render() of a new minute changed no pixels")
+	}
+	if n := darkPixels(after, g.Cell(0)); n == 0 {
+		t.Errorf("darkPixels(cell 0) = 0, wanted the digit 1")
+	}
+
+	if r := g.Changed("12:34", "12:34"); !r.Empty() {
+		t.Errorf("Changed() of the same text = %v, wanted empty", r)
+	}
+	if r := g.Changed("12:34", "12:3"); r != g.Cell(4) {
+		t.Errorf("Changed() of shorter text = %v, wanted the blanked cell %v", r, g.Cell(4))
+	}
+}
+
+func TestGridTooSmall(t *testing.T) {
+	if _, err := NewGrid(image.Pt(100, 100), 20, TextOptions{Size: 64}); err == nil {
+		t.Errorf("NewGrid() of 20 cells in 100 pixels = _, nil, wanted error")
+	}
+}
@@ -0,0 +1,51 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package render
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// progressBorder is the width in pixels of the progress bar outline and the gap inside it.
+const progressBorder = 2
+
+// ProgressBar returns a w by h image of a bar outlined in c and filled with c to pct, which is
+// clamped to [0, 1].
+//
+// The image is an *image.Paletted with exactly two colors, white and c, so that it can be
+// drawn without any color matching.
+func ProgressBar(pct float64, w, h int, c color.Color) image.Image {
+	if math.IsNaN(pct) || pct < 0 {
+		pct = 0
+	}
+	if pct > 1 {
+		pct = 1
+	}
+	img := image.NewPaletted(image.Rect(0, 0, w, h), color.Palette{color.White, c})
+	fill := func(r image.Rectangle) {
+		r = r.Intersect(img.Rect)
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				img.SetColorIndex(x, y, 1)
+			}
+		}
+	}
+	b := progressBorder
+	// Outline.
+	fill(image.Rect(0, 0, w, b))
+	fill(image.Rect(0, h-b, w, h))
+	fill(image.Rect(0, 0, b, h))
+	fill(image.Rect(w-b, 0, w, h))
+	// Fill, inset by the outline and a gap of the same width.
+	inner := image.Rect(2*b, 2*b, w-2*b, h-2*b)
+	if inner.Empty() {
+		return img
+	}
+	inner.Max.X = inner.Min.X + int(math.Round(pct*float64(inner.Dx())))
+	fill(inner)
+	return img
+}
@@ -0,0 +1,45 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestProgressBar(t *testing.T) {
+	cases := []struct {
+		desc  string
+		pct   float64
+		wantX int
+	}{
+		{desc: "empty", pct: 0, wantX: 4},
+		{desc: "half", pct: 0.5, wantX: 50},
+		{desc: "full", pct: 1, wantX: 96},
+		{desc: "clamped below", pct: -1, wantX: 4},
+		{desc: "clamped above", pct: 2, wantX: 96},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			img := ProgressBar(c.pct, 100, 20, color.Black).(*image.Paletted)
+			if len(img.Palette) != 2 {
+				t.Errorf("len(img.Palette) = %d, wanted 2", len(img.Palette))
+			}
+			// Walk the middle row inside the outline and gap to find the end of the fill.
+			x := 4
+			for x < 96 && img.ColorIndexAt(x, 10) == 1 {
+				x++
+			}
+			if x != c.wantX {
+				t.Errorf("fill ends at x = %d, wanted %d", x, c.wantX)
+			}
+			for _, p := range []image.Point{{0, 0}, {99, 19}, {1, 10}, {98, 10}} {
+				if img.ColorIndexAt(p.X, p.Y) != 1 {
+					t.Errorf("img.ColorIndexAt(%d, %d) = 0, wanted outline", p.X, p.Y)
+				}
+			}
+			if img.ColorIndexAt(2, 10) != 0 {
+				t.Errorf("img.ColorIndexAt(2, 10) = 1, wanted gap inside outline")
+			}
+		})
+	}
+}
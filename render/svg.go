@@ -0,0 +1,710 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package render
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"golang.org/x/image/vector"
+)
+
+// SVG rasterizes the SVG document in data to a w by h image on a white background. The
+// document's viewBox, or its width and height, is scaled to fit and centered, keeping its
+// aspect ratio. Shapes are drawn with smooth edges, which the display's color conversion
+// rounds to its nearest colors.
+//
+// Only filled shapes are supported: rect (including rounded corners), circle, ellipse,
+// polygon, polyline, and path, with all of the path commands. Fills are set with the fill
+// attribute or style property, inherited from groups, as hex, rgb(), or a basic named color,
+// and default to black. Other named colors are drawn in black, and transparent is the same as
+// none. Transforms are supported, except for skews.
+//
+// Shapes are filled with the nonzero rule unless fill-rule is evenodd. For evenodd, each
+// subpath is filled on its own and the results are combined with an exclusive or, so holes
+// drawn in the same direction as their outline are left unfilled, but a single subpath that
+// crosses itself is still filled as with nonzero.
+//
+// Everything else is ignored, including strokes, text, embedded images, opacity, clipping,
+// masks, CSS style sheets, and use references. Gradient and pattern fills are drawn in black.
+// Icons drawn with strokes rather than fills, such as line icon sets, come out blank.
+func SVG(data []byte, w, h int) (image.Image, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	var dst *image.RGBA
+	var z *vector.Rasterizer
+	var stack []svgState
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parsing SVG: %w", err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if dst == nil {
+				if t.Name.Local != "svg" {
+					return nil, fmt.Errorf("root element is <%s>, wanted <svg>", t.Name.Local)
+				}
+				m, err := viewBox(t.Attr, w, h)
+				if err != nil {
+					return nil, err
+				}
+				dst = image.NewRGBA(image.Rect(0, 0, w, h))
+				draw.Draw(dst, dst.Rect, image.White, image.Point{}, draw.Src)
+				z = vector.NewRasterizer(w, h)
+				root, err := svgState{fill: color.Black, m: m}.child(t.Attr)
+				if err != nil {
+					return nil, err
+				}
+				stack = append(stack, root)
+				continue
+			}
+			switch t.Name.Local {
+			case "defs", "symbol", "clipPath", "mask", "marker", "pattern", "linearGradient", "radialGradient", "style", "text", "title", "desc", "metadata":
+				// Nothing in these is drawn directly.
+				if err := dec.Skip(); err != nil {
+					return nil, fmt.Errorf("parsing SVG: %w", err)
+				}
+				continue
+			}
+			st, err := stack[len(stack)-1].child(t.Attr)
+			if err != nil {
+				return nil, fmt.Errorf("<%s>: %w", t.Name.Local, err)
+			}
+			stack = append(stack, st)
+			if st.fill == nil {
+				continue
+			}
+			z.Reset(w, h)
+			p := &pather{z: z, m: st.m}
+			if st.evenOdd {
+				p.mask = image.NewAlpha(dst.Rect)
+			}
+			drawn, err := shape(p, t)
+			if err != nil {
+				return nil, fmt.Errorf("<%s>: %w", t.Name.Local, err)
+			}
+			if drawn {
+				p.fill(dst, st.fill)
+			}
+		case xml.EndElement:
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		}
+	}
+	if dst == nil {
+		return nil, errors.New("no <svg> element found")
+	}
+	return dst, nil
+}
+
+// svgState is what an element inherits from its parents.
+type svgState struct {
+	// fill is nil for none.
+	fill color.Color
+	// evenOdd is whether fill-rule is evenodd.
+	evenOdd bool
+	// m maps the element's coordinates to pixels.
+	m affine
+}
+
+// child returns the state of an element with attrs within s.
+func (s svgState) child(attrs []xml.Attr) (svgState, error) {
+	if fill, ok := property(attrs, "fill"); ok && fill != "inherit" {
+		c, err := parseSVGColor(fill)
+		if err != nil {
+			return s, err
+		}
+		s.fill = c
+	}
+	if rule, ok := property(attrs, "fill-rule"); ok && rule != "inherit" {
+		s.evenOdd = rule == "evenodd"
+	}
+	if t, ok := attr(attrs, "transform"); ok {
+		m, err := parseTransform(t)
+		if err != nil {
+			return s, err
+		}
+		s.m = s.m.mul(m)
+	}
+	return s, nil
+}
+
+// property returns the value of the presentation attribute name in attrs, or of the property
+// name in the style attribute, which takes precedence, and whether either is present.
+func property(attrs []xml.Attr, name string) (string, bool) {
+	v, ok := attr(attrs, name)
+	for _, decl := range strings.Split(attrValue(attrs, "style"), ";") {
+		if i := strings.Index(decl, ":"); i >= 0 && strings.TrimSpace(decl[:i]) == name {
+			v, ok = strings.TrimSpace(decl[i+1:]), true
+		}
+	}
+	return strings.TrimSpace(v), ok
+}
+
+// viewBox returns the transform that fits the root element's viewBox, or its width and height,
+// centered in a w by h image.
+func viewBox(attrs []xml.Attr, w, h int) (affine, error) {
+	var vb [4]float64
+	if v, ok := attr(attrs, "viewBox"); ok {
+		s := &pathScanner{s: v}
+		for i := range vb {
+			vb[i] = s.num()
+		}
+		if s.err != nil {
+			return affine{}, fmt.Errorf("viewBox %q: %w", v, s.err)
+		}
+	} else {
+		var err error
+		if vb[2], err = parseLength(attrValue(attrs, "width")); err != nil {
+			return affine{}, fmt.Errorf("<svg> needs a viewBox, or a width and height: %w", err)
+		}
+		if vb[3], err = parseLength(attrValue(attrs, "height")); err != nil {
+			return affine{}, fmt.Errorf("<svg> needs a viewBox, or a width and height: %w", err)
+		}
+	}
+	if vb[2] <= 0 || vb[3] <= 0 {
+		return affine{}, fmt.Errorf("<svg> has an empty size of %vx%v", vb[2], vb[3])
+	}
+	scale := math.Min(float64(w)/vb[2], float64(h)/vb[3])
+	tx := (float64(w)-vb[2]*scale)/2 - vb[0]*scale
+	ty := (float64(h)-vb[3]*scale)/2 - vb[1]*scale
+	return affine{scale, 0, 0, scale, tx, ty}, nil
+}
+
+// shape adds the outline of the element in t to p, and reports whether it is a shape.
+func shape(p *pather, t xml.StartElement) (bool, error) {
+	num := func(name string) (float64, error) {
+		v, ok := attr(t.Attr, name)
+		if !ok {
+			return 0, nil
+		}
+		return parseLength(v)
+	}
+	var nums []float64
+	for _, name := range shapeAttrs[t.Name.Local] {
+		n, err := num(name)
+		if err != nil {
+			return false, fmt.Errorf("%s: %w", name, err)
+		}
+		nums = append(nums, n)
+	}
+	switch t.Name.Local {
+	case "rect":
+		x, y, w, h, rx, ry := nums[0], nums[1], nums[2], nums[3], nums[4], nums[5]
+		if w <= 0 || h <= 0 {
+			return false, nil
+		}
+		// Either radius defaults to the other.
+		if _, ok := attr(t.Attr, "rx"); !ok {
+			rx = ry
+		}
+		if _, ok := attr(t.Attr, "ry"); !ok {
+			ry = rx
+		}
+		rx, ry = math.Min(math.Abs(rx), w/2), math.Min(math.Abs(ry), h/2)
+		p.moveTo(x+rx, y)
+		p.lineTo(x+w-rx, y)
+		p.ellipseArc(x+w-rx, y+ry, rx, ry, 0, -math.Pi/2, math.Pi/2)
+		p.lineTo(x+w, y+h-ry)
+		p.ellipseArc(x+w-rx, y+h-ry, rx, ry, 0, 0, math.Pi/2)
+		p.lineTo(x+rx, y+h)
+		p.ellipseArc(x+rx, y+h-ry, rx, ry, 0, math.Pi/2, math.Pi/2)
+		p.lineTo(x, y+ry)
+		p.ellipseArc(x+rx, y+ry, rx, ry, 0, math.Pi, math.Pi/2)
+		p.close()
+	case "circle", "ellipse":
+		cx, cy, rx, ry := nums[0], nums[1], nums[2], nums[2]
+		if t.Name.Local == "ellipse" {
+			ry = nums[3]
+		}
+		if rx <= 0 || ry <= 0 {
+			return false, nil
+		}
+		p.moveTo(cx+rx, cy)
+		p.ellipseArc(cx, cy, rx, ry, 0, 0, 2*math.Pi)
+		p.close()
+	case "polygon", "polyline":
+		s := &pathScanner{s: attrValue(t.Attr, "points")}
+		for i := 0; !s.done(); i++ {
+			x, y := s.num(), s.num()
+			if s.err != nil {
+				return false, fmt.Errorf("points: %w", s.err)
+			}
+			if i == 0 {
+				p.moveTo(x, y)
+			} else {
+				p.lineTo(x, y)
+			}
+		}
+		p.close()
+	case "path":
+		if err := drawPath(p, attrValue(t.Attr, "d")); err != nil {
+			return false, err
+		}
+	default:
+		return false, nil
+	}
+	return true, nil
+}
+
+// shapeAttrs are the numeric attributes of each shape, in the order shape reads them.
+var shapeAttrs = map[string][]string{
+	"rect":    {"x", "y", "width", "height", "rx", "ry"},
+	"circle":  {"cx", "cy", "r"},
+	"ellipse": {"cx", "cy", "rx", "ry"},
+}
+
+// drawPath adds the outline described by the path data d to p.
+func drawPath(p *pather, d string) error {
+	s := &pathScanner{s: d}
+	// cur is the current point, start is the start of the subpath, and ctrl is the last
+	// control point, for the shorthand curves.
+	var curX, curY, startX, startY, ctrlX, ctrlY float64
+	var cmd, prev byte
+	for !s.done() {
+		if c := s.s[s.i]; isCommand(c) {
+			cmd = c
+			s.i++
+		} else if cmd == 0 {
+			return fmt.Errorf("path data %q: expected a command at offset %d", d, s.i)
+		}
+		var ox, oy float64
+		rel := cmd >= 'a'
+		if rel {
+			ox, oy = curX, curY
+		}
+		upper := cmd &^ 0x20
+		switch upper {
+		case 'M':
+			curX, curY = ox+s.num(), oy+s.num()
+			startX, startY = curX, curY
+			p.moveTo(curX, curY)
+			// Further pairs are lines.
+			cmd = 'L' | cmd&0x20
+		case 'L':
+			curX, curY = ox+s.num(), oy+s.num()
+			p.lineTo(curX, curY)
+		case 'H':
+			curX = ox + s.num()
+			p.lineTo(curX, curY)
+		case 'V':
+			curY = oy + s.num()
+			p.lineTo(curX, curY)
+		case 'C', 'S':
+			x1, y1 := 2*curX-ctrlX, 2*curY-ctrlY
+			if upper == 'C' {
+				x1, y1 = ox+s.num(), oy+s.num()
+			} else if prev != 'C' && prev != 'S' {
+				x1, y1 = curX, curY
+			}
+			ctrlX, ctrlY = ox+s.num(), oy+s.num()
+			curX, curY = ox+s.num(), oy+s.num()
+			p.cubeTo(x1, y1, ctrlX, ctrlY, curX, curY)
+		case 'Q', 'T':
+			x1, y1 := 2*curX-ctrlX, 2*curY-ctrlY
+			if upper == 'Q' {
+				x1, y1 = ox+s.num(), oy+s.num()
+			} else if prev != 'Q' && prev != 'T' {
+				x1, y1 = curX, curY
+			}
+			ctrlX, ctrlY = x1, y1
+			curX, curY = ox+s.num(), oy+s.num()
+			p.quadTo(ctrlX, ctrlY, curX, curY)
+		case 'A':
+			rx, ry, rot := s.num(), s.num(), s.num()
+			large, sweep := s.flag(), s.flag()
+			x, y := ox+s.num(), oy+s.num()
+			if s.err == nil {
+				p.arcTo(curX, curY, rx, ry, rot, large, sweep, x, y)
+			}
+			curX, curY = x, y
+		case 'Z':
+			p.close()
+			curX, curY = startX, startY
+			// Numbers can't follow a close without a new command.
+			cmd = 0
+		default:
+			return fmt.Errorf("path data %q: unknown command %q", d, cmd)
+		}
+		if s.err != nil {
+			return fmt.Errorf("path data %q: %w", d, s.err)
+		}
+		prev = upper
+	}
+	return nil
+}
+
+func isCommand(c byte) bool {
+	return c != 'e' && c != 'E' && ('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z')
+}
+
+// pathScanner reads the numbers of path data, points, and viewBox attributes, which may be
+// separated by whitespace, a comma, or nothing at all where the next number starts with a sign
+// or a second decimal point. The first error is kept in err, and later reads return 0.
+type pathScanner struct {
+	s   string
+	i   int
+	err error
+}
+
+// skip moves past separators.
+func (s *pathScanner) skip() {
+	for s.i < len(s.s) && strings.IndexByte(" \t\r\n,", s.s[s.i]) >= 0 {
+		s.i++
+	}
+}
+
+// done reports whether only separators are left.
+func (s *pathScanner) done() bool {
+	s.skip()
+	return s.i >= len(s.s)
+}
+
+// num reads a number.
+func (s *pathScanner) num() float64 {
+	if s.err != nil {
+		return 0
+	}
+	s.skip()
+	start := s.i
+	if s.i < len(s.s) && (s.s[s.i] == '+' || s.s[s.i] == '-') {
+		s.i++
+	}
+	digits := func() {
+		for s.i < len(s.s) && '0' <= s.s[s.i] && s.s[s.i] <= '9' {
+			s.i++
+		}
+	}
+	digits()
+	if s.i < len(s.s) && s.s[s.i] == '.' {
+		s.i++
+		digits()
+	}
+	if s.i < len(s.s) && (s.s[s.i] == 'e' || s.s[s.i] == 'E') {
+		s.i++
+		if s.i < len(s.s) && (s.s[s.i] == '+' || s.s[s.i] == '-') {
+			s.i++
+		}
+		digits()
+	}
+	f, err := strconv.ParseFloat(s.s[start:s.i], 64)
+	if err != nil {
+		s.err = fmt.Errorf("expected a number at offset %d", start)
+		return 0
+	}
+	return f
+}
+
+// flag reads an arc flag, which is a single 0 or 1 that may be followed directly by the next
+// number.
+func (s *pathScanner) flag() bool {
+	if s.err != nil {
+		return false
+	}
+	s.skip()
+	if s.i < len(s.s) && (s.s[s.i] == '0' || s.s[s.i] == '1') {
+		s.i++
+		return s.s[s.i-1] == '1'
+	}
+	s.err = fmt.Errorf("expected a flag of 0 or 1 at offset %d", s.i)
+	return false
+}
+
+// pather adds outlines to a Rasterizer, mapping each point with m.
+type pather struct {
+	z *vector.Rasterizer
+	m affine
+	// mask, if set, is where subpaths are combined for the evenodd fill rule. Each subpath is
+	// added to it as the next one starts, and by fill.
+	mask *image.Alpha
+	// sub holds a subpath's coverage while it is added to mask.
+	sub *image.Alpha
+}
+
+func (p *pather) moveTo(x, y float64) {
+	if p.mask != nil {
+		p.addSubpath()
+	}
+	p.z.MoveTo(p.m.apply(x, y))
+}
+
+// addSubpath combines the coverage of the outline in z with mask, by an exclusive or that
+// keeps smooth edges, and resets z for the next subpath.
+func (p *pather) addSubpath() {
+	if p.sub == nil {
+		p.sub = image.NewAlpha(p.mask.Rect)
+	}
+	p.z.ClosePath()
+	p.z.DrawOp = draw.Src
+	p.z.Draw(p.sub, p.sub.Rect, image.Opaque, image.Point{})
+	p.z.DrawOp = draw.Over
+	for i, b := range p.sub.Pix {
+		a := int(p.mask.Pix[i])
+		p.mask.Pix[i] = uint8(a + int(b) - 2*a*int(b)/0xff)
+	}
+	size := p.mask.Rect.Size()
+	p.z.Reset(size.X, size.Y)
+}
+
+// fill draws the outline in c over dst.
+func (p *pather) fill(dst draw.Image, c color.Color) {
+	if p.mask == nil {
+		p.z.Draw(dst, dst.Bounds(), image.NewUniform(c), image.Point{})
+		return
+	}
+	p.addSubpath()
+	draw.DrawMask(dst, dst.Bounds(), image.NewUniform(c), image.Point{}, p.mask, image.Point{}, draw.Over)
+}
+
+func (p *pather) lineTo(x, y float64) {
+	p.z.LineTo(p.m.apply(x, y))
+}
+
+func (p *pather) quadTo(x1, y1, x, y float64) {
+	bx, by := p.m.apply(x1, y1)
+	cx, cy := p.m.apply(x, y)
+	p.z.QuadTo(bx, by, cx, cy)
+}
+
+func (p *pather) cubeTo(x1, y1, x2, y2, x, y float64) {
+	bx, by := p.m.apply(x1, y1)
+	cx, cy := p.m.apply(x2, y2)
+	dx, dy := p.m.apply(x, y)
+	p.z.CubeTo(bx, by, cx, cy, dx, dy)
+}
+
+func (p *pather) close() {
+	p.z.ClosePath()
+}
+
+// arcTo adds an elliptical arc from (x1, y1) to (x2, y2), as described by the parameters of the
+// path command A. See the SVG specification's implementation notes on elliptical arcs.
+func (p *pather) arcTo(x1, y1, rx, ry, rotDeg float64, large, sweep bool, x2, y2 float64) {
+	if x1 == x2 && y1 == y2 {
+		return
+	}
+	rx, ry = math.Abs(rx), math.Abs(ry)
+	if rx == 0 || ry == 0 {
+		p.lineTo(x2, y2)
+		return
+	}
+	phi := rotDeg * math.Pi / 180
+	sin, cos := math.Sincos(phi)
+	dx, dy := (x1-x2)/2, (y1-y2)/2
+	x1p, y1p := cos*dx+sin*dy, -sin*dx+cos*dy
+	// Radii too small to reach the end point are scaled up until they do.
+	if l := x1p*x1p/(rx*rx) + y1p*y1p/(ry*ry); l > 1 {
+		rx, ry = rx*math.Sqrt(l), ry*math.Sqrt(l)
+	}
+	num := rx*rx*ry*ry - rx*rx*y1p*y1p - ry*ry*x1p*x1p
+	den := rx*rx*y1p*y1p + ry*ry*x1p*x1p
+	var co float64
+	if num > 0 && den > 0 {
+		co = math.Sqrt(num / den)
+	}
+	if large == sweep {
+		co = -co
+	}
+	cxp, cyp := co*rx*y1p/ry, -co*ry*x1p/rx
+	cx := cos*cxp - sin*cyp + (x1+x2)/2
+	cy := sin*cxp + cos*cyp + (y1+y2)/2
+	ux, uy := (x1p-cxp)/rx, (y1p-cyp)/ry
+	vx, vy := (-x1p-cxp)/rx, (-y1p-cyp)/ry
+	theta := math.Atan2(uy, ux)
+	delta := math.Atan2(ux*vy-uy*vx, ux*vx+uy*vy)
+	if !sweep && delta > 0 {
+		delta -= 2 * math.Pi
+	} else if sweep && delta < 0 {
+		delta += 2 * math.Pi
+	}
+	p.ellipseArc(cx, cy, rx, ry, phi, theta, delta)
+}
+
+// ellipseArc adds the arc of the ellipse centered on (cx, cy), rotated by phi radians, from the
+// angle theta through delta radians, as cubic curves of at most a quarter turn each. The pen
+// must already be at the start of the arc.
+func (p *pather) ellipseArc(cx, cy, rx, ry, phi, theta, delta float64) {
+	if rx == 0 || ry == 0 {
+		return
+	}
+	sin, cos := math.Sincos(phi)
+	pt := func(u, v float64) (float64, float64) {
+		return cx + rx*u*cos - ry*v*sin, cy + rx*u*sin + ry*v*cos
+	}
+	n := int(math.Ceil(math.Abs(delta) / (math.Pi / 2)))
+	d := delta / float64(n)
+	k := 4.0 / 3 * math.Tan(d/4)
+	for i := 0; i < n; i++ {
+		s1, c1 := math.Sincos(theta)
+		s2, c2 := math.Sincos(theta + d)
+		x1, y1 := pt(c1-k*s1, s1+k*c1)
+		x2, y2 := pt(c2+k*s2, s2-k*c2)
+		x, y := pt(c2, s2)
+		p.cubeTo(x1, y1, x2, y2, x, y)
+		theta += d
+	}
+}
+
+// affine is the transform matrix(a, b, c, d, e, f), mapping (x, y) to
+// (a*x + c*y + e, b*x + d*y + f).
+type affine [6]float64
+
+// mul returns the transform that applies n, then m.
+func (m affine) mul(n affine) affine {
+	return affine{
+		m[0]*n[0] + m[2]*n[1],
+		m[1]*n[0] + m[3]*n[1],
+		m[0]*n[2] + m[2]*n[3],
+		m[1]*n[2] + m[3]*n[3],
+		m[0]*n[4] + m[2]*n[5] + m[4],
+		m[1]*n[4] + m[3]*n[5] + m[5],
+	}
+}
+
+func (m affine) apply(x, y float64) (float32, float32) {
+	return float32(m[0]*x + m[2]*y + m[4]), float32(m[1]*x + m[3]*y + m[5])
+}
+
+// parseTransform parses a transform attribute, a list of translate, scale, rotate, and matrix
+// functions applied from right to left.
+func parseTransform(t string) (affine, error) {
+	m := affine{1, 0, 0, 1, 0, 0}
+	rest := strings.TrimSpace(t)
+	for rest != "" {
+		open, end := strings.Index(rest, "("), strings.Index(rest, ")")
+		if open < 0 || end < open {
+			return m, fmt.Errorf("transform %q is not a list of functions", t)
+		}
+		name := strings.TrimSpace(rest[:open])
+		s := &pathScanner{s: rest[open+1 : end]}
+		var args []float64
+		for !s.done() {
+			args = append(args, s.num())
+		}
+		if s.err != nil {
+			return m, fmt.Errorf("transform %q: %w", t, s.err)
+		}
+		rest = strings.TrimLeft(rest[end+1:], " \t\r\n,")
+
+		var f affine
+		switch {
+		case name == "matrix" && len(args) == 6:
+			copy(f[:], args)
+		case name == "translate" && len(args) == 1:
+			f = affine{1, 0, 0, 1, args[0], 0}
+		case name == "translate" && len(args) == 2:
+			f = affine{1, 0, 0, 1, args[0], args[1]}
+		case name == "scale" && len(args) == 1:
+			f = affine{args[0], 0, 0, args[0], 0, 0}
+		case name == "scale" && len(args) == 2:
+			f = affine{args[0], 0, 0, args[1], 0, 0}
+		case name == "rotate" && (len(args) == 1 || len(args) == 3):
+			sin, cos := math.Sincos(args[0] * math.Pi / 180)
+			f = affine{cos, sin, -sin, cos, 0, 0}
+			if len(args) == 3 {
+				// Rotate about (cx, cy).
+				to := affine{1, 0, 0, 1, args[1], args[2]}
+				from := affine{1, 0, 0, 1, -args[1], -args[2]}
+				f = to.mul(f).mul(from)
+			}
+		default:
+			return m, fmt.Errorf("transform %q: unsupported %s with %d arguments", t, name, len(args))
+		}
+		m = m.mul(f)
+	}
+	return m, nil
+}
+
+// svgColors are the named colors that parseSVGColor accepts.
+var svgColors = map[string]color.Color{
+	"black":  color.Black,
+	"white":  color.White,
+	"red":    color.RGBA{0xff, 0, 0, 0xff},
+	"green":  color.RGBA{0, 0x80, 0, 0xff},
+	"blue":   color.RGBA{0, 0, 0xff, 0xff},
+	"yellow": color.RGBA{0xff, 0xff, 0, 0xff},
+	"orange": color.RGBA{0xff, 0xa5, 0, 0xff},
+	"gray":   color.RGBA{0x80, 0x80, 0x80, 0xff},
+	"grey":   color.RGBA{0x80, 0x80, 0x80, 0xff},
+	"silver": color.RGBA{0xc0, 0xc0, 0xc0, 0xff},
+}
+
+// parseSVGColor parses a fill. It returns nil for none and transparent. currentColor,
+// references to gradients or patterns, and named colors other than svgColors are black.
+func parseSVGColor(s string) (color.Color, error) {
+	s = strings.TrimSpace(s)
+	switch {
+	case s == "none" || strings.EqualFold(s, "transparent"):
+		return nil, nil
+	case s == "currentColor" || strings.HasPrefix(s, "url("):
+		return color.Black, nil
+	case strings.HasPrefix(s, "#"):
+		hex := s[1:]
+		if len(hex) == 3 {
+			hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+		}
+		v, err := strconv.ParseUint(hex, 16, 32)
+		if len(hex) != 6 || err != nil {
+			return nil, fmt.Errorf("invalid color %q", s)
+		}
+		return color.RGBA{uint8(v >> 16), uint8(v >> 8), uint8(v), 0xff}, nil
+	case strings.HasPrefix(s, "rgb(") && strings.HasSuffix(s, ")"):
+		parts := strings.Split(s[len("rgb("):len(s)-1], ",")
+		var c [3]uint8
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("invalid color %q", s)
+		}
+		for i, part := range parts {
+			v, err := strconv.ParseUint(strings.TrimSpace(part), 10, 8)
+			if err != nil {
+				return nil, fmt.Errorf("invalid color %q", s)
+			}
+			c[i] = uint8(v)
+		}
+		return color.RGBA{c[0], c[1], c[2], 0xff}, nil
+	}
+	if c, ok := svgColors[strings.ToLower(s)]; ok {
+		return c, nil
+	}
+	return color.Black, nil
+}
+
+// parseLength parses a length in user units, with an optional px suffix.
+func parseLength(s string) (float64, error) {
+	v, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(s), "px"), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid length %q", s)
+	}
+	return v, nil
+}
+
+// attr returns the value of the attribute name in attrs, and whether it is present.
+func attr(attrs []xml.Attr, name string) (string, bool) {
+	for _, a := range attrs {
+		if a.Name.Local == name {
+			return a.Value, true
+		}
+	}
+	return "", false
+}
+
+// attrValue returns the value of the attribute name in attrs, or "" if it is not present.
+func attrValue(attrs []xml.Attr, name string) string {
+	v, _ := attr(attrs, name)
+	return v
+}
@@ -0,0 +1,134 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+func TestSVG(t *testing.T) {
+	white, black, red := color.RGBA{0xff, 0xff, 0xff, 0xff}, color.RGBA{0, 0, 0, 0xff}, color.RGBA{0xff, 0, 0, 0xff}
+	cases := []struct {
+		desc string
+		svg  string
+		// want is the expected color of each point of a 100 by 100 image.
+		want map[image.Point]color.RGBA
+	}{
+		{
+			desc: "rect",
+			svg:  `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 10 10"><rect x="0" y="0" width="5" height="10"/></svg>`,
+			want: map[image.Point]color.RGBA{{25, 50}: black, {75, 50}: white},
+		},
+		{
+			desc: "rounded rect",
+			svg:  `<svg viewBox="0 0 10 10"><rect width="10" height="10" rx="5"/></svg>`,
+			want: map[image.Point]color.RGBA{{50, 50}: black, {50, 2}: black, {2, 2}: white, {97, 97}: white},
+		},
+		{
+			desc: "circle and inherited fill",
+			svg:  `<svg viewBox="0 0 10 10"><g fill="red"><circle cx="5" cy="5" r="3"/></g></svg>`,
+			want: map[image.Point]color.RGBA{{50, 50}: red, {5, 5}: white, {50, 15}: white, {50, 25}: red},
+		},
+		{
+			desc: "style fill and none",
+			svg:  `<svg viewBox="0 0 10 10"><rect width="10" height="10" fill="none"/><rect width="5" height="5" style="stroke:#000; fill: #f00"/></svg>`,
+			want: map[image.Point]color.RGBA{{25, 25}: red, {75, 75}: white},
+		},
+		{
+			desc: "relative path",
+			svg:  `<svg viewBox="0 0 10 10"><path d="m5 0h5v10h-5z"/></svg>`,
+			want: map[image.Point]color.RGBA{{25, 50}: white, {75, 50}: black},
+		},
+		{
+			desc: "arc",
+			// A half disc on the left, closed by its diameter.
+			svg:  `<svg viewBox="0 0 10 10"><path d="M5,1A4,4 0 0,0 5,9Z"/></svg>`,
+			want: map[image.Point]color.RGBA{{20, 50}: black, {80, 50}: white, {45, 15}: black},
+		},
+		{
+			desc: "curves",
+			svg:  `<svg viewBox="0 0 10 10"><path d="M0 0C0 10 10 10 10 0S5-5 0 0Q5 10 10 10T0 10z" fill="#000"/></svg>`,
+			want: map[image.Point]color.RGBA{{50, 30}: black},
+		},
+		{
+			desc: "transform",
+			svg:  `<svg viewBox="0 0 10 10"><g transform="translate(5 5)"><rect width="5" height="5" transform="scale(1, 1)"/></g></svg>`,
+			want: map[image.Point]color.RGBA{{25, 25}: white, {75, 75}: black},
+		},
+		{
+			desc: "rotate about a point",
+			svg:  `<svg viewBox="0 0 10 10"><rect width="5" height="10" transform="rotate(180 5 5)"/></svg>`,
+			want: map[image.Point]color.RGBA{{25, 50}: white, {75, 50}: black},
+		},
+		{
+			desc: "polygon",
+			svg:  `<svg viewBox="0 0 10 10"><polygon points="0,0 10,0 0,10" fill="rgb(0, 0, 0)"/></svg>`,
+			want: map[image.Point]color.RGBA{{20, 20}: black, {80, 80}: white},
+		},
+		{
+			desc: "evenodd",
+			// Both squares are drawn clockwise, so the inner one is only a hole with evenodd.
+			svg:  `<svg viewBox="0 0 10 10"><path fill-rule="evenodd" d="M0 0h10v10h-10zM3 3h4v4h-4z"/></svg>`,
+			want: map[image.Point]color.RGBA{{10, 10}: black, {50, 50}: white},
+		},
+		{
+			desc: "evenodd inherited, nonzero in style",
+			svg:  `<svg viewBox="0 0 10 10"><g fill-rule="evenodd"><path style="fill-rule: nonzero" d="M0 0h10v10h-10zM3 3h4v4h-4z"/></g></svg>`,
+			want: map[image.Point]color.RGBA{{10, 10}: black, {50, 50}: black},
+		},
+		{
+			desc: "other named colors are black, transparent is none",
+			svg:  `<svg viewBox="0 0 10 10"><rect width="5" height="10" fill="crimson"/><rect x="5" width="5" height="10" fill="transparent"/></svg>`,
+			want: map[image.Point]color.RGBA{{25, 50}: black, {75, 50}: white},
+		},
+		{
+			desc: "defs are not drawn",
+			svg:  `<svg viewBox="0 0 10 10"><defs><rect id="r" width="10" height="10"/></defs><title>Icon</title></svg>`,
+			want: map[image.Point]color.RGBA{{50, 50}: white},
+		},
+		{
+			desc: "width and height, centered",
+			// 10 by 5 is scaled to 100 by 50, and centered vertically.
+			svg:  `<svg width="10px" height="5"><rect width="10" height="5"/></svg>`,
+			want: map[image.Point]color.RGBA{{50, 10}: white, {50, 50}: black, {50, 90}: white},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			img, err := SVG([]byte(c.svg), 100, 100)
+			if err != nil {
+				t.Fatalf("SVG() = _, %v, wanted no error", err)
+			}
+			if got := img.Bounds(); got != image.Rect(0, 0, 100, 100) {
+				t.Errorf("SVG().Bounds() = %v, wanted 100x100", got)
+			}
+			for p, want := range c.want {
+				if got := color.RGBAModel.Convert(img.At(p.X, p.Y)); got != want {
+					t.Errorf("SVG().At(%d, %d) = %v, wanted %v", p.X, p.Y, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestSVGErrors(t *testing.T) {
+	cases := []struct {
+		svg  string
+		want string
+	}{
+		{svg: `<html></html>`, want: "root element is <html>"},
+		{svg: ``, want: "no <svg> element"},
+		{svg: `<svg><rect/></svg>`, want: "needs a viewBox"},
+		{svg: `<svg viewBox="0 0 10 10"><path d="10 10"/></svg>`, want: "expected a command"},
+		{svg: `<svg viewBox="0 0 10 10"><path d="M0 0 L 5"/></svg>`, want: "expected a number"},
+		{svg: `<svg viewBox="0 0 10 10"><path d="M0 0 A 1 1 0 2 0 5 5"/></svg>`, want: "expected a flag"},
+		{svg: `<svg viewBox="0 0 10 10"><rect fill="#12"/></svg>`, want: "invalid color"},
+		{svg: `<svg viewBox="0 0 10 10"><g transform="skewX(10)"/></svg>`, want: "unsupported skewX"},
+	}
+	for _, c := range cases {
+		if _, err := SVG([]byte(c.svg), 10, 10); err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("SVG(%q) = _, %v, wanted error containing %q", c.svg, err, c.want)
+		}
+	}
+}
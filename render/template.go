@@ -0,0 +1,63 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package render
+
+import (
+	"image"
+	"image/draw"
+
+	"github.com/fogleman/gg"
+)
+
+// Template renders frames that share a static background and differ only in the text within
+// one region, such as a clock.
+//
+// The background is rasterized and the font is loaded once, when the Template is created. Each
+// Render only restores and redraws the text region.
+type Template struct {
+	frame  *image.RGBA
+	base   *image.RGBA
+	region image.Rectangle
+	// scratch holds the region while its text is drawn.
+	scratch *image.RGBA
+	ctx     *gg.Context
+	opts    TextOptions
+}
+
+// NewTemplate returns a Template for frames of base, with text drawn by Render laid out
+// within region according to opts. opts.Background is ignored, as the text is drawn over base.
+func NewTemplate(base image.Image, region image.Rectangle, opts TextOptions) (*Template, error) {
+	face, err := opts.face()
+	if err != nil {
+		return nil, err
+	}
+	b := image.NewRGBA(base.Bounds())
+	draw.Draw(b, b.Bounds(), base, b.Bounds().Min, draw.Src)
+	frame := image.NewRGBA(b.Bounds())
+	draw.Draw(frame, frame.Bounds(), b, b.Bounds().Min, draw.Src)
+
+	region = region.Intersect(b.Bounds())
+	scratch := image.NewRGBA(image.Rectangle{Max: region.Size()})
+	ctx := gg.NewContextForRGBA(scratch)
+	ctx.SetFontFace(face)
+	return &Template{
+		frame:   frame,
+		base:    b,
+		region:  region,
+		scratch: scratch,
+		ctx:     ctx,
+		opts:    opts,
+	}, nil
+}
+
+// Render returns a frame with s drawn within the template's region.
+//
+// The returned image is reused by the next call to Render.
+func (t *Template) Render(s string) image.Image {
+	draw.Draw(t.scratch, t.scratch.Bounds(), t.base, t.region.Min, draw.Src)
+	drawText(t.ctx, t.region.Size(), s, t.opts)
+	draw.Draw(t.frame, t.region, t.scratch, image.Point{}, draw.Src)
+	return t.frame
+}
@@ -0,0 +1,64 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+func TestTemplate(t *testing.T) {
+	base := image.NewRGBA(image.Rect(0, 0, 400, 200))
+	draw.Draw(base, base.Bounds(), image.White, image.Point{}, draw.Src)
+	// A static black bar along the top, outside of the text region.
+	draw.Draw(base, image.Rect(0, 0, 400, 10), image.Black, image.Point{}, draw.Src)
+	region := image.Rect(0, 100, 400, 200)
+
+	tmpl, err := NewTemplate(base, region, TextOptions{Size: 32})
+	if err != nil {
+		t.Fatalf("NewTemplate() = _, %v, wanted no error", err)
+	}
+	want, err := Text(region.Size(), "12:34", TextOptions{Size: 32})
+	if err != nil {
+		t.Fatalf("Text() = _, %v, wanted no error", err)
+	}
+	tmpl.Render("88:88")
+	got := tmpl.Render("12:34")
+	if n := darkPixels(got, image.Rect(0, 0, 400, 10)); n != 400*10 {
+		t.Errorf("darkPixels(static bar) = %d, wanted %d", n, 400*10)
+	}
+	if n := darkPixels(got, image.Rect(0, 10, 400, 100)); n != 0 {
+		t.Errorf("darkPixels(between bar and region) = %d, wanted 0", n)
+	}
+	for y := 0; y < region.Dy(); y++ {
+		for x := 0; x < region.Dx(); x++ {
+			g := color.GrayModel.Convert(got.At(x+region.Min.X, y+region.Min.Y))
+			w := color.GrayModel.Convert(want.At(x, y))
+			if g != w {
+				t.Fatalf("got.At(%d, %d) = %v, wanted %v as drawn by Text", x+region.Min.X, y+region.Min.Y, g, w)
+			}
+		}
+	}
+}
+
+func BenchmarkText(b *testing.B) {
+	size := image.Pt(880, 528)
+	for i := 0; i < b.N; i++ {
+		if _, err := Text(size, "15:04", TextOptions{Size: 128}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkTemplateRender(b *testing.B) {
+	base := image.NewRGBA(image.Rect(0, 0, 880, 528))
+	draw.Draw(base, base.Bounds(), image.White, image.Point{}, draw.Src)
+	tmpl, err := NewTemplate(base, image.Rect(0, 164, 880, 364), TextOptions{Size: 128})
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tmpl.Render("15:04")
+	}
+}
@@ -0,0 +1,200 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package render draws text and simple layouts into images suitable for e-Paper displays.
+package render
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"strings"
+
+	"github.com/fogleman/gg"
+	"golang.org/x/image/font"
+)
+
+// DefaultSize is the font size in points used when TextOptions.Size is unset.
+const DefaultSize = 92
+
+// Align is the horizontal alignment of wrapped lines of text.
+type Align int
+
+const (
+	// AlignCenter centers each line. It is the zero value.
+	AlignCenter Align = iota
+	AlignLeft
+	AlignRight
+)
+
+// TextOptions configures how Text lays out a string.
+type TextOptions struct {
+	// Face is the font face to draw with. If nil, the embedded Go Mono Bold font is used at Size.
+	Face font.Face
+	// Size is the font size in points of the embedded font. Defaults to DefaultSize.
+	Size float64
+	// Color is the text color. Defaults to black.
+	Color color.Color
+	// Background is the color behind the text. Defaults to white.
+	Background color.Color
+	// Align is the horizontal alignment of each line.
+	Align Align
+	// Margin is the minimum distance in pixels between the text and the image edges.
+	Margin int
+	// LineSpacing is the distance between lines as a multiple of the font height. Defaults to 1.
+	LineSpacing float64
+	// Rotate is the angle in degrees to rotate the text counter-clockwise about the center of
+	// the image, as imaging.Rotate rotates. The text is wrapped within the largest box that
+	// fits the image once rotated, so 90 and 270 lay it out as if the image were portrait, and
+	// each glyph is drawn rotated rather than the finished image being resampled.
+	Rotate float64
+}
+
+func (o TextOptions) face() (font.Face, error) {
+	if o.Face != nil {
+		return o.Face, nil
+	}
+	size := o.Size
+	if size <= 0 {
+		size = DefaultSize
+	}
+	return DefaultMonoFace(size)
+}
+
+func (o TextOptions) colors() (fg, bg color.Color) {
+	fg, bg = color.Black, color.White
+	if o.Color != nil {
+		fg = o.Color
+	}
+	if o.Background != nil {
+		bg = o.Background
+	}
+	return fg, bg
+}
+
+func (o TextOptions) lineSpacing() float64 {
+	if o.LineSpacing <= 0 {
+		return 1
+	}
+	return o.LineSpacing
+}
+
+// Text returns an image of the given size with s word-wrapped and vertically centered within it.
+func Text(size image.Point, s string, opts TextOptions) (image.Image, error) {
+	face, err := opts.face()
+	if err != nil {
+		return nil, err
+	}
+	_, bg := opts.colors()
+	ctx := gg.NewContext(size.X, size.Y)
+	ctx.SetColor(bg)
+	ctx.Clear()
+	ctx.SetFontFace(face)
+	drawText(ctx, size, s, opts)
+	return ctx.Image(), nil
+}
+
+// drawText draws s onto ctx with the context's font face, laid out within size according to opts.
+func drawText(ctx *gg.Context, size image.Point, s string, opts TextOptions) {
+	fg, _ := opts.colors()
+	ctx.SetColor(fg)
+	m := float64(opts.Margin)
+	w, h := layoutBox(size, opts.Rotate)
+	if opts.Rotate != 0 {
+		ctx.Push()
+		defer ctx.Pop()
+		// Whole pixels keep right angles from resampling each glyph.
+		cx, cy := float64(size.X/2), float64(size.Y/2)
+		ctx.RotateAbout(gg.Radians(-opts.Rotate), cx, cy)
+		ctx.Translate(math.Round(cx-w/2), math.Round(cy-h/2))
+	}
+	x, ax, align := w/2, 0.5, gg.AlignCenter
+	switch opts.Align {
+	case AlignLeft:
+		x, ax, align = m, 0, gg.AlignLeft
+	case AlignRight:
+		x, ax, align = w-m, 1, gg.AlignRight
+	}
+	drawLines(ctx, wrap(ctx, s, w-2*m), x, h/2, ax, 0.5, w-2*m, opts.lineSpacing(), align)
+}
+
+// layoutBox returns the size of the largest box that fits within size once rotated by degrees
+// about its center. The box has the aspect ratio of size, or of size transposed if the rotation
+// is nearer to portrait.
+func layoutBox(size image.Point, degrees float64) (w, h float64) {
+	w, h = float64(size.X), float64(size.Y)
+	if degrees == 0 {
+		return w, h
+	}
+	rad := gg.Radians(degrees)
+	cos, sin := math.Abs(math.Cos(rad)), math.Abs(math.Sin(rad))
+	// Right angles are exact, so that the box is too.
+	if cos < 1e-9 {
+		cos = 0
+	}
+	if sin < 1e-9 {
+		sin = 0
+	}
+	a, b := w, h
+	if sin > cos {
+		a, b = h, w
+	}
+	scale := math.Min(w/(a*cos+b*sin), h/(a*sin+b*cos))
+	return a * scale, b * scale
+}
+
+// drawLines draws lines as gg.Context.DrawStringWrapped draws the lines it wraps.
+func drawLines(ctx *gg.Context, lines []string, x, y, ax, ay, width, lineSpacing float64, align gg.Align) {
+	_, fh := ctx.MeasureString("")
+	h := float64(len(lines))*fh*lineSpacing - (lineSpacing-1)*fh
+	x -= ax * width
+	y -= ay * h
+	switch align {
+	case gg.AlignLeft:
+		ax = 0
+	case gg.AlignCenter:
+		ax = 0.5
+		x += width / 2
+	case gg.AlignRight:
+		ax = 1
+		x += width
+	}
+	for _, line := range lines {
+		ctx.DrawStringAnchored(line, x, y, ax, 1)
+		y += fh * lineSpacing
+	}
+}
+
+// Pages splits s into pages that each fit within an image of the given size when drawn by Text
+// with the same options. Lines are wrapped as Text wraps them.
+func Pages(size image.Point, s string, opts TextOptions) ([]string, error) {
+	face, err := opts.face()
+	if err != nil {
+		return nil, err
+	}
+	ctx := gg.NewContext(size.X, size.Y)
+	ctx.SetFontFace(face)
+	m := float64(opts.Margin)
+	w, h := layoutBox(size, opts.Rotate)
+	lines := wrap(ctx, s, w-2*m)
+
+	fh := float64(face.Metrics().Height) / 64
+	perPage := 1
+	if step := fh * opts.lineSpacing(); step > 0 {
+		perPage += int((h - 2*m - fh) / step)
+	}
+	if perPage < 1 {
+		perPage = 1
+	}
+	var pages []string
+	for len(lines) > 0 {
+		n := perPage
+		if n > len(lines) {
+			n = len(lines)
+		}
+		pages = append(pages, strings.Join(lines[:n], "\n"))
+		lines = lines[n:]
+	}
+	return pages, nil
+}
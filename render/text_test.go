@@ -0,0 +1,175 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+
+	"github.com/disintegration/imaging"
+)
+
+func TestText(t *testing.T) {
+	cases := []struct {
+		desc  string
+		opts  TextOptions
+		dark  image.Rectangle
+		light image.Rectangle
+	}{
+		{
+			desc:  "center",
+			opts:  TextOptions{Size: 32},
+			dark:  image.Rect(100, 0, 300, 100),
+			light: image.Rect(0, 0, 40, 100),
+		},
+		{
+			desc:  "left",
+			opts:  TextOptions{Size: 32, Align: AlignLeft, Margin: 10},
+			dark:  image.Rect(0, 0, 100, 100),
+			light: image.Rect(300, 0, 400, 100),
+		},
+		{
+			desc:  "right",
+			opts:  TextOptions{Size: 32, Align: AlignRight, Margin: 10},
+			dark:  image.Rect(300, 0, 400, 100),
+			light: image.Rect(0, 0, 100, 100),
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			img, err := Text(image.Pt(400, 100), "Hello", c.opts)
+			if err != nil {
+				t.Fatalf("Text() = _, %v, wanted no error", err)
+			}
+			if got := img.Bounds(); got != image.Rect(0, 0, 400, 100) {
+				t.Errorf("img.Bounds() = %v, wanted %v", got, image.Rect(0, 0, 400, 100))
+			}
+			if n := darkPixels(img, c.dark); n == 0 {
+				t.Errorf("darkPixels(img, %v) = 0, wanted text in region", c.dark)
+			}
+			if n := darkPixels(img, c.light); n != 0 {
+				t.Errorf("darkPixels(img, %v) = %d, wanted no text in region", c.light, n)
+			}
+		})
+	}
+}
+
+func TestTextRotate(t *testing.T) {
+	size := image.Pt(400, 240)
+	opts := TextOptions{Size: 32, Margin: 10}
+	// At right angles, drawing rotated text matches rotating text laid out for the rotated
+	// size, which imaging.Rotate does without resampling.
+	for _, deg := range []float64{90, 180, 270} {
+		ro := opts
+		ro.Rotate = deg
+		native, err := Text(size, "Hello, world", ro)
+		if err != nil {
+			t.Fatalf("Text() = _, %v, wanted no error", err)
+		}
+		laid := size
+		if deg != 180 {
+			laid = image.Pt(size.Y, size.X)
+		}
+		plain, err := Text(laid, "Hello, world", opts)
+		if err != nil {
+			t.Fatalf("Text() = _, %v, wanted no error", err)
+		}
+		if n := differentPixels(native, imaging.Rotate(plain, deg, color.White)); n != 0 {
+			t.Errorf("Rotate %v: %d pixels differ from imaging.Rotate, wanted 0", deg, n)
+		}
+	}
+
+	// At other angles, rotating the finished image, and scaling it back to size as the tools
+	// did, shrinks and blurs the text.
+	ro := opts
+	ro.Rotate = 30
+	native, err := Text(size, "Hello, world", ro)
+	if err != nil {
+		t.Fatalf("Text() = _, %v, wanted no error", err)
+	}
+	plain, err := Text(size, "Hello, world", opts)
+	if err != nil {
+		t.Fatalf("Text() = _, %v, wanted no error", err)
+	}
+	after := imaging.Fit(imaging.Rotate(plain, 30, color.White), size.X, size.Y, imaging.Lanczos)
+	nd, ad := darkPixels(native, native.Bounds()), darkPixels(after, after.Bounds())
+	if nd <= ad {
+		t.Errorf("Rotate 30: %d dark pixels, wanted more than the %d from rotating afterwards", nd, ad)
+	}
+	// Blur shows as more gray edge pixels for each dark pixel.
+	if ns, as := float64(grayPixels(native))/float64(nd), float64(grayPixels(after))/float64(ad); ns >= as {
+		t.Errorf("Rotate 30: %.2f gray pixels per dark pixel, wanted fewer than the %.2f from rotating afterwards", ns, as)
+	}
+}
+
+// differentPixels returns how many pixels of a and b differ in gray level.
+func differentPixels(a, b image.Image) int {
+	if a.Bounds() != b.Bounds() {
+		return a.Bounds().Dx() * a.Bounds().Dy()
+	}
+	var n int
+	r := a.Bounds()
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			if color.GrayModel.Convert(a.At(x, y)) != color.GrayModel.Convert(b.At(x, y)) {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// grayPixels returns how many pixels of img are neither nearly black nor nearly white.
+func grayPixels(img image.Image) int {
+	var n int
+	r := img.Bounds()
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			if g := color.GrayModel.Convert(img.At(x, y)).(color.Gray); g.Y > 0x20 && g.Y < 0xE0 {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+func darkPixels(img image.Image, r image.Rectangle) int {
+	var n int
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			if g := color.GrayModel.Convert(img.At(x, y)).(color.Gray); g.Y < 0x80 {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+func TestPages(t *testing.T) {
+	size := image.Pt(400, 100)
+	opts := TextOptions{Size: 32, Margin: 10}
+	s := strings.Repeat("lorem ipsum dolor ", 20)
+	pages, err := Pages(size, s, opts)
+	if err != nil {
+		t.Fatalf("Pages() = _, %v, wanted no error", err)
+	}
+	if len(pages) < 2 {
+		t.Fatalf("len(Pages()) = %d, wanted multiple pages", len(pages))
+	}
+	got := strings.Join(strings.Fields(strings.Join(pages, " ")), " ")
+	if want := strings.TrimSpace(s); got != want {
+		t.Errorf("Pages() joined = %q, wanted %q", got, want)
+	}
+	for i, p := range pages {
+		img, err := Text(size, p, opts)
+		if err != nil {
+			t.Fatalf("Text(%q) = _, %v, wanted no error", p, err)
+		}
+		if n := darkPixels(img, image.Rect(0, 0, size.X, 2)); n != 0 {
+			t.Errorf("page %d: darkPixels at top edge = %d, wanted page to fit", i, n)
+		}
+		if n := darkPixels(img, image.Rect(0, size.Y-2, size.X, size.Y)); n != 0 {
+			t.Errorf("page %d: darkPixels at bottom edge = %d, wanted page to fit", i, n)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package render
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/fogleman/gg"
+)
+
+// wrap splits s into lines that are each at most width wide when drawn with ctx's font face.
+//
+// Lines break at spaces, and between CJK characters, which are not separated by spaces. A word
+// that is wider than a line by itself, such as a long URL, is broken between grapheme clusters.
+// Explicit newlines are kept, including blank lines.
+func wrap(ctx *gg.Context, s string, width float64) []string {
+	fits := func(s string) bool {
+		w, _ := ctx.MeasureString(s)
+		return w <= width
+	}
+	var lines []string
+	for _, para := range strings.Split(s, "\n") {
+		var cur string
+		for _, w := range splitWords(para) {
+			if cur != "" && fits(cur+w.sep+w.text) {
+				cur += w.sep + w.text
+				continue
+			}
+			if cur != "" {
+				lines = append(lines, cur)
+				cur = ""
+			}
+			if fits(w.text) {
+				cur = w.text
+				continue
+			}
+			for _, g := range graphemes(w.text) {
+				if cur != "" && !fits(cur+g) {
+					lines = append(lines, cur)
+					cur = ""
+				}
+				cur += g
+			}
+		}
+		lines = append(lines, cur)
+	}
+	return lines
+}
+
+// word is a run of text that is only broken if it does not fit on a line by itself.
+type word struct {
+	// sep is the whitespace before the word, which is dropped at the start of a line.
+	sep  string
+	text string
+}
+
+// splitWords splits s into words at whitespace and around each CJK grapheme.
+func splitWords(s string) []word {
+	var words []word
+	var cur word
+	flush := func() {
+		if cur.text != "" {
+			words = append(words, cur)
+		}
+		cur = word{}
+	}
+	for _, g := range graphemes(s) {
+		r, _ := utf8.DecodeRuneInString(g)
+		switch {
+		case unicode.IsSpace(r):
+			if cur.text != "" {
+				flush()
+			}
+			cur.sep += g
+		case isCJK(r):
+			flush()
+			words = append(words, word{text: g})
+		default:
+			cur.text += g
+		}
+	}
+	flush()
+	return words
+}
+
+// isCJK reports whether a line may break before or after r without a space.
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) ||
+		(r >= 0x3000 && r <= 0x303F) || // CJK symbols and punctuation.
+		(r >= 0xFF00 && r <= 0xFFEF) // Halfwidth and fullwidth forms.
+}
+
+// graphemes splits s into approximate grapheme clusters: each rune along with any combining
+// marks, variation selectors, and emoji modifiers after it, joined across zero width joiners.
+// Pairs of regional indicators, which form flags, are kept together.
+func graphemes(s string) []string {
+	var gs []string
+	start := 0
+	var prev rune
+	for i, r := range s {
+		if i > start && !extends(prev, r) {
+			gs = append(gs, s[start:i])
+			start = i
+			prev = 0
+		}
+		if isRegionalIndicator(prev) && isRegionalIndicator(r) {
+			// A flag is complete; don't join a third indicator to it.
+			r = 0
+		}
+		prev = r
+	}
+	if start < len(s) {
+		gs = append(gs, s[start:])
+	}
+	return gs
+}
+
+const zeroWidthJoiner = '\u200d'
+
+// extends reports whether r continues the grapheme cluster ending in prev.
+func extends(prev, r rune) bool {
+	switch {
+	case prev == zeroWidthJoiner, r == zeroWidthJoiner:
+		return true
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc, unicode.Variation_Selector):
+		return true
+	case r >= 0x1F3FB && r <= 0x1F3FF: // Emoji skin tone modifiers.
+		return true
+	case isRegionalIndicator(prev) && isRegionalIndicator(r):
+		return true
+	}
+	return false
+}
+
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1F1E6 && r <= 0x1F1FF
+}
@@ -0,0 +1,58 @@
+package render
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/fogleman/gg"
+)
+
+func TestWrap(t *testing.T) {
+	face, err := DefaultMonoFace(32)
+	if err != nil {
+		t.Fatalf("DefaultMonoFace() = _, %v, wanted no error", err)
+	}
+	ctx := gg.NewContext(1, 1)
+	ctx.SetFontFace(face)
+	const width = 300
+
+	s := "こんにちは世界、今日はいい天気ですね。 see https://example.com/a/very/long/path/that/does/not/fit?q=1"
+	lines := wrap(ctx, s, width)
+	if len(lines) < 3 {
+		t.Errorf("wrap() = %q, wanted at least 3 lines", lines)
+	}
+	for _, l := range lines {
+		if w, _ := ctx.MeasureString(l); w > width {
+			t.Errorf("wrap() line %q is %v wide, wanted at most %v", l, w, width)
+		}
+	}
+	strip := func(s string) string { return strings.Join(strings.Fields(s), "") }
+	if got := strip(strings.Join(lines, "")); got != strip(s) {
+		t.Errorf("wrap() joined = %q, wanted %q", got, strip(s))
+	}
+
+	if got, want := wrap(ctx, "a b\n\nc", width), []string{"a b", "", "c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("wrap() = %q, wanted %q", got, want)
+	}
+}
+
+func TestGraphemes(t *testing.T) {
+	cases := []struct {
+		s    string
+		want []string
+	}{
+		{s: "abc", want: []string{"a", "b", "c"}},
+		// e followed by a combining acute accent.
+		{s: "éx", want: []string{"é", "x"}},
+		// Family emoji joined with zero width joiners, and a skin tone modifier.
+		{s: "👩‍👧👍🏽", want: []string{"👩‍👧", "👍🏽"}},
+		// Two flags.
+		{s: "🇯🇵🇫🇷", want: []string{"🇯🇵", "🇫🇷"}},
+	}
+	for _, c := range cases {
+		if got := graphemes(c.s); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("graphemes(%q) = %q, wanted %q", c.s, got, c.want)
+		}
+	}
+}